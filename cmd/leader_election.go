@@ -0,0 +1,49 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// defaultLeaderElectionID is the name of the Lease replicas compete for.
+const defaultLeaderElectionID = "cert-sync-leader-lock"
+
+// leaderElectionFlags configures leader election, which keeps replicas from
+// racing each other on the same imports. It is off by default, for a single
+// replica.
+type leaderElectionFlags struct {
+	enabled   bool
+	id        string
+	namespace string
+}
+
+// bind registers the leader election flags on fs.
+func (f *leaderElectionFlags) bind(fs *flag.FlagSet) {
+	fs.BoolVar(&f.enabled, "leader-elect", false, "Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	fs.StringVar(&f.id, "leader-election-id", defaultLeaderElectionID, "Name of the Lease used for leader election. Replicas sharing it elect a single leader.")
+	fs.StringVar(&f.namespace, "leader-election-namespace", "", "Namespace of the leader election Lease. Empty uses the namespace the controller runs in.")
+}
+
+// apply sets the leader election options of opts.
+func (f *leaderElectionFlags) apply(opts *ctrl.Options) {
+	opts.LeaderElection = f.enabled
+	opts.LeaderElectionID = f.id
+	opts.LeaderElectionNamespace = f.namespace
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"flag"
+	"testing"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestLeaderElectionFlags(t *testing.T) {
+	cases := []struct {
+		name          string
+		args          []string
+		wantEnabled   bool
+		wantID        string
+		wantNamespace string
+	}{
+		{
+			name:   "defaults",
+			wantID: defaultLeaderElectionID,
+		},
+		{
+			name:          "enabled",
+			args:          []string{"--leader-elect", "--leader-election-id=acm-sync", "--leader-election-namespace=cert-sync-system"},
+			wantEnabled:   true,
+			wantID:        "acm-sync",
+			wantNamespace: "cert-sync-system",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var f leaderElectionFlags
+			fs := flag.NewFlagSet("cert-sync", flag.ContinueOnError)
+			f.bind(fs)
+			if err := fs.Parse(tc.args); err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+
+			var opts ctrl.Options
+			f.apply(&opts)
+			if opts.LeaderElection != tc.wantEnabled {
+				t.Errorf("LeaderElection = %v, want %v", opts.LeaderElection, tc.wantEnabled)
+			}
+			if opts.LeaderElectionID != tc.wantID {
+				t.Errorf("LeaderElectionID = %q, want %q", opts.LeaderElectionID, tc.wantID)
+			}
+			if opts.LeaderElectionNamespace != tc.wantNamespace {
+				t.Errorf("LeaderElectionNamespace = %q, want %q", opts.LeaderElectionNamespace, tc.wantNamespace)
+			}
+		})
+	}
+}
@@ -17,18 +17,29 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"flag"
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
@@ -38,6 +49,7 @@ import (
 	// +kubebuilder:scaffold:imports
 
 	"github.com/denyshubh/cert-sync/controllers"
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
 )
 
 var (
@@ -53,25 +65,254 @@ func init() {
 
 func main() {
 	var metricsAddr string
-	var enableLeaderElection bool
+	var leaderElection leaderElectionFlags
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var adminAddr string
+	var onEmptyChain string
+	var auditLogFile string
+	var validateDomainDNS bool
+	var dnsResolver string
+	var dnsTimeout time.Duration
+	var expectedZones string
+	var validateSANs bool
+	var maxConcurrentImports int
+	var acmEventQueueURL string
+	var cleanupOnDelete bool
+	var maxTags int
+	var minNotBefore string
+	var importStaged bool
+	var domainAnnotations string
+	var strictPEM bool
+	var annotateNotAfter bool
+	var maxActiveSyncs int
+	var maxConcurrentReconciles int
+	var normalizeKeys bool
+	var reuseTagged bool
+	var rootCABundle string
+	var adoptIdentical bool
+	var chainExpiryWarning time.Duration
+	var metricsDomainLimit int
+	var expiryPriorityWindow time.Duration
+	var pruneStaleTags bool
+	var stateConfigMap string
+	var scanThrottleRetries int
+	var scanThrottleMaxDelay time.Duration
+	var minRemainingValidity time.Duration
+	var canonicalizeChain bool
+	var fieldManager string
+	var mirrorACMErrors bool
+	var refreshTagsOnStart bool
+	var tagRefreshConcurrency int
+	var namespaceRegions bool
+	var keyUsage string
+	var shutdownSummary bool
+	var supersetMatch string
+	var contentHashTags bool
+	var onInUse string
+	var splitCombinedPEM bool
+	var regionBackoffMax time.Duration
+	var consolidateDuplicates bool
+	var logFormat string
+	var runOnce bool
+	var verifyTags bool
+	var loopThreshold int
+	var loopWindow time.Duration
+	var credentialAnnotations bool
+	var allowedProfiles, allowedRoleARNs string
+	var acmClientPoolSize int
+	var repairChainOnError bool
+	var lastSyncedTag bool
+	var renewBefore time.Duration
+	var lenientAnnotations bool
+	var terminalACMErrors bool
+	var userAgent string
+	var preferInUse bool
+	var waitForCacheSync bool
+	var sourceVersionTags bool
+	var arnStoreConfigMap string
+	var allowExpired bool
+	var keyPassphraseField string
+	var dryRun bool
+	var errorBackoffMax time.Duration
+	var annotationTags bool
+	var importThrottleRetries int
+	var syncAnnotation string
+	var domainAnnotation string
+	var awsTimeout time.Duration
+	var awsEndpoint string
+	var awsRegion string
+	var watchNamespaces string
+	var secretLabelSelector string
+	var initialSync bool
+	var initialSyncInterval time.Duration
+	var gcOrphans bool
+	var gcInterval time.Duration
+	var clusterName string
+	var allowPinnedARNs bool
 	var tlsOpts []func(*tls.Config)
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metrics endpoint binds to. Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
-	flag.BoolVar(&enableLeaderElection, "leader-elect", false, "Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	leaderElection.bind(flag.CommandLine)
 	flag.BoolVar(&secureMetrics, "metrics-secure", false, "If set, the metrics endpoint is served securely via HTTPS. Use --metrics-secure=false to use HTTP instead.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false, "If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&adminAddr, "admin-bind-address", "0", "The address the admin endpoint binds to. It accepts POST /reconcile?namespace=<ns>&name=<name> to force a reconcile of a Secret. Leave as 0 to disable the admin endpoint.")
 
+	flag.StringVar(&onEmptyChain, "on-empty-chain", string(controllers.EmptyChainWarn), "What to do when a certificate has no intermediate chain: warn (log and import), fail (skip the Secret) or proceed (import silently).")
+	flag.StringVar(&auditLogFile, "audit-log-file", "", "File to append JSON audit entries for mutating ACM calls to. Defaults to stdout.")
+	flag.BoolVar(&validateDomainDNS, "validate-domain-dns", false, "If set, a Secret's domain must resolve in DNS before it is synced.")
+	flag.StringVar(&dnsResolver, "dns-resolver", "", "host:port of the DNS server used by --validate-domain-dns. Defaults to the system resolver.")
+	flag.DurationVar(&dnsTimeout, "dns-timeout", 5*time.Second, "Timeout for each DNS lookup made by --validate-domain-dns.")
+	flag.StringVar(&expectedZones, "expected-zones", "", "Comma-separated DNS zones a Secret's domain must belong to. Empty allows any zone.")
+	flag.BoolVar(&validateSANs, "validate-sans", false, "If set, every subject alternative name of the certificate must also belong to --expected-zones; certificates with other names are not synced.")
+	flag.IntVar(&maxConcurrentImports, "max-concurrent-imports", 0, "Maximum number of in-flight ACM imports shared across all workers and regions. 0 means unlimited.")
+	flag.StringVar(&acmEventQueueURL, "acm-event-queue-url", "", "URL of an SQS queue fed by EventBridge ACM events. When set, Secrets are re-synced as soon as their ACM certificate changes externally.")
+	flag.BoolVar(&cleanupOnDelete, "cleanup-on-delete", false, "If set, the ACM certificate imported from a Secret is deleted when the Secret is deleted, unless the Secret is annotated with cert-sync.denyshubh.github.io/delete-protection: \"true\".")
+	flag.IntVar(&maxTags, "max-tags", 50, "Maximum number of tags applied to an ACM certificate. Built-in tags take priority over custom tags.")
+	flag.StringVar(&minNotBefore, "min-notbefore", "", "Only sync certificates issued (NotBefore) at or after this date, as RFC 3339 or YYYY-MM-DD. Empty syncs all certificates.")
+	flag.BoolVar(&importStaged, "import-staged", false, "If set, newly imported certificates are tagged cert-sync/stage=staged until the Secret is annotated with cert-sync.denyshubh.github.io/promote: \"true\".")
+	flag.StringVar(&domainAnnotations, "domain-annotations", strings.Join(controllers.DefaultDomainAnnotations, ","), "Comma-separated annotation keys to read a Secret's domain from, in order of precedence.")
+	flag.BoolVar(&strictPEM, "strict-pem", false, "If set, Secrets with unexpected data after the last PEM block are skipped instead of imported.")
+	flag.BoolVar(&annotateNotAfter, "annotate-notafter", false, "If set, the ACM certificate's expiry is written to the Secret annotation cert-sync.denyshubh.github.io/acm-notafter after each sync.")
+	flag.IntVar(&maxActiveSyncs, "max-active-syncs", 0, "Maximum number of distinct Secrets synced at once; excess Secrets are requeued shortly. 0 means unlimited.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1, "Number of Secrets reconciled in parallel. Secrets claiming the same domain are still synced one at a time.")
+	flag.BoolVar(&normalizeKeys, "normalize-private-keys", false, "If set, PKCS#1 and SEC1 private keys are converted to PKCS#8 before import.")
+
+	flag.BoolVar(&reuseTagged, "reuse-tagged-certificates", false, "If set, an ACM certificate tagged with the Secret's identity is reused before importing a new one, even if its domain doesn't match.")
+
+	flag.StringVar(&rootCABundle, "root-ca-bundle", "", "Path to a PEM bundle of root CAs. When set, Secrets whose chain doesn't verify against one of them are skipped.")
+
+	flag.BoolVar(&adoptIdentical, "adopt-identical-certificates", false, "If set, an untagged ACM certificate whose leaf and chain already match the Secret is adopted by tagging it on the first reconcile after start, rather than re-imported.")
+
+	flag.DurationVar(&chainExpiryWarning, "chain-expiry-warning", 0, "Warn when an intermediate certificate in a Secret's chain expires within this window, e.g. 720h. 0 disables the check.")
+	flag.IntVar(&metricsDomainLimit, "metrics-domain-limit", controllers.DefaultMetricsDomainLimit, "Maximum number of distinct domains labelling per-domain metrics. Secrets with further domains are reported under the \"__other__\" domain. 0 disables the cap.")
+
+	flag.DurationVar(&expiryPriorityWindow, "expiry-priority-window", 0, "If set, newly seen Secrets are enqueued with a delay of up to this window that grows with their certificate's remaining lifetime, so Secrets closest to expiry sync first after a restart. 0 enqueues immediately.")
+
+	flag.BoolVar(&pruneStaleTags, "prune-stale-tags", false, "If set, tags that are no longer desired for a Secret (e.g. removed from its tag template) are removed from the ACM certificate when it is re-imported.")
+
+	flag.StringVar(&stateConfigMap, "state-configmap", "", "<namespace>/<name> of a ConfigMap the active replica periodically writes its identity and sync counters to. Empty disables it.")
+
+	flag.IntVar(&scanThrottleRetries, "scan-throttle-retries", 5, "How many times a throttled ListCertificates or DescribeCertificate call is retried with back-off while scanning ACM, before the reconcile fails.")
+	flag.DurationVar(&scanThrottleMaxDelay, "scan-throttle-max-delay", 5*time.Second, "Maximum back-off between retries of a throttled ACM call.")
+
+	flag.DurationVar(&minRemainingValidity, "min-remaining-validity", 0, "Skip Secrets whose certificate expires within this duration, e.g. 24h, and wait for cert-manager to reissue it. 0 syncs every certificate.")
+
+	flag.BoolVar(&canonicalizeChain, "canonicalize-chain", false, "If set, the certificate chain is ordered leaf to root, de-duplicated and stripped of self-signed roots before it is imported or compared with ACM.")
+
+	flag.StringVar(&fieldManager, "field-manager", controllers.DefaultFieldManager, "Field manager used when writing annotations back to Secrets.")
+
+	flag.BoolVar(&mirrorACMErrors, "mirror-acm-errors", false, "If set, ACM validation errors (e.g. an invalid chain) are recorded as JSON in the Secret annotation cert-sync.denyshubh.github.io/acm-error until the next successful sync.")
+
+	flag.BoolVar(&refreshTagsOnStart, "refresh-tags-on-start", false, "If set, the tags of every synced ACM certificate are brought in line with the current tag configuration once at startup, without re-importing.")
+	flag.IntVar(&tagRefreshConcurrency, "tag-refresh-concurrency", 4, "Maximum number of certificates whose tags are updated at once by --refresh-tags-on-start.")
+
+	flag.BoolVar(&namespaceRegions, "namespace-regions", false, "If set, the annotation cert-sync.denyshubh.github.io/regions on a Namespace sets the default regions for its Secrets. The same annotation on a Secret always takes precedence.")
+
+	flag.StringVar(&keyUsage, "check-key-usage", string(controllers.KeyUsageIgnore), "What to do when a certificate's key usage doesn't allow TLS server auth: ignore (don't check), warn (log and import) or skip (skip the Secret).")
+
+	flag.BoolVar(&shutdownSummary, "shutdown-summary", false, "If set, a summary of the imports, updates and deletes made and the domains touched is logged on shutdown.")
+
+	flag.StringVar(&supersetMatch, "superset-match", string(controllers.SupersetMatchAccept), "What to do when an ACM certificate covers the Secret's domain and other domains: accept (update it) or reject (import a certificate for the Secret's domains only).")
+
+	flag.BoolVar(&contentHashTags, "content-hash-tag", true, "Tag imported certificates cert-sync/content-sha with a hash of their leaf and chain, which is compared instead of fetching the certificate from ACM. Set to false to leave the tag off.")
+
+	flag.StringVar(&onInUse, "on-in-use", string(controllers.InUseOrphan), "With --cleanup-on-delete, what to do when a deleted Secret's ACM certificate is still in use: orphan (leave it in ACM) or wait (keep the Secret until the certificate is detached, then delete it).")
+
+	flag.BoolVar(&splitCombinedPEM, "split-combined-pem", false, "If set, a certificate and private key concatenated in tls.crt or tls.key are split and imported as separate certificate and key.")
+
+	flag.DurationVar(&regionBackoffMax, "region-backoff-max", 0, "If set, Secrets whose ACM region can't be reached (DNS or connection failure) are retried with an exponential back-off capped at this delay. 0 disables the back-off.")
+
+	flag.BoolVar(&consolidateDuplicates, "consolidate-duplicates", false, "If set, duplicate ACM certificates holding a Secret's certificate and chain are deleted once per Secret after start-up, keeping the one in use or tagged for the Secret. Certificates in use or tagged for another Secret are kept.")
+
+	flag.StringVar(&logFormat, "log-format", "", "Log encoding: json (one structured object per line, for log ingestion) or console. Overrides --zap-encoder when set.")
+
+	flag.BoolVar(&runOnce, "run-once", false, "If set, sync every annotated Secret once and exit, with a nonzero exit code if any failed. For running as a Job.")
+
+	flag.BoolVar(&verifyTags, "verify-tags", false, "If set, tags missing from an ACM certificate are re-applied after it is updated, since ACM ignores tags on re-import. Keeps tags consistent across regions.")
+
+	flag.IntVar(&loopThreshold, "loop-threshold", 0, "If set, warn about a suspected reconcile loop when a Secret reconciles more than this many times within --loop-window. 0 disables the check.")
+	flag.DurationVar(&loopWindow, "loop-window", time.Minute, "Window over which --loop-threshold counts reconciles.")
+
+	flag.BoolVar(&credentialAnnotations, "credential-annotations", false, "If set, Secrets may choose the AWS profile and IAM role they are synced with through the cert-sync.denyshubh.github.io/aws-profile and role-arn annotations.")
+	flag.StringVar(&allowedProfiles, "allowed-aws-profiles", "", "Comma-separated patterns of the AWS profiles Secrets may select with --credential-annotations, e.g. 'team-*'. Secrets selecting any other aren't synced.")
+	flag.StringVar(&allowedRoleARNs, "allowed-role-arns", "", "Comma-separated patterns of the IAM roles Secrets may select with --credential-annotations, e.g. 'arn:aws:iam::123456789012:role/cert-sync-*'. Secrets selecting any other aren't synced.")
+	flag.IntVar(&acmClientPoolSize, "acm-client-pool-size", 32, "Maximum number of ACM clients, one per region, profile and role, kept for reuse. 0 keeps all of them.")
+
+	flag.BoolVar(&repairChainOnError, "repair-chain-on-error", false, "If set, an import ACM rejects because of its certificate chain is retried once with the chain reordered from the leaf's issuer up and stripped of duplicates and roots.")
+
+	flag.BoolVar(&lastSyncedTag, "last-synced-tag", false, "If set, the ACM certificate in each region is tagged cert-sync/last-synced with the time of its last successful sync.")
+
+	flag.DurationVar(&renewBefore, "renew-before", controllers.DefaultRenewBefore, "How long before its expiry the certificate in ACM is replaced. Secrets can override it with the cert-sync.denyshubh.github.io/renew-before annotation.")
+
+	flag.BoolVar(&lenientAnnotations, "lenient-annotations", false, "If set, sync-to-acm also accepts True, 1, yes and similar values, and Secrets with annotations that look like a misspelled sync-to-acm are logged once.")
+
+	flag.BoolVar(&terminalACMErrors, "terminal-acm-errors", false, "If set, a certificate ACM rejects as invalid is not retried until the certificate or key in the Secret change.")
+
+	flag.StringVar(&userAgent, "user-agent", awsclient.UserAgent, "Name appended, with the cert-sync version, to the User-Agent of AWS API calls so they can be identified in CloudTrail. Empty leaves the SDK's User-Agent unchanged.")
+
+	flag.BoolVar(&preferInUse, "prefer-in-use", false, "If set, when several ACM certificates match a domain the one attached to AWS resources is updated rather than the first one found.")
+
+	flag.BoolVar(&waitForCacheSync, "wait-for-cache-sync", false, "If set, reconciles that run before the Secret cache has synced are requeued instead of treating Secrets missing from the cache as deleted.")
+
+	flag.BoolVar(&sourceVersionTags, "source-version-tags", false, "If set, ACM certificates are tagged with the UID and resourceVersion of their Secret, so reconciles of an unchanged Secret skip the content comparison.")
+
+	flag.StringVar(&arnStoreConfigMap, "arn-store-configmap", "", "<namespace>/<name> of a ConfigMap the ARN each Secret was synced to is kept in, so it survives restarts. Empty keeps it in memory.")
+	flag.BoolVar(&allowExpired, "allow-expired", false, "If set, certificates that have already expired are imported instead of skipped. Meant for testing.")
+	flag.StringVar(&keyPassphraseField, "key-passphrase-field", controllers.DefaultKeyPassphraseField, "Secret field holding the passphrase of an encrypted PKCS#8 tls.key.")
+	flag.BoolVar(&dryRun, "dry-run", false, "If set, the ACM imports, updates, deletions and tag changes the controller would make are logged instead of made.")
+	flag.DurationVar(&errorBackoffMax, "error-backoff-max", controllers.DefaultErrorBackoffMax, "Cap of the per-Secret exponential back-off with which Secrets whose reconcile failed are retried, reset by the next success.")
+	flag.BoolVar(&annotationTags, "annotation-tags", false, "If set, Secret annotations of the form cert-sync.denyshubh.github.io/tag-<key>: <value> tag their ACM certificates, and those tags are kept in sync on every reconcile.")
+	flag.IntVar(&importThrottleRetries, "import-throttle-retries", 3, "How many times a throttled ImportCertificate call is retried with back-off before the reconcile fails.")
+	flag.StringVar(&syncAnnotation, "sync-annotation", controllers.DefaultSyncAnnotation, "Annotation key that opts a Secret into syncing when set to \"true\".")
+	flag.StringVar(&domainAnnotation, "domain-annotation", "", "Annotation key to read a Secret's domain from. Shorthand for --domain-annotations with a single key; the two can't be combined. Secrets without the annotation are synced under their certificate's common name or first DNS name.")
+	flag.DurationVar(&awsTimeout, "aws-timeout", controllers.DefaultACMTimeout, "Timeout for each ACM call, applied per page when listing certificates. A call that times out is retried with the Secret. 0 disables the timeout.")
+	flag.StringVar(&awsEndpoint, "aws-endpoint", "", "URL replacing the ACM endpoint, e.g. http://localhost:4566 for LocalStack or a VPC endpoint. Empty uses the standard endpoint of each region.")
+	flag.StringVar(&awsRegion, "aws-region", "", "Default AWS region, overriding AWS_REGION and the shared configuration. Regions chosen per Secret still apply.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", "", "Comma-separated namespaces to watch Secrets in. Empty watches every namespace.")
+	flag.StringVar(&secretLabelSelector, "secret-label-selector", "", "Label selector a Secret must match to be synced, e.g. team=web,env!=dev. Empty selects every Secret.")
+	flag.BoolVar(&initialSync, "initial-sync", false, "If set, every Secret asking to be synced is enqueued once at startup, so certificates that changed or expired while the controller was down are synced promptly.")
+	flag.DurationVar(&initialSyncInterval, "initial-sync-interval", controllers.DefaultInitialSyncInterval, "Pause between two Secrets enqueued by --initial-sync, bounding the load on ACM after a restart.")
+	flag.BoolVar(&gcOrphans, "gc-orphans", false, "If set, ACM certificates imported by this cluster from a Secret that no longer exists or no longer asks to be synced are periodically deleted, unless they are in use. Requires --cluster-name.")
+	flag.DurationVar(&gcInterval, "gc-interval", controllers.DefaultGCInterval, "Time between two passes of --gc-orphans.")
+	flag.StringVar(&clusterName, "cluster-name", "", "Name of this cluster, tagged on imported certificates as cert-sync/cluster so clusters sharing an AWS account can tell their certificates apart. Required by --gc-orphans.")
+	flag.BoolVar(&allowPinnedARNs, "allow-pinned-arns", false, "If set, an ARN set by hand in the cert-sync.denyshubh.github.io/acm-arn annotation targets the certificate even when it wasn't imported from the Secret. Otherwise such certificates are ignored, since anyone who can annotate a Secret could overwrite them.")
 	opts := zap.Options{
 		Development: true,
 	}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
-	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+	logOpts := []zap.Opts{zap.UseFlagOptions(&opts)}
+	var logFormatErr error
+	switch logFormat {
+	case "":
+	case "json":
+		logOpts = append(logOpts, zap.JSONEncoder())
+	case "console":
+		logOpts = append(logOpts, zap.ConsoleEncoder())
+	default:
+		logFormatErr = fmt.Errorf("must be one of json, console; got %q", logFormat)
+	}
+	ctrl.SetLogger(zap.New(logOpts...))
+	if logFormatErr != nil {
+		setupLog.Error(logFormatErr, "invalid --log-format")
+		os.Exit(1)
+	}
+	awsclient.UserAgent = userAgent
+	awsclient.ACMEndpoint = awsEndpoint
+	awsclient.Region = awsRegion
+	if domainAnnotation != "" {
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "domain-annotations" {
+				setupLog.Error(errors.New("--domain-annotation and --domain-annotations are mutually exclusive"), "invalid domain annotation flags")
+				os.Exit(1)
+			}
+		})
+		domainAnnotations = domainAnnotation
+	}
 
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
@@ -116,13 +357,11 @@ func main() {
 		metricsServerOptions.FilterProvider = filters.WithAuthenticationAndAuthorization
 	}
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	mgrOptions := ctrl.Options{
 		Scheme:                 scheme,
 		Metrics:                metricsServerOptions,
 		WebhookServer:          webhookServer,
 		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "cert-sync-leader-lock",
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -134,18 +373,316 @@ func main() {
 		// if you are doing or is intended to do any operation such as perform cleanups
 		// after the manager stops then its usage might be unsafe.
 		// LeaderElectionReleaseOnCancel: true,
-	})
+	}
+	if watchNamespaces != "" {
+		mgrOptions.Cache.DefaultNamespaces = map[string]cache.Config{}
+		for _, namespace := range splitList(watchNamespaces) {
+			mgrOptions.Cache.DefaultNamespaces[namespace] = cache.Config{}
+		}
+	}
+	leaderElection.apply(&mgrOptions)
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), mgrOptions)
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	var secretSelector labels.Selector
+	if secretLabelSelector != "" {
+		secretSelector, err = labels.Parse(secretLabelSelector)
+		if err != nil {
+			setupLog.Error(err, "invalid --secret-label-selector", "value", secretLabelSelector)
+			os.Exit(1)
+		}
+	}
+
+	emptyChainPolicy, err := controllers.ParseEmptyChainPolicy(onEmptyChain)
+	if err != nil {
+		setupLog.Error(err, "invalid --on-empty-chain")
+		os.Exit(1)
+	}
+	keyUsagePolicy, err := controllers.ParseKeyUsagePolicy(keyUsage)
+	if err != nil {
+		setupLog.Error(err, "invalid --check-key-usage")
+		os.Exit(1)
+	}
+	supersetMatchPolicy, err := controllers.ParseSupersetMatchPolicy(supersetMatch)
+	if err != nil {
+		setupLog.Error(err, "invalid --superset-match")
+		os.Exit(1)
+	}
+	inUsePolicy, err := controllers.ParseInUsePolicy(onInUse)
+	if err != nil {
+		setupLog.Error(err, "invalid --on-in-use")
+		os.Exit(1)
+	}
+
+	auditSink := os.Stdout
+	if auditLogFile != "" {
+		auditSink, err = os.OpenFile(auditLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			setupLog.Error(err, "unable to open audit log file", "path", auditLogFile)
+			os.Exit(1)
+		}
+		defer auditSink.Close()
+	}
+
+	var domainValidator *controllers.DomainValidator
+	if validateDomainDNS || expectedZones != "" {
+		domainValidator = &controllers.DomainValidator{Timeout: dnsTimeout}
+		if validateDomainDNS {
+			domainValidator.Resolver = controllers.NewDomainResolver(dnsResolver)
+		}
+		if expectedZones != "" {
+			domainValidator.Zones = strings.Split(expectedZones, ",")
+			domainValidator.CheckSANs = validateSANs
+		}
+	}
+	if validateSANs && expectedZones == "" {
+		setupLog.Error(nil, "--validate-sans requires --expected-zones")
+		os.Exit(1)
+	}
+
+	var minNotBeforeTime time.Time
+	if minNotBefore != "" {
+		minNotBeforeTime, err = time.Parse(time.RFC3339, minNotBefore)
+		if err != nil {
+			minNotBeforeTime, err = time.Parse(time.DateOnly, minNotBefore)
+		}
+		if err != nil {
+			setupLog.Error(err, "invalid --min-notbefore", "value", minNotBefore)
+			os.Exit(1)
+		}
+	}
+
+	var rootCAs *x509.CertPool
+	if rootCABundle != "" {
+		rootCAs, err = controllers.LoadRootBundle(rootCABundle)
+		if err != nil {
+			setupLog.Error(err, "unable to load root CA bundle", "path", rootCABundle)
+			os.Exit(1)
+		}
+	}
+
+	// The ACM client of the default credential context, shared by the
+	// reconciler and the background jobs
+	acmClient, err := awsclient.NewACMClient(context.Background())
+	if err != nil {
+		setupLog.Error(err, "unable to create ACM client")
+		os.Exit(1)
+	}
+
 	// Set up the SecretReconciler
-	if err = (&controllers.SecretReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Secret"),
-	}).SetupWithManager(mgr); err != nil {
+	secretReconciler := &controllers.SecretReconciler{
+		Client:                  mgr.GetClient(),
+		ACM:                     acmClient,
+		Scheme:                  mgr.GetScheme(),
+		Log:                     ctrl.Log.WithName("controllers").WithName("Secret"),
+		Audit:                   controllers.NewAuditLogger(auditSink),
+		DomainAnnotations:       splitList(domainAnnotations),
+		SecretSelector:          secretSelector,
+		ClusterName:             clusterName,
+		AllowPinnedARNs:         allowPinnedARNs,
+		CleanupOnDelete:         cleanupOnDelete,
+		ImportStaged:            importStaged,
+		MaxTags:                 maxTags,
+		ReuseTaggedCertificates: reuseTagged,
+		AdoptIdentical:          adoptIdentical,
+		ConsolidateDuplicates:   consolidateDuplicates,
+		ContentHashTags:         contentHashTags,
+		PruneStaleTags:          pruneStaleTags,
+		VerifyTags:              verifyTags,
+		LastSyncedTag:           lastSyncedTag,
+		ScanThrottleRetries:     scanThrottleRetries,
+		ScanThrottleMaxDelay:    scanThrottleMaxDelay,
+		ImportThrottleRetries:   importThrottleRetries,
+		SyncAnnotation:          syncAnnotation,
+		ACMTimeout:              awsTimeout,
+		ImportLimiter:           controllers.NewImportLimiter(maxConcurrentImports),
+		SyncLimiter:             controllers.NewSyncLimiter(maxActiveSyncs),
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		DomainValidator:         domainValidator,
+		MinNotBefore:            minNotBeforeTime,
+		MinRemainingValidity:    minRemainingValidity,
+		RenewBefore:             renewBefore,
+		LenientAnnotations:      lenientAnnotations,
+		TerminalACMErrors:       terminalACMErrors,
+		PreferInUse:             preferInUse,
+		WaitForCacheSync:        waitForCacheSync,
+		SourceVersionTags:       sourceVersionTags,
+		AllowExpired:            allowExpired,
+		KeyPassphraseField:      keyPassphraseField,
+		DryRun:                  dryRun,
+		ErrorBackoffMax:         errorBackoffMax,
+		AnnotationTags:          annotationTags,
+		AnnotateNotAfter:        annotateNotAfter,
+		FieldManager:            fieldManager,
+		MirrorACMErrors:         mirrorACMErrors,
+		NamespaceRegions:        namespaceRegions,
+		RegionBackoffMax:        regionBackoffMax,
+		LoopThreshold:           loopThreshold,
+		LoopWindow:              loopWindow,
+		CredentialAnnotations:   credentialAnnotations,
+		AllowedProfiles:         splitList(allowedProfiles),
+		AllowedRoleARNs:         splitList(allowedRoleARNs),
+		ACMClientPoolSize:       acmClientPoolSize,
+		NormalizeKeyToPKCS8:     normalizeKeys,
+		StrictPEM:               strictPEM,
+		SplitCombinedPEM:        splitCombinedPEM,
+		ChainExpiryWarning:      chainExpiryWarning,
+		MetricsDomainLimit:      metricsDomainLimit,
+		RootCAs:                 rootCAs,
+		CanonicalizeChain:       canonicalizeChain,
+		RepairChainOnError:      repairChainOnError,
+		KeyUsagePolicy:          keyUsagePolicy,
+		SupersetMatch:           supersetMatchPolicy,
+		OnInUse:                 inUsePolicy,
+		OnEmptyChain:            emptyChainPolicy,
+		ExpiryPriorityWindow:    expiryPriorityWindow,
+	}
+
+	triggers := make(chan event.GenericEvent, 16)
+
+	if adminAddr != "" && adminAddr != "0" {
+		adminServer := controllers.NewAdminServer(adminAddr, triggers, ctrl.Log.WithName("admin"))
+		secretReconciler.Triggers = triggers
+		if err := mgr.Add(adminServer); err != nil {
+			setupLog.Error(err, "unable to set up admin server")
+			os.Exit(1)
+		}
+	}
+
+	if acmEventQueueURL != "" {
+		sqsClient, err := awsclient.NewSQSClient(context.Background())
+		if err != nil {
+			setupLog.Error(err, "unable to create SQS client")
+			os.Exit(1)
+		}
+		secretReconciler.Index = controllers.NewCertificateIndex()
+		secretReconciler.Triggers = triggers
+		if err := mgr.Add(&controllers.ACMEventConsumer{
+			Queue:    sqsClient,
+			QueueURL: acmEventQueueURL,
+			Index:    secretReconciler.Index,
+			Events:   triggers,
+			Log:      ctrl.Log.WithName("acm-events"),
+		}); err != nil {
+			setupLog.Error(err, "unable to set up ACM event consumer")
+			os.Exit(1)
+		}
+	}
+
+	if initialSync {
+		secretReconciler.Triggers = triggers
+		if err := mgr.Add(&controllers.InitialSync{
+			Reconciler: secretReconciler,
+			Events:     triggers,
+			Interval:   initialSyncInterval,
+			Log:        ctrl.Log.WithName("initial-sync"),
+		}); err != nil {
+			setupLog.Error(err, "unable to set up initial sync")
+			os.Exit(1)
+		}
+	}
+
+	if arnStoreConfigMap != "" {
+		namespace, name, ok := strings.Cut(arnStoreConfigMap, "/")
+		if !ok || namespace == "" || name == "" {
+			setupLog.Error(nil, "invalid --arn-store-configmap, expected <namespace>/<name>", "value", arnStoreConfigMap)
+			os.Exit(1)
+		}
+		secretReconciler.ARNStore = controllers.NewConfigMapARNStore(mgr.GetClient(), mgr.GetAPIReader(), types.NamespacedName{Namespace: namespace, Name: name})
+	}
+
+	if stateConfigMap != "" {
+		namespace, name, ok := strings.Cut(stateConfigMap, "/")
+		if !ok || namespace == "" || name == "" {
+			setupLog.Error(nil, "invalid --state-configmap, expected <namespace>/<name>", "value", stateConfigMap)
+			os.Exit(1)
+		}
+		identity, err := os.Hostname()
+		if err != nil {
+			setupLog.Error(err, "unable to determine replica identity")
+			os.Exit(1)
+		}
+		secretReconciler.State = controllers.NewStateReporter(mgr.GetClient(), types.NamespacedName{Namespace: namespace, Name: name}, identity, ctrl.Log.WithName("state"))
+		if err := mgr.Add(secretReconciler.State); err != nil {
+			setupLog.Error(err, "unable to set up state reporter")
+			os.Exit(1)
+		}
+	}
+
+	if refreshTagsOnStart {
+		var refreshACM awsclient.ACMAPI = acmClient
+		if awsTimeout > 0 {
+			refreshACM = controllers.NewTimeoutACM(refreshACM, awsTimeout)
+		}
+		if dryRun {
+			refreshACM = controllers.NewDryRunACM(refreshACM, ctrl.Log.WithName("tag-refresh"))
+		}
+		if err := mgr.Add(&controllers.TagRefresher{
+			Reconciler:  secretReconciler,
+			ACM:         refreshACM,
+			Concurrency: tagRefreshConcurrency,
+			Log:         ctrl.Log.WithName("tag-refresh"),
+		}); err != nil {
+			setupLog.Error(err, "unable to set up tag refresh")
+			os.Exit(1)
+		}
+	}
+
+	if gcOrphans {
+		if clusterName == "" {
+			setupLog.Error(nil, "--gc-orphans requires --cluster-name, so certificates of other clusters sharing the AWS account are never deleted")
+			os.Exit(1)
+		}
+		var gcACM awsclient.ACMAPI = acmClient
+		if awsTimeout > 0 {
+			gcACM = controllers.NewTimeoutACM(gcACM, awsTimeout)
+		}
+		if dryRun {
+			gcACM = controllers.NewDryRunACM(gcACM, ctrl.Log.WithName("gc"))
+		}
+		if err := mgr.Add(&controllers.OrphanCollector{
+			Reconciler: secretReconciler,
+			ACM:        gcACM,
+			Interval:   gcInterval,
+			Log:        ctrl.Log.WithName("gc"),
+		}); err != nil {
+			setupLog.Error(err, "unable to set up orphan collection")
+			os.Exit(1)
+		}
+	}
+
+	if shutdownSummary {
+		secretReconciler.Summary = controllers.NewSessionSummary(ctrl.Log.WithName("summary"))
+		if err := mgr.Add(secretReconciler.Summary); err != nil {
+			setupLog.Error(err, "unable to set up session summary")
+			os.Exit(1)
+		}
+	}
+
+	if runOnce {
+		// The manager's cache is never started, so read straight from the API server
+		directClient, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+		if err != nil {
+			setupLog.Error(err, "unable to create client")
+			os.Exit(1)
+		}
+		secretReconciler.Client = directClient
+
+		report, err := secretReconciler.RunOnce(ctrl.SetupSignalHandler())
+		if err != nil {
+			setupLog.Error(err, "unable to list Secrets")
+			os.Exit(1)
+		}
+		for key, err := range report.Failed {
+			setupLog.Error(err, "Secret failed to sync", "secret", key)
+		}
+		os.Exit(report.ExitCode())
+	}
+
+	if err = secretReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Secret")
 		os.Exit(1)
 	}
@@ -167,3 +704,15 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// splitList splits a comma-separated flag value, trimming the entries and
+// dropping empty ones.
+func splitList(value string) []string {
+	var list []string
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
@@ -0,0 +1,36 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitList(t *testing.T) {
+	cases := map[string][]string{
+		"":                                      nil,
+		"cert-manager.io/common-name":           {"cert-manager.io/common-name"},
+		" domain , cert-manager.io/common-name": {"domain", "cert-manager.io/common-name"},
+		"domain,,":                              {"domain"},
+	}
+	for value, want := range cases {
+		if got := splitList(value); !slices.Equal(got, want) {
+			t.Errorf("splitList(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
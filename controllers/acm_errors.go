@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/aws/smithy-go"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// acmErrorAnnotation holds the last ACM validation error for the Secret as
+// JSON (see acmErrorDetail). It is removed after the next successful sync.
+const acmErrorAnnotation = "cert-sync.denyshubh.github.io/acm-error"
+
+// maxACMErrorMessage caps the length of a mirrored error message.
+const maxACMErrorMessage = 512
+
+// pemPattern matches PEM blocks, which must never be copied into an
+// annotation even if an error message echoes them.
+var pemPattern = regexp.MustCompile(`(?s)-----BEGIN [^-]+-----.*?(-----END [^-]+-----|$)`)
+
+// acmErrorDetail is the structured form of an ACM validation error surfaced
+// on the Secret.
+type acmErrorDetail struct {
+	Operation string `json:"operation"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Time      string `json:"time"`
+}
+
+// validationErrorDetail returns the detail of err if ACM rejected the request
+// as invalid, as opposed to failing for transient reasons.
+func validationErrorDetail(operation string, err error, now time.Time) (*acmErrorDetail, bool) {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return nil, false
+	}
+
+	switch apiErr.ErrorCode() {
+	case "ValidationException", "InvalidParameterException", "InvalidTagException", "TagPolicyException":
+	default:
+		return nil, false
+	}
+
+	message := pemPattern.ReplaceAllString(apiErr.ErrorMessage(), "[redacted]")
+	if len(message) > maxACMErrorMessage {
+		message = message[:maxACMErrorMessage] + "..."
+	}
+	return &acmErrorDetail{
+		Operation: operation,
+		Code:      apiErr.ErrorCode(),
+		Message:   message,
+		Time:      now.UTC().Format(time.RFC3339),
+	}, true
+}
+
+// mirrorACMError records a validation error returned by operation on secret
+// so it can be correlated without access to the controller logs. Other
+// errors are ignored. It does nothing unless MirrorACMErrors is set.
+func (r *SecretReconciler) mirrorACMError(ctx context.Context, secret *corev1.Secret, operation string, err error) error {
+	if !r.MirrorACMErrors {
+		return nil
+	}
+	detail, ok := validationErrorDetail(operation, err, time.Now())
+	if !ok {
+		return nil
+	}
+
+	value, err := json.Marshal(detail)
+	if err != nil {
+		return err
+	}
+
+	original := secret.DeepCopy()
+	if secret.Annotations == nil {
+		secret.Annotations = map[string]string{}
+	}
+	secret.Annotations[acmErrorAnnotation] = string(value)
+	return r.writeBack(ctx, original, secret)
+}
+
+// clearACMError removes a previously mirrored ACM error from secret.
+func (r *SecretReconciler) clearACMError(ctx context.Context, secret *corev1.Secret) error {
+	if _, exists := secret.Annotations[acmErrorAnnotation]; !exists {
+		return nil
+	}
+
+	original := secret.DeepCopy()
+	delete(secret.Annotations, acmErrorAnnotation)
+	return r.writeBack(ctx, original, secret)
+}
@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// CertificateIndex maps ACM certificate ARNs to the Secret they were synced
+// from. The reconciler fills it in so external ACM events can be routed back
+// to the owning Secret.
+type CertificateIndex struct {
+	mu    sync.RWMutex
+	byArn map[string]types.NamespacedName
+}
+
+// NewCertificateIndex returns an empty CertificateIndex.
+func NewCertificateIndex() *CertificateIndex {
+	return &CertificateIndex{byArn: map[string]types.NamespacedName{}}
+}
+
+// Set records that certificateArn belongs to secret. No-op on a nil index.
+func (i *CertificateIndex) Set(certificateArn string, secret types.NamespacedName) {
+	if i == nil || certificateArn == "" {
+		return
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.byArn[certificateArn] = secret
+}
+
+// Get returns the Secret that certificateArn was synced from.
+func (i *CertificateIndex) Get(certificateArn string) (types.NamespacedName, bool) {
+	if i == nil {
+		return types.NamespacedName{}, false
+	}
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	secret, ok := i.byArn[certificateArn]
+	return secret, ok
+}
+
+// SQSAPI is the subset of the SQS client used by ACMEventConsumer.
+type SQSAPI interface {
+	ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error)
+}
+
+// acmEvent is the part of an EventBridge event for ACM that we care about.
+// Native ACM events (e.g. "ACM Certificate Expired") list the certificate in
+// Resources; CloudTrail API events (e.g. DeleteCertificate) carry it in the
+// request parameters.
+type acmEvent struct {
+	Source     string   `json:"source"`
+	DetailType string   `json:"detail-type"`
+	Resources  []string `json:"resources"`
+	Detail     struct {
+		EventName         string `json:"eventName"`
+		RequestParameters struct {
+			CertificateArn string `json:"certificateArn"`
+		} `json:"requestParameters"`
+	} `json:"detail"`
+}
+
+// certificateArns returns the ACM certificate ARNs referenced by the event.
+func (e *acmEvent) certificateArns() []string {
+	if e.Source != "aws.acm" {
+		return nil
+	}
+	arns := append([]string{}, e.Resources...)
+	if arn := e.Detail.RequestParameters.CertificateArn; arn != "" {
+		arns = append(arns, arn)
+	}
+	return arns
+}
+
+// ACMEventConsumer polls an SQS queue fed by EventBridge ACM events and
+// enqueues the Secret owning each referenced certificate, so external changes
+// such as a deleted certificate are corrected without waiting for the next
+// periodic resync.
+type ACMEventConsumer struct {
+	Queue    SQSAPI
+	QueueURL string
+	Index    *CertificateIndex
+	Events   chan<- event.GenericEvent
+	Log      logr.Logger
+}
+
+// Start polls the queue until ctx is cancelled. It implements
+// manager.Runnable.
+func (c *ACMEventConsumer) Start(ctx context.Context) error {
+	c.Log.Info("Starting ACM event consumer", "queueURL", c.QueueURL)
+	for ctx.Err() == nil {
+		if err := c.poll(ctx); err != nil && ctx.Err() == nil {
+			c.Log.Error(err, "Failed to receive ACM events")
+			select {
+			case <-ctx.Done():
+			case <-time.After(10 * time.Second):
+			}
+		}
+	}
+	return nil
+}
+
+// poll receives one batch of messages and handles them.
+func (c *ACMEventConsumer) poll(ctx context.Context) error {
+	output, err := c.Queue.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(c.QueueURL),
+		MaxNumberOfMessages: 10,
+		WaitTimeSeconds:     20,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, msg := range output.Messages {
+		c.handle(ctx, aws.ToString(msg.Body))
+
+		if _, err := c.Queue.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+			QueueUrl:      aws.String(c.QueueURL),
+			ReceiptHandle: msg.ReceiptHandle,
+		}); err != nil {
+			c.Log.Error(err, "Failed to delete ACM event message", "messageId", aws.ToString(msg.MessageId))
+		}
+	}
+	return nil
+}
+
+// handle enqueues the Secrets referenced by a single event body.
+func (c *ACMEventConsumer) handle(ctx context.Context, body string) {
+	var evt acmEvent
+	if err := json.Unmarshal([]byte(body), &evt); err != nil {
+		c.Log.Error(err, "Ignoring malformed ACM event")
+		return
+	}
+
+	for _, arn := range evt.certificateArns() {
+		key, ok := c.Index.Get(arn)
+		if !ok {
+			continue
+		}
+
+		c.Log.Info("ACM event for synced certificate; enqueuing Secret", "detailType", evt.DetailType, "eventName", evt.Detail.EventName, "certificateArn", arn, "secret", key)
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}
+		select {
+		case c.Events <- event.GenericEvent{Object: secret}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
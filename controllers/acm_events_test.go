@@ -0,0 +1,108 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+const deleteCertificateEvent = `{
+  "source": "aws.acm",
+  "detail-type": "AWS API Call via CloudTrail",
+  "resources": [],
+  "detail": {
+    "eventName": "DeleteCertificate",
+    "requestParameters": {"certificateArn": "arn:aws:acm:us-east-1:123456789012:certificate/abc"}
+  }
+}`
+
+// fakeQueue delivers its messages once and records deletions.
+type fakeQueue struct {
+	messages []sqstypes.Message
+	deleted  []string
+}
+
+func (q *fakeQueue) ReceiveMessage(_ context.Context, _ *sqs.ReceiveMessageInput, _ ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	if len(q.messages) == 0 {
+		return nil, errors.New("queue drained")
+	}
+	out := &sqs.ReceiveMessageOutput{Messages: q.messages}
+	q.messages = nil
+	return out, nil
+}
+
+func (q *fakeQueue) DeleteMessage(_ context.Context, params *sqs.DeleteMessageInput, _ ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
+	q.deleted = append(q.deleted, aws.ToString(params.ReceiptHandle))
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+var _ = Describe("ACMEventConsumer", func() {
+	var (
+		queue    *fakeQueue
+		events   chan event.GenericEvent
+		consumer *ACMEventConsumer
+	)
+
+	BeforeEach(func() {
+		queue = &fakeQueue{}
+		events = make(chan event.GenericEvent, 4)
+		index := NewCertificateIndex()
+		index.Set("arn:aws:acm:us-east-1:123456789012:certificate/abc", types.NamespacedName{Namespace: "prod", Name: "web-tls"})
+		consumer = &ACMEventConsumer{Queue: queue, QueueURL: "https://sqs.example/queue", Index: index, Events: events, Log: logr.Discard()}
+	})
+
+	It("enqueues the owning Secret for re-import when its certificate is deleted", func() {
+		queue.messages = []sqstypes.Message{{Body: aws.String(deleteCertificateEvent), ReceiptHandle: aws.String("r1")}}
+
+		Expect(consumer.poll(context.Background())).To(Succeed())
+
+		var evt event.GenericEvent
+		Expect(events).To(Receive(&evt))
+		Expect(evt.Object.GetNamespace()).To(Equal("prod"))
+		Expect(evt.Object.GetName()).To(Equal("web-tls"))
+		Expect(queue.deleted).To(Equal([]string{"r1"}))
+	})
+
+	It("ignores events for certificates it does not manage", func() {
+		queue.messages = []sqstypes.Message{{
+			Body:          aws.String(`{"source":"aws.acm","detail-type":"ACM Certificate Expired","resources":["arn:aws:acm:us-east-1:123456789012:certificate/other"]}`),
+			ReceiptHandle: aws.String("r2"),
+		}}
+
+		Expect(consumer.poll(context.Background())).To(Succeed())
+		Expect(events).NotTo(Receive())
+		Expect(queue.deleted).To(Equal([]string{"r2"}))
+	})
+
+	It("drops malformed messages", func() {
+		queue.messages = []sqstypes.Message{{Body: aws.String("not json"), ReceiptHandle: aws.String("r3")}}
+
+		Expect(consumer.poll(context.Background())).To(Succeed())
+		Expect(events).NotTo(Receive())
+		Expect(queue.deleted).To(Equal([]string{"r3"}))
+	})
+})
@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+// DefaultACMTimeout bounds each ACM call, so a hung connection can't block a
+// worker indefinitely.
+const DefaultACMTimeout = 30 * time.Second
+
+// timeoutACM bounds every call to ACMAPI by timeout. Paginators make one call
+// per page, so a long scan is bounded per page rather than as a whole.
+type timeoutACM struct {
+	awsclient.ACMAPI
+	timeout time.Duration
+}
+
+// NewTimeoutACM wraps acmClient so that each call fails with
+// context.DeadlineExceeded after timeout.
+func NewTimeoutACM(acmClient awsclient.ACMAPI, timeout time.Duration) awsclient.ACMAPI {
+	return &timeoutACM{ACMAPI: acmClient, timeout: timeout}
+}
+
+// errACMTimeout marks a call cut off by the ACM timeout, as opposed to one
+// whose caller's context expired or was cancelled.
+var errACMTimeout = errors.New("ACM call timed out")
+
+// withTimeout calls call with ctx bounded by timeout. An error caused by the
+// timeout itself wraps errACMTimeout.
+func withTimeout[T any](ctx context.Context, timeout time.Duration, call func(context.Context) (T, error)) (T, error) {
+	callCtx, cancel := context.WithTimeoutCause(ctx, timeout, errACMTimeout)
+	defer cancel()
+	out, err := call(callCtx)
+	if err != nil && errors.Is(context.Cause(callCtx), errACMTimeout) {
+		err = fmt.Errorf("%w after %s: %w", errACMTimeout, timeout, err)
+	}
+	return out, err
+}
+
+func (t *timeoutACM) ListCertificates(ctx context.Context, params *acm.ListCertificatesInput, optFns ...func(*acm.Options)) (*acm.ListCertificatesOutput, error) {
+	return withTimeout(ctx, t.timeout, func(ctx context.Context) (*acm.ListCertificatesOutput, error) {
+		return t.ACMAPI.ListCertificates(ctx, params, optFns...)
+	})
+}
+
+func (t *timeoutACM) DescribeCertificate(ctx context.Context, params *acm.DescribeCertificateInput, optFns ...func(*acm.Options)) (*acm.DescribeCertificateOutput, error) {
+	return withTimeout(ctx, t.timeout, func(ctx context.Context) (*acm.DescribeCertificateOutput, error) {
+		return t.ACMAPI.DescribeCertificate(ctx, params, optFns...)
+	})
+}
+
+func (t *timeoutACM) GetCertificate(ctx context.Context, params *acm.GetCertificateInput, optFns ...func(*acm.Options)) (*acm.GetCertificateOutput, error) {
+	return withTimeout(ctx, t.timeout, func(ctx context.Context) (*acm.GetCertificateOutput, error) {
+		return t.ACMAPI.GetCertificate(ctx, params, optFns...)
+	})
+}
+
+func (t *timeoutACM) ImportCertificate(ctx context.Context, params *acm.ImportCertificateInput, optFns ...func(*acm.Options)) (*acm.ImportCertificateOutput, error) {
+	return withTimeout(ctx, t.timeout, func(ctx context.Context) (*acm.ImportCertificateOutput, error) {
+		return t.ACMAPI.ImportCertificate(ctx, params, optFns...)
+	})
+}
+
+func (t *timeoutACM) DeleteCertificate(ctx context.Context, params *acm.DeleteCertificateInput, optFns ...func(*acm.Options)) (*acm.DeleteCertificateOutput, error) {
+	return withTimeout(ctx, t.timeout, func(ctx context.Context) (*acm.DeleteCertificateOutput, error) {
+		return t.ACMAPI.DeleteCertificate(ctx, params, optFns...)
+	})
+}
+
+func (t *timeoutACM) ListTagsForCertificate(ctx context.Context, params *acm.ListTagsForCertificateInput, optFns ...func(*acm.Options)) (*acm.ListTagsForCertificateOutput, error) {
+	return withTimeout(ctx, t.timeout, func(ctx context.Context) (*acm.ListTagsForCertificateOutput, error) {
+		return t.ACMAPI.ListTagsForCertificate(ctx, params, optFns...)
+	})
+}
+
+func (t *timeoutACM) AddTagsToCertificate(ctx context.Context, params *acm.AddTagsToCertificateInput, optFns ...func(*acm.Options)) (*acm.AddTagsToCertificateOutput, error) {
+	return withTimeout(ctx, t.timeout, func(ctx context.Context) (*acm.AddTagsToCertificateOutput, error) {
+		return t.ACMAPI.AddTagsToCertificate(ctx, params, optFns...)
+	})
+}
+
+func (t *timeoutACM) RemoveTagsFromCertificate(ctx context.Context, params *acm.RemoveTagsFromCertificateInput, optFns ...func(*acm.Options)) (*acm.RemoveTagsFromCertificateOutput, error) {
+	return withTimeout(ctx, t.timeout, func(ctx context.Context) (*acm.RemoveTagsFromCertificateOutput, error) {
+		return t.ACMAPI.RemoveTagsFromCertificate(ctx, params, optFns...)
+	})
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// slowACM delays every ListCertificates page by delay, returning pages
+// pages, and never answers DescribeCertificate.
+type slowACM struct {
+	*fakeACM
+	delay time.Duration
+	pages int
+}
+
+func (s *slowACM) ListCertificates(ctx context.Context, params *acm.ListCertificatesInput, _ ...func(*acm.Options)) (*acm.ListCertificatesOutput, error) {
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	page, _ := strconv.Atoi(aws.ToString(params.NextToken))
+	out := &acm.ListCertificatesOutput{}
+	if page+1 < s.pages {
+		out.NextToken = aws.String(strconv.Itoa(page + 1))
+	}
+	return out, nil
+}
+
+func (s *slowACM) DescribeCertificate(ctx context.Context, _ *acm.DescribeCertificateInput, _ ...func(*acm.Options)) (*acm.DescribeCertificateOutput, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+var _ = Describe("ACM call timeouts", func() {
+	It("fails a call that outlasts the timeout", func() {
+		acmClient := NewTimeoutACM(&slowACM{fakeACM: newFakeACM()}, 20*time.Millisecond)
+		_, err := acmClient.DescribeCertificate(context.Background(), &acm.DescribeCertificateInput{})
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+		Expect(isRegionUnreachable(err)).To(BeTrue())
+	})
+
+	It("doesn't blame the region when the caller's context expires first", func() {
+		acmClient := NewTimeoutACM(&slowACM{fakeACM: newFakeACM()}, time.Minute)
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err := acmClient.DescribeCertificate(ctx, &acm.DescribeCertificateInput{})
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+		Expect(isRegionUnreachable(err)).To(BeFalse())
+	})
+
+	It("doesn't blame the region when the caller is cancelled", func() {
+		acmClient := NewTimeoutACM(&slowACM{fakeACM: newFakeACM()}, time.Minute)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := acmClient.DescribeCertificate(ctx, &acm.DescribeCertificateInput{})
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+		Expect(isRegionUnreachable(err)).To(BeFalse())
+	})
+
+	It("bounds a scan per page rather than as a whole", func() {
+		acmClient := NewTimeoutACM(&slowACM{fakeACM: newFakeACM(), delay: 20 * time.Millisecond, pages: 4}, 60*time.Millisecond)
+		certificate, err := (&SecretReconciler{Log: logr.Discard()}).findSecretByDomain(context.Background(), acmClient, "example.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(certificate).To(BeNil())
+	})
+
+	It("fails the reconcile when ACM hangs", func() {
+		_, intermediate, leaf := newTestChain("example.com")
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "prod",
+				Name:      "web-tls",
+				Annotations: map[string]string{
+					"sync-to-acm":                 "true",
+					"cert-manager.io/common-name": "example.com",
+					arnAnnotation:                 "arn:aws:acm:us-east-1:123456789012:certificate/1",
+				},
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       append(append([]byte{}, leaf.PEM...), intermediate.PEM...),
+				corev1.TLSPrivateKeyKey: leaf.keyPEM(),
+			},
+		}
+		r := &SecretReconciler{
+			Client:     fake.NewClientBuilder().WithObjects(secret).Build(),
+			Log:        logr.Discard(),
+			ACM:        &slowACM{fakeACM: newFakeACM()},
+			ACMTimeout: 20 * time.Millisecond,
+		}
+
+		_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+		Expect(isRegionUnreachable(err)).To(BeTrue())
+	})
+})
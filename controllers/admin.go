@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// AdminServer exposes operator-initiated actions over HTTP. It lets an operator
+// force a reconcile of a single Secret without editing the Secret itself, e.g.
+// during incident response.
+//
+//	curl -X POST 'http://<addr>/reconcile?namespace=default&name=my-tls'
+type AdminServer struct {
+	// BindAddress is the address the admin endpoint listens on.
+	BindAddress string
+	// Events receives a GenericEvent for every accepted reconcile request. The
+	// SecretReconciler consumes it as a watch source.
+	Events chan event.GenericEvent
+	Log    logr.Logger
+}
+
+// NewAdminServer returns an AdminServer publishing to events.
+func NewAdminServer(bindAddress string, events chan event.GenericEvent, log logr.Logger) *AdminServer {
+	return &AdminServer{
+		BindAddress: bindAddress,
+		Events:      events,
+		Log:         log,
+	}
+}
+
+// ServeHTTP handles POST /reconcile?namespace=<ns>&name=<name>.
+func (a *AdminServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/reconcile" {
+		http.NotFound(w, req)
+		return
+	}
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	namespace := req.URL.Query().Get("namespace")
+	name := req.URL.Query().Get("name")
+	if namespace == "" || name == "" {
+		http.Error(w, "namespace and name are required", http.StatusBadRequest)
+		return
+	}
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+	select {
+	case a.Events <- event.GenericEvent{Object: secret}:
+		a.Log.Info("Enqueued Secret for reconcile", "namespace", namespace, "name", name)
+		w.WriteHeader(http.StatusAccepted)
+	case <-req.Context().Done():
+		http.Error(w, "request cancelled", http.StatusServiceUnavailable)
+	}
+}
+
+// Start runs the admin HTTP server until ctx is cancelled. It implements
+// manager.Runnable so it only serves while this replica is the leader.
+func (a *AdminServer) Start(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:              a.BindAddress,
+		Handler:           a,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	a.Log.Info("Starting admin server", "address", a.BindAddress)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
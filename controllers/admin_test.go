@@ -0,0 +1,62 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+var _ = Describe("AdminServer", func() {
+	var admin *AdminServer
+
+	BeforeEach(func() {
+		admin = NewAdminServer(":0", make(chan event.GenericEvent, 1), logr.Discard())
+	})
+
+	It("enqueues the named Secret", func() {
+		rec := httptest.NewRecorder()
+		admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/reconcile?namespace=prod&name=web-tls", nil))
+
+		Expect(rec.Code).To(Equal(http.StatusAccepted))
+		var evt event.GenericEvent
+		Expect(admin.Events).To(Receive(&evt))
+		Expect(evt.Object.GetNamespace()).To(Equal("prod"))
+		Expect(evt.Object.GetName()).To(Equal("web-tls"))
+	})
+
+	It("rejects requests without a name", func() {
+		rec := httptest.NewRecorder()
+		admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/reconcile?namespace=prod", nil))
+
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(admin.Events).NotTo(Receive())
+	})
+
+	It("rejects non-POST requests", func() {
+		rec := httptest.NewRecorder()
+		admin.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/reconcile?namespace=prod&name=web-tls", nil))
+
+		Expect(rec.Code).To(Equal(http.StatusMethodNotAllowed))
+		Expect(admin.Events).NotTo(Receive())
+	})
+})
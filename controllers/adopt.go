@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+// adoptIdentical takes ownership of the certificate at certificateArn by
+// tagging it with secret's identity, provided no Secret owns it yet and it
+// already holds exactly the Secret's leaf and chain, e.g. because an older
+// controller version imported it without tags. It only runs on the first reconcile of each Secret after
+// the controller starts, so upgrades don't trigger a wave of re-imports and
+// steady-state reconciles don't fetch every certificate. It reports whether
+// the certificate was adopted.
+func (r *SecretReconciler) adoptIdentical(ctx context.Context, acmClient awsclient.ACMAPI, secret *corev1.Secret, certificateArn *string, leafPEM, chainPEM []byte) (bool, error) {
+	if !r.AdoptIdentical {
+		return false, nil
+	}
+	key := client.ObjectKeyFromObject(secret)
+	if _, seen := r.adoptChecked.LoadOrStore(key, struct{}{}); seen {
+		return false, nil
+	}
+
+	adopted, err := r.adopt(ctx, acmClient, secret, certificateArn, leafPEM, chainPEM)
+	if err != nil {
+		// Try again on the next reconcile
+		r.adoptChecked.Delete(key)
+		return false, err
+	}
+	if adopted {
+		r.Log.Info("Adopted identical ACM certificate", "secret", key, "certificateArn", aws.ToString(certificateArn))
+	}
+	return adopted, nil
+}
+
+func (r *SecretReconciler) adopt(ctx context.Context, acmClient awsclient.ACMAPI, secret *corev1.Secret, certificateArn *string, leafPEM, chainPEM []byte) (bool, error) {
+	tags, err := acmClient.ListTagsForCertificate(ctx, &acm.ListTagsForCertificateInput{CertificateArn: certificateArn})
+	if err != nil {
+		return false, err
+	}
+	for _, tag := range tags.Tags {
+		if aws.ToString(tag.Key) == secretTagKey {
+			// Owned by this Secret already, or by another one
+			return false, nil
+		}
+	}
+
+	identical, err := r.acmContentMatches(ctx, acmClient, certificateArn, leafPEM, chainPEM)
+	if err != nil || !identical {
+		return false, err
+	}
+
+	_, err = acmClient.AddTagsToCertificate(ctx, &acm.AddTagsToCertificateInput{
+		CertificateArn: certificateArn,
+		Tags:           r.certificateTags(secret, r.contentHashTags(leafPEM, chainPEM), r.templateTags(secret)),
+	})
+	return err == nil, err
+}
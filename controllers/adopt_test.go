@@ -0,0 +1,140 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("adoptIdentical", func() {
+	var (
+		ctx                context.Context
+		acmFake            *fakeACM
+		secret             *corev1.Secret
+		r                  *SecretReconciler
+		intermediate, leaf *testCert
+		certificateArn     *string
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		acmFake = newFakeACM()
+		_, intermediate, leaf = newTestChain("example.com")
+		certificateArn = aws.String(acmFake.add("example.com", &fakeCertificate{
+			Detail: types.CertificateDetail{Type: types.CertificateTypeImported},
+			Cert:   string(leaf.PEM),
+			Chain:  string(intermediate.PEM),
+		}))
+		secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web-tls"}}
+		r = &SecretReconciler{Log: logr.Discard(), AdoptIdentical: true}
+	})
+
+	It("tags an identical certificate on the first reconcile", func() {
+		adopted, err := r.adoptIdentical(ctx, acmFake, secret, certificateArn, leaf.PEM, intermediate.PEM)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(adopted).To(BeTrue())
+		Expect(acmFake.tag(*certificateArn, secretTagKey)).To(Equal("prod/web-tls"))
+		Expect(acmFake.called("ImportCertificate")).To(Equal(0))
+	})
+
+	It("only checks once per Secret", func() {
+		_, err := r.adoptIdentical(ctx, acmFake, secret, certificateArn, leaf.PEM, intermediate.PEM)
+		Expect(err).NotTo(HaveOccurred())
+
+		adopted, err := r.adoptIdentical(ctx, acmFake, secret, certificateArn, leaf.PEM, intermediate.PEM)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(adopted).To(BeFalse())
+		Expect(acmFake.called("GetCertificate")).To(Equal(1))
+	})
+
+	It("leaves a certificate with different content alone", func() {
+		_, otherIntermediate, otherLeaf := newTestChain("example.com")
+
+		adopted, err := r.adoptIdentical(ctx, acmFake, secret, certificateArn, otherLeaf.PEM, otherIntermediate.PEM)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(adopted).To(BeFalse())
+		Expect(acmFake.called("AddTagsToCertificate")).To(Equal(0))
+	})
+
+	It("doesn't retag a certificate the Secret already owns", func() {
+		owned := aws.String(acmFake.add("example.com", &fakeCertificate{
+			Detail: types.CertificateDetail{Type: types.CertificateTypeImported},
+			Cert:   string(leaf.PEM),
+			Chain:  string(intermediate.PEM),
+			Tags:   []types.Tag{{Key: aws.String(secretTagKey), Value: aws.String("prod/web-tls")}},
+		}))
+
+		adopted, err := r.adoptIdentical(ctx, acmFake, secret, owned, leaf.PEM, intermediate.PEM)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(adopted).To(BeFalse())
+		Expect(acmFake.called("AddTagsToCertificate")).To(Equal(0))
+	})
+
+	It("doesn't take over a certificate another Secret owns", func() {
+		other := aws.String(acmFake.add("example.com", &fakeCertificate{
+			Detail: types.CertificateDetail{Type: types.CertificateTypeImported},
+			Cert:   string(leaf.PEM),
+			Chain:  string(intermediate.PEM),
+			Tags:   []types.Tag{{Key: aws.String(secretTagKey), Value: aws.String("staging/web-tls")}},
+		}))
+
+		adopted, err := r.adoptIdentical(ctx, acmFake, secret, other, leaf.PEM, intermediate.PEM)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(adopted).To(BeFalse())
+		Expect(acmFake.tag(*other, secretTagKey)).To(Equal("staging/web-tls"))
+		Expect(acmFake.called("GetCertificate")).To(Equal(0))
+	})
+
+	It("does nothing when disabled", func() {
+		r.AdoptIdentical = false
+
+		adopted, err := r.adoptIdentical(ctx, acmFake, secret, certificateArn, leaf.PEM, intermediate.PEM)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(adopted).To(BeFalse())
+		Expect(acmFake.called("GetCertificate")).To(Equal(0))
+	})
+
+	It("doesn't fetch the certificate again on the reconcile that adopted it", func() {
+		acmFake.certs[0].Detail.NotAfter = aws.Time(time.Now().Add(24 * time.Hour))
+		secret.Annotations = map[string]string{"sync-to-acm": "true", "cert-manager.io/common-name": "example.com"}
+		secret.Type = corev1.SecretTypeTLS
+		secret.Data = map[string][]byte{
+			corev1.TLSCertKey:       append(append([]byte{}, leaf.PEM...), intermediate.PEM...),
+			corev1.TLSPrivateKeyKey: leaf.keyPEM(),
+		}
+		r.Client = fake.NewClientBuilder().WithObjects(secret).Build()
+		r.ACM = acmFake
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acmFake.tag(*certificateArn, secretTagKey)).To(Equal("prod/web-tls"))
+		Expect(acmFake.called("GetCertificate")).To(Equal(1))
+		Expect(acmFake.called("ImportCertificate")).To(Equal(0))
+	})
+})
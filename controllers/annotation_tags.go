@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+// tagAnnotationPrefix prefixes the Secret annotations that set an ACM tag,
+// e.g. cert-sync.denyshubh.github.io/tag-CostCenter: "1234" tags the
+// certificate with CostCenter=1234. tagTemplateAnnotation shares the prefix
+// and is not a tag.
+const tagAnnotationPrefix = "cert-sync.denyshubh.github.io/tag-"
+
+// annotationTagsTagKey lists the keys of the tags set from annotations,
+// separated by spaces, so tags whose annotation was removed can be removed
+// from ACM too.
+const annotationTagsTagKey = "cert-sync/annotation-tags"
+
+// annotationTags returns the tags set by secret's tag annotations, sorted by
+// key. Invalid tags are logged and dropped. It returns nothing unless
+// AnnotationTags is set.
+func (r *SecretReconciler) annotationTags(secret *corev1.Secret) []types.Tag {
+	if !r.AnnotationTags {
+		return nil
+	}
+
+	var tags []types.Tag
+	for annotation, value := range secret.Annotations {
+		key, ok := strings.CutPrefix(annotation, tagAnnotationPrefix)
+		if !ok || annotation == tagTemplateAnnotation {
+			continue
+		}
+		if err := validateTag(key, value); err != nil {
+			r.Log.Error(err, "Dropping invalid tag annotation", "secret", client.ObjectKeyFromObject(secret), "annotation", annotation)
+			continue
+		}
+		tags = append(tags, types.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	return sortTags(tags)
+}
+
+// annotationTagsMarker returns the annotationTagsTagKey tag recording the
+// keys of tags, or nothing when tags is empty. Keys that don't fit in a tag
+// value are left out, and are then not removed with their annotation.
+func annotationTagsMarker(tags []types.Tag) []types.Tag {
+	var keys string
+	for _, key := range tagKeys(tags) {
+		if len(keys)+len(key)+1 > 256 {
+			break
+		}
+		keys = strings.TrimSpace(keys + " " + key)
+	}
+	if keys == "" {
+		return nil
+	}
+	return []types.Tag{{Key: aws.String(annotationTagsTagKey), Value: aws.String(keys)}}
+}
+
+// mergeTags returns base with the tags in overrides added, replacing those
+// with the same key.
+func mergeTags(base, overrides []types.Tag) []types.Tag {
+	merged := slices.DeleteFunc(append([]types.Tag{}, base...), func(tag types.Tag) bool {
+		return slices.ContainsFunc(overrides, func(override types.Tag) bool {
+			return aws.ToString(override.Key) == aws.ToString(tag.Key)
+		})
+	})
+	return append(merged, overrides...)
+}
+
+// syncAnnotationTags brings the tags on certificateArn in line with secret's
+// tag annotations. ACM ignores tags on re-import, so added and changed tags
+// are applied explicitly, and tags whose annotation was removed are removed
+// unless another source, such as the tag template, still sets them. It does
+// nothing unless AnnotationTags is set.
+func (r *SecretReconciler) syncAnnotationTags(ctx context.Context, acmClient awsclient.ACMAPI, certificateArn *string, secret *corev1.Secret) error {
+	if !r.AnnotationTags {
+		return nil
+	}
+
+	output, err := acmClient.ListTagsForCertificate(ctx, &acm.ListTagsForCertificateInput{CertificateArn: certificateArn})
+	if err != nil {
+		return err
+	}
+
+	tags := r.annotationTags(secret)
+	desired := append(annotationTagsMarker(tags), tags...)
+	current := make(map[string]string, len(output.Tags))
+	for _, tag := range output.Tags {
+		current[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+
+	var add, remove []types.Tag
+	for _, tag := range desired {
+		if value, ok := current[aws.ToString(tag.Key)]; !ok || value != aws.ToString(tag.Value) {
+			add = append(add, tag)
+		}
+	}
+	kept := tagKeys(append(desired, r.certificateTags(secret, nil, r.templateTags(secret))...))
+	previous := strings.Fields(current[annotationTagsTagKey])
+	if current[annotationTagsTagKey] != "" {
+		previous = append(previous, annotationTagsTagKey)
+	}
+	for _, key := range previous {
+		if value, ok := current[key]; ok && !slices.Contains(kept, key) {
+			remove = append(remove, types.Tag{Key: aws.String(key), Value: aws.String(value)})
+		}
+	}
+
+	if len(add) > 0 {
+		if _, err := acmClient.AddTagsToCertificate(ctx, &acm.AddTagsToCertificateInput{CertificateArn: certificateArn, Tags: add}); err != nil {
+			return err
+		}
+	}
+	if len(remove) > 0 {
+		if _, err := acmClient.RemoveTagsFromCertificate(ctx, &acm.RemoveTagsFromCertificateInput{CertificateArn: certificateArn, Tags: remove}); err != nil {
+			return err
+		}
+	}
+	if len(add) > 0 || len(remove) > 0 {
+		r.Log.Info("Synced ACM tags from annotations", "secret", client.ObjectKeyFromObject(secret), "certificateArn", aws.ToString(certificateArn), "set", tagKeys(add), "removed", tagKeys(remove))
+	}
+	return nil
+}
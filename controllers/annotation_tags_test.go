@@ -0,0 +1,133 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("--annotation-tags", func() {
+	var (
+		ctx     context.Context
+		acmFake *fakeACM
+		r       *SecretReconciler
+		req     reconcile.Request
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		acmFake = newFakeACM()
+		_, intermediate, leaf := newTestChain("example.com")
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "prod",
+				Name:      "web-tls",
+				Annotations: map[string]string{
+					"sync-to-acm":                      "true",
+					"cert-manager.io/common-name":      "example.com",
+					tagAnnotationPrefix + "CostCenter": "1234",
+					tagAnnotationPrefix + "owner":      "payments",
+				},
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       append(append([]byte{}, leaf.PEM...), intermediate.PEM...),
+				corev1.TLSPrivateKeyKey: leaf.keyPEM(),
+			},
+		}
+		r = &SecretReconciler{
+			Client:         fake.NewClientBuilder().WithObjects(secret).Build(),
+			Log:            logr.Discard(),
+			ACM:            acmFake,
+			AnnotationTags: true,
+		}
+		req = reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)}
+	})
+
+	tags := func() map[string]string {
+		Expect(acmFake.certs).To(HaveLen(1))
+		tags := map[string]string{}
+		for _, tag := range acmFake.certs[0].Tags {
+			tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+		return tags
+	}
+
+	annotate := func(mutate func(annotations map[string]string)) {
+		var secret corev1.Secret
+		Expect(r.Get(ctx, req.NamespacedName, &secret)).To(Succeed())
+		mutate(secret.Annotations)
+		Expect(r.Update(ctx, &secret)).To(Succeed())
+		_, err := r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		_, err := r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("tags an imported certificate from the annotations", func() {
+		Expect(tags()).To(Equal(map[string]string{
+			secretTagKey:         "prod/web-tls",
+			"CostCenter":         "1234",
+			"owner":              "payments",
+			annotationTagsTagKey: "CostCenter owner",
+		}))
+	})
+
+	It("adds tags for new annotations", func() {
+		annotate(func(annotations map[string]string) { annotations[tagAnnotationPrefix+"env"] = "prod" })
+		Expect(tags()).To(HaveKeyWithValue("env", "prod"))
+		Expect(tags()).To(HaveKeyWithValue(annotationTagsTagKey, "CostCenter env owner"))
+	})
+
+	It("updates tags whose annotation changed", func() {
+		annotate(func(annotations map[string]string) { annotations[tagAnnotationPrefix+"CostCenter"] = "5678" })
+		Expect(tags()).To(HaveKeyWithValue("CostCenter", "5678"))
+	})
+
+	It("removes tags whose annotation was removed and keeps other tags", func() {
+		acmFake.certs[0].Tags = append(acmFake.certs[0].Tags, types.Tag{Key: aws.String("manual"), Value: aws.String("yes")})
+
+		annotate(func(annotations map[string]string) { delete(annotations, tagAnnotationPrefix+"owner") })
+		Expect(tags()).NotTo(HaveKey("owner"))
+		Expect(tags()).To(HaveKeyWithValue(annotationTagsTagKey, "CostCenter"))
+
+		annotate(func(annotations map[string]string) { delete(annotations, tagAnnotationPrefix+"CostCenter") })
+		Expect(tags()).To(Equal(map[string]string{secretTagKey: "prod/web-tls", "manual": "yes"}))
+	})
+
+	It("ignores the tag template annotation and invalid tags", func() {
+		var secret corev1.Secret
+		Expect(r.Get(ctx, req.NamespacedName, &secret)).To(Succeed())
+		secret.Annotations[tagTemplateAnnotation] = "team=web"
+		secret.Annotations[tagAnnotationPrefix+"aws:reserved"] = "x"
+		Expect(tagKeys(r.annotationTags(&secret))).To(Equal([]string{"CostCenter", "owner"}))
+	})
+})
@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultSyncAnnotation opts a Secret into syncing when set to "true".
+const DefaultSyncAnnotation = "sync-to-acm"
+
+// truthyValues are the spellings of "true" accepted with LenientAnnotations.
+var truthyValues = map[string]bool{"true": true, "1": true, "yes": true, "y": true, "on": true}
+
+// falsyValues are the spellings of "false" that aren't worth a warning.
+var falsyValues = map[string]bool{"false": true, "0": true, "no": true, "n": true, "off": true, "": true}
+
+// isTruthy reports whether value is a common spelling of true, ignoring case
+// and surrounding space.
+func isTruthy(value string) bool {
+	return truthyValues[strings.ToLower(strings.TrimSpace(value))]
+}
+
+// syncAnnotation returns the annotation key that opts a Secret into syncing.
+func (r *SecretReconciler) syncAnnotation() string {
+	if r.SyncAnnotation != "" {
+		return r.SyncAnnotation
+	}
+	return DefaultSyncAnnotation
+}
+
+// syncRequested reports whether secret asks to be synced to ACM. Only "true"
+// counts unless LenientAnnotations also accepts "True", "1", "yes" and the
+// like.
+func (r *SecretReconciler) syncRequested(secret *corev1.Secret) bool {
+	value := secret.Annotations[r.syncAnnotation()]
+	if r.LenientAnnotations {
+		return isTruthy(value)
+	}
+	return value == "true"
+}
+
+// checkAnnotationTypos logs, once per Secret, a suggestion for an annotation
+// that looks like a misspelled sync annotation or a value that won't be read as
+// either true or false. It does nothing unless LenientAnnotations is set.
+func (r *SecretReconciler) checkAnnotationTypos(log logr.Logger, secret *corev1.Secret) {
+	if !r.LenientAnnotations {
+		return
+	}
+	key := client.ObjectKeyFromObject(secret)
+	if _, logged := r.typosLogged.Load(key); logged {
+		return
+	}
+
+	syncAnnotation := r.syncAnnotation()
+	for annotation, value := range secret.Annotations {
+		if annotation != syncAnnotation && nearMiss(annotation, syncAnnotation) {
+			log.Info("Annotation looks like a misspelling; did you mean "+syncAnnotation+"?", "annotation", annotation)
+			r.typosLogged.Store(key, struct{}{})
+			return
+		}
+		if annotation == syncAnnotation && !isTruthy(value) && !falsyValues[strings.ToLower(strings.TrimSpace(value))] {
+			log.Info("Unrecognised annotation value; did you mean \"true\"?", "annotation", annotation, "value", value)
+			r.typosLogged.Store(key, struct{}{})
+			return
+		}
+	}
+}
+
+// misspelled reports whether secret carries an annotation that looks like a
+// misspelled sync annotation.
+func (r *SecretReconciler) misspelled(secret *corev1.Secret) bool {
+	syncAnnotation := r.syncAnnotation()
+	for annotation := range secret.Annotations {
+		if annotation != syncAnnotation && nearMiss(annotation, syncAnnotation) {
+			return true
+		}
+	}
+	return false
+}
+
+// nearMiss reports whether annotation differs from want only in case,
+// separators or at most two edits.
+func nearMiss(annotation, want string) bool {
+	normalize := strings.NewReplacer("_", "-", ".", "-", " ", "-")
+	annotation = normalize.Replace(strings.ToLower(annotation))
+	if annotation == want {
+		return true
+	}
+	return editDistance(annotation, want) <= 2
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("annotation typos", func() {
+	secretWith := func(annotations map[string]string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web-tls", Annotations: annotations}}
+	}
+
+	DescribeTable("normalizes truthy values",
+		func(value string, expected bool) {
+			Expect(isTruthy(value)).To(Equal(expected))
+		},
+		Entry("true", "true", true),
+		Entry("True", "True", true),
+		Entry("1", "1", true),
+		Entry("yes", " YES ", true),
+		Entry("false", "false", false),
+		Entry("empty", "", false),
+		Entry("typo", "ture", false),
+	)
+
+	It("only accepts other spellings of true when lenient", func() {
+		secret := secretWith(map[string]string{DefaultSyncAnnotation: "yes"})
+		Expect((&SecretReconciler{}).syncRequested(secret)).To(BeFalse())
+		Expect((&SecretReconciler{LenientAnnotations: true}).syncRequested(secret)).To(BeTrue())
+		Expect((&SecretReconciler{}).syncRequested(secretWith(map[string]string{DefaultSyncAnnotation: "true"}))).To(BeTrue())
+	})
+
+	DescribeTable("spots near misses of sync-to-acm",
+		func(annotation string, expected bool) {
+			Expect(nearMiss(annotation, DefaultSyncAnnotation)).To(Equal(expected))
+		},
+		Entry("underscores", "sync_to_acm", true),
+		Entry("upper case", "Sync-To-ACM", true),
+		Entry("missing letter", "sync-to-am", true),
+		Entry("transposed letters", "snyc-to-acm", true),
+		Entry("unrelated", "cert-manager.io/common-name", false),
+	)
+
+	Context("when warning", func() {
+		var (
+			logged []string
+			log    logr.Logger
+			r      *SecretReconciler
+		)
+
+		BeforeEach(func() {
+			logged = nil
+			log = funcr.New(func(_, args string) { logged = append(logged, args) }, funcr.Options{})
+			r = &SecretReconciler{LenientAnnotations: true}
+		})
+
+		It("suggests the annotation key once", func() {
+			secret := secretWith(map[string]string{"sync_to_acm": "true"})
+			r.checkAnnotationTypos(log, secret)
+			r.checkAnnotationTypos(log, secret)
+			Expect(logged).To(HaveLen(1))
+			Expect(logged[0]).To(ContainSubstring("sync_to_acm"))
+		})
+
+		It("suggests a value for an unrecognised one", func() {
+			r.checkAnnotationTypos(log, secretWith(map[string]string{DefaultSyncAnnotation: "ture"}))
+			Expect(strings.Join(logged, "")).To(ContainSubstring(`did you mean \"true\"`))
+		})
+
+		It("stays quiet about valid annotations", func() {
+			r.checkAnnotationTypos(log, secretWith(map[string]string{DefaultSyncAnnotation: "True"}))
+			r.checkAnnotationTypos(log, secretWith(map[string]string{DefaultSyncAnnotation: "false"}))
+			Expect(logged).To(BeEmpty())
+		})
+
+		It("does nothing unless lenient", func() {
+			r.LenientAnnotations = false
+			r.checkAnnotationTypos(log, secretWith(map[string]string{"sync_to_acm": "true"}))
+			Expect(logged).To(BeEmpty())
+		})
+	})
+})
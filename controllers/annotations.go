@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultFieldManager is the field manager cert-sync writes Secret metadata
+// as, kept apart from cert-manager's so neither takes the other's fields.
+const DefaultFieldManager = "cert-sync"
+
+// notAfterAnnotation holds the expiry (RFC 3339) of the ACM certificate the
+// Secret was last synced to.
+const notAfterAnnotation = "cert-sync.denyshubh.github.io/acm-notafter"
+
+// DefaultDomainAnnotations are the annotation keys the domain is read from, in
+// order of precedence: the current cert-manager key followed by the key used
+// by cert-manager releases before v0.11.
+var DefaultDomainAnnotations = []string{
+	"cert-manager.io/common-name",
+	"certmanager.k8s.io/common-name",
+}
+
+// domainAnnotations returns the annotation keys the domain is read from.
+func (r *SecretReconciler) domainAnnotations() []string {
+	if len(r.DomainAnnotations) == 0 {
+		return DefaultDomainAnnotations
+	}
+	return r.DomainAnnotations
+}
+
+// domainFromAnnotations returns the domain from the first non-empty annotation
+// in DomainAnnotations, along with the key it came from.
+func (r *SecretReconciler) domainFromAnnotations(secret *corev1.Secret) (domain, key string) {
+	for _, key := range r.domainAnnotations() {
+		if domain := secret.Annotations[key]; domain != "" {
+			return domain, key
+		}
+	}
+	return "", ""
+}
+
+// Domain sources of a Secret whose domain isn't annotated.
+const (
+	domainSourceCommonName = "certificate common name"
+	domainSourceDNSName    = "certificate DNS name"
+)
+
+// secretDomain returns the domain secret is synced under, along with where it
+// came from: the first annotation in DomainAnnotations, or else the subject
+// common name or first DNS name of the leaf certificate.
+func (r *SecretReconciler) secretDomain(secret *corev1.Secret) (domain, source string) {
+	if domain, key := r.domainFromAnnotations(secret); domain != "" {
+		return domain, key
+	}
+	return domainFromCertificate(secret.Data[corev1.TLSCertKey])
+}
+
+// domainFromCertificate returns the subject common name of the leaf in
+// certPEM, or its first DNS name when it has none, along with which it was.
+// It returns nothing when the leaf can't be parsed or names no domain.
+func domainFromCertificate(certPEM []byte) (domain, source string) {
+	leaf, err := parseLeafCertificate(certPEM)
+	if err != nil {
+		return "", ""
+	}
+	if leaf.Subject.CommonName != "" {
+		return leaf.Subject.CommonName, domainSourceCommonName
+	}
+	if len(leaf.DNSNames) > 0 {
+		return leaf.DNSNames[0], domainSourceDNSName
+	}
+	return "", ""
+}
+
+// annotateNotAfter sets notAfterAnnotation on secret to notAfter, or removes it
+// when notAfter is nil. It only patches when the value changes.
+func (r *SecretReconciler) annotateNotAfter(ctx context.Context, secret *corev1.Secret, notAfter *time.Time) error {
+	if !r.AnnotateNotAfter {
+		return nil
+	}
+
+	current, exists := secret.Annotations[notAfterAnnotation]
+	original := secret.DeepCopy()
+	if notAfter == nil {
+		if !exists {
+			return nil
+		}
+		delete(secret.Annotations, notAfterAnnotation)
+	} else {
+		value := notAfter.UTC().Format(time.RFC3339)
+		if current == value {
+			return nil
+		}
+		if secret.Annotations == nil {
+			secret.Annotations = map[string]string{}
+		}
+		secret.Annotations[notAfterAnnotation] = value
+	}
+
+	return r.writeBack(ctx, original, secret)
+}
+
+// writeBack patches secret's metadata from original to its current state
+// under our own field manager. Write-backs are best effort: immutable Secrets
+// are skipped, logging once per Secret, and a conflicting concurrent write is
+// left to the next reconcile instead of failing this one. A dry run skips
+// them all.
+func (r *SecretReconciler) writeBack(ctx context.Context, original, secret *corev1.Secret) error {
+	if r.DryRun {
+		return nil
+	}
+	key := client.ObjectKeyFromObject(secret)
+	if secret.Immutable != nil && *secret.Immutable {
+		if _, logged := r.immutableLogged.LoadOrStore(key, struct{}{}); !logged {
+			r.Log.Info("Secret is immutable; skipping annotation write-back", "secret", key)
+		}
+		return nil
+	}
+
+	fieldManager := r.FieldManager
+	if fieldManager == "" {
+		fieldManager = DefaultFieldManager
+	}
+
+	// The merge patch carries no resourceVersion, so it lands on whatever
+	// version the API server holds now rather than the one read at the start
+	// of the reconcile.
+	err := r.Patch(ctx, secret, client.MergeFrom(original), client.FieldOwner(fieldManager))
+	if apierrors.IsConflict(err) {
+		r.Log.Info("Secret changed while writing annotations back; retrying on next reconcile", "secret", key)
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,270 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("domainFromAnnotations", func() {
+	secretWith := func(annotations map[string]string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}}
+	}
+
+	It("prefers the current cert-manager annotation", func() {
+		domain, key := (&SecretReconciler{}).domainFromAnnotations(secretWith(map[string]string{
+			"certmanager.k8s.io/common-name": "legacy.example.com",
+			"cert-manager.io/common-name":    "example.com",
+		}))
+		Expect(domain).To(Equal("example.com"))
+		Expect(key).To(Equal("cert-manager.io/common-name"))
+	})
+
+	It("falls back to the legacy cert-manager annotation", func() {
+		domain, key := (&SecretReconciler{}).domainFromAnnotations(secretWith(map[string]string{
+			"certmanager.k8s.io/common-name": "legacy.example.com",
+		}))
+		Expect(domain).To(Equal("legacy.example.com"))
+		Expect(key).To(Equal("certmanager.k8s.io/common-name"))
+	})
+
+	It("honours a configured precedence and skips empty values", func() {
+		r := &SecretReconciler{DomainAnnotations: []string{"example.io/domain", "cert-manager.io/common-name"}}
+		domain, key := r.domainFromAnnotations(secretWith(map[string]string{
+			"example.io/domain":           "",
+			"cert-manager.io/common-name": "example.com",
+		}))
+		Expect(domain).To(Equal("example.com"))
+		Expect(key).To(Equal("cert-manager.io/common-name"))
+
+		r.DomainAnnotations = []string{"example.io/domain", "cert-manager.io/common-name"}
+		domain, _ = r.domainFromAnnotations(secretWith(map[string]string{
+			"example.io/domain":           "custom.example.com",
+			"cert-manager.io/common-name": "example.com",
+		}))
+		Expect(domain).To(Equal("custom.example.com"))
+	})
+
+	It("returns nothing when no key is present", func() {
+		domain, key := (&SecretReconciler{}).domainFromAnnotations(secretWith(nil))
+		Expect(domain).To(BeEmpty())
+		Expect(key).To(BeEmpty())
+	})
+})
+
+var _ = Describe("configurable annotation keys", func() {
+	var (
+		ctx     context.Context
+		acmFake *fakeACM
+		secret  *corev1.Secret
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		acmFake = newFakeACM()
+		_, intermediate, leaf := newTestChain("example.com")
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web-tls"},
+			Type:       corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       append(append([]byte{}, leaf.PEM...), intermediate.PEM...),
+				corev1.TLSPrivateKeyKey: leaf.keyPEM(),
+			},
+		}
+	})
+
+	reconcileWith := func(r *SecretReconciler) {
+		r.Client = fake.NewClientBuilder().WithObjects(secret).Build()
+		r.Log = logr.Discard()
+		r.ACM = acmFake
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	It("syncs Secrets carrying custom sync and domain annotations", func() {
+		secret.Annotations = map[string]string{
+			"example.io/acm-sync":   "true",
+			"example.io/acm-domain": "example.com",
+		}
+		r := &SecretReconciler{SyncAnnotation: "example.io/acm-sync", DomainAnnotations: []string{"example.io/acm-domain"}}
+		Expect(r.syncRequested(secret)).To(BeTrue())
+		domain, source := r.secretDomain(secret)
+		Expect(domain).To(Equal("example.com"))
+		Expect(source).To(Equal("example.io/acm-domain"))
+
+		reconcileWith(r)
+		Expect(acmFake.called("ImportCertificate")).To(Equal(1))
+	})
+
+	It("ignores the default sync annotation once another is configured", func() {
+		secret.Annotations = map[string]string{DefaultSyncAnnotation: "true", "cert-manager.io/common-name": "example.com"}
+		reconcileWith(&SecretReconciler{SyncAnnotation: "example.io/acm-sync"})
+		Expect(acmFake.called("ImportCertificate")).To(BeZero())
+	})
+
+	It("falls back to the certificate when the domain annotation is missing", func() {
+		secret.Annotations = map[string]string{DefaultSyncAnnotation: "true"}
+		r := &SecretReconciler{DomainAnnotations: []string{"example.io/acm-domain"}}
+		domain, source := r.secretDomain(secret)
+		Expect(domain).To(Equal("example.com"))
+		Expect(source).To(Equal(domainSourceCommonName))
+
+		reconcileWith(r)
+		Expect(acmFake.called("ImportCertificate")).To(Equal(1))
+		Expect(acmFake.certs[0].Detail.DomainName).To(Equal(aws.String("example.com")))
+	})
+
+	It("prefers the annotation over the certificate", func() {
+		secret.Annotations = map[string]string{"cert-manager.io/common-name": "www.example.com"}
+		domain, source := (&SecretReconciler{}).secretDomain(secret)
+		Expect(domain).To(Equal("www.example.com"))
+		Expect(source).To(Equal("cert-manager.io/common-name"))
+	})
+})
+
+var _ = Describe("domainFromCertificate", func() {
+	It("uses the subject common name", func() {
+		cert := newTestCert("example.com", nil, testCertOptions{DNSNames: []string{"www.example.com"}})
+		domain, source := domainFromCertificate(cert.PEM)
+		Expect(domain).To(Equal("example.com"))
+		Expect(source).To(Equal(domainSourceCommonName))
+	})
+
+	It("uses the first DNS name when there is no common name", func() {
+		cert := newTestCert("", nil, testCertOptions{DNSNames: []string{"www.example.com", "example.com"}})
+		domain, source := domainFromCertificate(cert.PEM)
+		Expect(domain).To(Equal("www.example.com"))
+		Expect(source).To(Equal(domainSourceDNSName))
+	})
+
+	It("skips certificates naming no domain", func() {
+		cert := newTestCert("", nil, testCertOptions{})
+		domain, source := domainFromCertificate(cert.PEM)
+		Expect(domain).To(BeEmpty())
+		Expect(source).To(BeEmpty())
+
+		var logged []string
+		acmFake := newFakeACM()
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web-tls", Annotations: map[string]string{DefaultSyncAnnotation: "true"}},
+			Type:       corev1.SecretTypeTLS,
+			Data:       map[string][]byte{corev1.TLSCertKey: cert.PEM, corev1.TLSPrivateKeyKey: cert.keyPEM()},
+		}
+		r := &SecretReconciler{
+			Client: fake.NewClientBuilder().WithObjects(secret).Build(),
+			Log:    funcr.New(func(_, args string) { logged = append(logged, args) }, funcr.Options{}),
+			ACM:    acmFake,
+		}
+		_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acmFake.called("ImportCertificate")).To(BeZero())
+		Expect(strings.Join(logged, "\n")).To(ContainSubstring("its certificate names no domain; skipping"))
+	})
+})
+
+var _ = Describe("annotateNotAfter", func() {
+	var (
+		ctx    context.Context
+		secret *corev1.Secret
+		k8s    client.Client
+		r      *SecretReconciler
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web-tls"}}
+		k8s = fake.NewClientBuilder().WithObjects(secret).Build()
+		r = &SecretReconciler{Client: k8s, Log: logr.Discard(), AnnotateNotAfter: true}
+	})
+
+	stored := func() *corev1.Secret {
+		var got corev1.Secret
+		Expect(k8s.Get(ctx, client.ObjectKeyFromObject(secret), &got)).To(Succeed())
+		return &got
+	}
+
+	It("sets the annotation from the ACM detail", func() {
+		notAfter := aws.Time(time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+		Expect(r.annotateNotAfter(ctx, secret, notAfter)).To(Succeed())
+		Expect(stored().Annotations).To(HaveKeyWithValue(notAfterAnnotation, "2025-01-02T03:04:05Z"))
+	})
+
+	It("removes the annotation when the expiry is unknown", func() {
+		Expect(r.annotateNotAfter(ctx, secret, aws.Time(time.Now()))).To(Succeed())
+		Expect(r.annotateNotAfter(ctx, secret, nil)).To(Succeed())
+		Expect(stored().Annotations).NotTo(HaveKey(notAfterAnnotation))
+	})
+
+	It("does nothing when disabled", func() {
+		r.AnnotateNotAfter = false
+		Expect(r.annotateNotAfter(ctx, secret, aws.Time(time.Now()))).To(Succeed())
+		Expect(stored().Annotations).NotTo(HaveKey(notAfterAnnotation))
+	})
+
+	It("writes as the dedicated field manager", func() {
+		var fieldManager string
+		k8s = fake.NewClientBuilder().WithObjects(secret).WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				fieldManager = (&client.PatchOptions{}).ApplyOptions(opts).FieldManager
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		}).Build()
+		r.Client = k8s
+
+		Expect(r.annotateNotAfter(ctx, secret, aws.Time(time.Now()))).To(Succeed())
+		Expect(fieldManager).To(Equal(DefaultFieldManager))
+	})
+
+	It("skips immutable Secrets and logs it once", func() {
+		var logged []string
+		r.Log = funcr.New(func(_, args string) { logged = append(logged, args) }, funcr.Options{})
+		immutable := true
+		secret.Immutable = &immutable
+
+		Expect(r.annotateNotAfter(ctx, secret, aws.Time(time.Now()))).To(Succeed())
+		Expect(r.annotateNotAfter(ctx, secret, aws.Time(time.Now().Add(time.Hour)))).To(Succeed())
+		Expect(stored().Annotations).NotTo(HaveKey(notAfterAnnotation))
+		Expect(logged).To(HaveLen(1))
+		Expect(strings.Join(logged, "")).To(ContainSubstring("immutable"))
+	})
+
+	It("leaves a conflicting write to the next reconcile", func() {
+		k8s = fake.NewClientBuilder().WithObjects(secret).WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(context.Context, client.WithWatch, client.Object, client.Patch, ...client.PatchOption) error {
+				return apierrors.NewConflict(corev1.Resource("secrets"), secret.Name, nil)
+			},
+		}).Build()
+		r.Client = k8s
+
+		Expect(r.annotateNotAfter(ctx, secret, aws.Time(time.Now()))).To(Succeed())
+	})
+})
@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+// arnAnnotation holds the ARNs of the ACM certificates a Secret was synced
+// to, comma-separated, one per region. Setting it by hand targets an existing
+// imported certificate, e.g. one attached to a load balancer, which is then
+// re-imported in place without a domain search. Only certificates carrying
+// the Secret's identity tag are targeted, unless AllowPinnedARNs is set.
+const arnAnnotation = "cert-sync.denyshubh.github.io/acm-arn"
+
+// findCertificate returns the ACM certificate to update for secret. It tries
+// the ARN recorded on the Secret first, which avoids scanning every
+// certificate in the account, then the one in the ARNStore, and falls back to
+// a domain search when neither points at an imported certificate.
+func (r *SecretReconciler) findCertificate(ctx context.Context, acmClient awsclient.ACMAPI, secret *corev1.Secret, domainName string) (*types.CertificateDetail, error) {
+	if malformed := slices.DeleteFunc(splitArns(secret.Annotations[arnAnnotation]), isCertificateArn); len(malformed) > 0 {
+		r.Log.Info("Ignoring malformed ACM certificate ARNs", "secret", secret.Namespace+"/"+secret.Name, "annotation", arnAnnotation, "arns", malformed)
+	}
+	if certificateArn := recordedArn(secret, acmClient.Options().Region); certificateArn != "" {
+		certificate, err := describeImported(ctx, acmClient, certificateArn)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case certificate == nil:
+			r.Log.Info("Recorded ACM certificate is gone or not imported; searching by domain", "secret", secret.Namespace+"/"+secret.Name, "certificateArn", certificateArn)
+		default:
+			// Anyone who can annotate a Secret could otherwise overwrite any
+			// imported certificate in the account
+			allowed, err := r.mayOverwrite(ctx, acmClient, certificate, secret)
+			if err != nil {
+				return nil, err
+			}
+			if allowed {
+				return certificate, nil
+			}
+			r.Log.Info("Recorded ACM certificate wasn't imported from the Secret; ignoring it", "secret", secret.Namespace+"/"+secret.Name, "certificateArn", certificateArn)
+			r.eventf(secret, corev1.EventTypeWarning, reasonUnownedCertificate, "Ignoring certificate %s in %s: it isn't tagged %s=%s/%s", certificateArn, acmClient.Options().Region, secretTagKey, secret.Namespace, secret.Name)
+		}
+	}
+
+	key := ARNKey{Region: acmClient.Options().Region, Secret: client.ObjectKeyFromObject(secret)}
+	if certificateArn, ok, err := r.arnStore().Get(ctx, key); err != nil {
+		return nil, err
+	} else if ok {
+		certificate, err := describeImported(ctx, acmClient, certificateArn)
+		if err != nil {
+			return nil, err
+		}
+		if certificate != nil {
+			return certificate, nil
+		}
+		r.Log.Info("Stored ACM certificate is gone or not imported; searching by domain", "secret", secret.Namespace+"/"+secret.Name, "certificateArn", certificateArn)
+		if err := r.arnStore().Delete(ctx, key); err != nil {
+			return nil, err
+		}
+	}
+
+	certificate, err := r.findMatchingCertificate(ctx, acmClient, domainName, r.supersetFilter(secret))
+	if err != nil || certificate != nil || !r.ReuseTaggedCertificates {
+		return certificate, err
+	}
+	return r.findCertificateByTag(ctx, acmClient, secret)
+}
+
+// mayOverwrite reports whether certificate may be re-imported with the
+// content of secret: it carries the Secret's identity tag, or
+// AllowPinnedARNs is set and it is the certificate recorded on the Secret.
+func (r *SecretReconciler) mayOverwrite(ctx context.Context, acmClient awsclient.ACMAPI, certificate *types.CertificateDetail, secret *corev1.Secret) (bool, error) {
+	if r.AllowPinnedARNs && aws.ToString(certificate.CertificateArn) == recordedArn(secret, acmClient.Options().Region) {
+		return true, nil
+	}
+	return ownedBySecret(ctx, acmClient, certificate.CertificateArn, secret)
+}
+
+// findCertificateByTag returns the imported certificate carrying secret's
+// identity tag, or nil if this region has none. It finds certificates synced
+// from the Secret when no ARN is recorded for this region and the domain
+// doesn't match, e.g. when the Secret's domain annotation changed.
+func (r *SecretReconciler) findCertificateByTag(ctx context.Context, acmClient awsclient.ACMAPI, secret *corev1.Secret) (*types.CertificateDetail, error) {
+	var certificate *types.CertificateDetail
+	err := r.forEachImported(ctx, acmClient, func(summary types.CertificateSummary) (bool, error) {
+		owned, err := retryThrottled(ctx, r, "ListTagsForCertificate", r.ScanThrottleRetries, func() (bool, error) {
+			return ownedBySecret(ctx, acmClient, summary.CertificateArn, secret)
+		})
+		if err != nil || !owned {
+			return false, err
+		}
+		certificate, err = describeImported(ctx, acmClient, aws.ToString(summary.CertificateArn))
+		if err != nil || certificate == nil {
+			return false, err
+		}
+		r.Log.Info("Reusing ACM certificate tagged for Secret", "secret", secret.Namespace+"/"+secret.Name, "certificateArn", aws.ToString(summary.CertificateArn))
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return certificate, nil
+}
+
+// recordedArn returns the ARN recorded on secret for region, or "" if there
+// is none.
+func recordedArn(secret *corev1.Secret, region string) string {
+	for _, certificateArn := range splitArns(secret.Annotations[arnAnnotation]) {
+		if isCertificateArn(certificateArn) && inRegion(certificateArn, region) {
+			return certificateArn
+		}
+	}
+	return ""
+}
+
+// withRecordedArn returns the arnAnnotation value with the ARN for the region
+// of certificateArn replaced by it.
+func withRecordedArn(value, certificateArn string) string {
+	arns := slices.DeleteFunc(splitArns(value), func(recorded string) bool {
+		return sameRegion(recorded, certificateArn)
+	})
+	return strings.Join(append(arns, certificateArn), ",")
+}
+
+// recordArns writes the ARNs synced in this reconcile back onto secret. ARNs
+// of regions that failed are kept and those of regions no longer targeted are
+// dropped.
+func (r *SecretReconciler) recordArns(ctx context.Context, secret *corev1.Secret, regions []string, synced []string) error {
+	value := secret.Annotations[arnAnnotation]
+	for _, certificateArn := range synced {
+		if certificateArn != "" {
+			value = withRecordedArn(value, certificateArn)
+		}
+	}
+	arns := slices.DeleteFunc(splitArns(value), func(recorded string) bool {
+		return !isCertificateArn(recorded) || !slices.ContainsFunc(regions, func(region string) bool { return inRegion(recorded, region) })
+	})
+	value = strings.Join(arns, ",")
+	if value == secret.Annotations[arnAnnotation] {
+		return nil
+	}
+
+	original := secret.DeepCopy()
+	if value == "" {
+		delete(secret.Annotations, arnAnnotation)
+	} else {
+		if secret.Annotations == nil {
+			secret.Annotations = map[string]string{}
+		}
+		secret.Annotations[arnAnnotation] = value
+	}
+	return r.writeBack(ctx, original, secret)
+}
+
+// splitArns splits an arnAnnotation value into its ARNs.
+func splitArns(value string) []string {
+	var arns []string
+	for _, certificateArn := range strings.Split(value, ",") {
+		if certificateArn = strings.TrimSpace(certificateArn); certificateArn != "" {
+			arns = append(arns, certificateArn)
+		}
+	}
+	return arns
+}
+
+// sameRegion reports whether the ARNs a and b are in the same region.
+func sameRegion(a, b string) bool {
+	parsed, err := arn.Parse(b)
+	return err == nil && inRegion(a, parsed.Region)
+}
+
+// isCertificateArn reports whether certificateArn is the ARN of an ACM
+// certificate, e.g. arn:aws:acm:us-east-1:123456789012:certificate/<id>.
+func isCertificateArn(certificateArn string) bool {
+	parsed, err := arn.Parse(certificateArn)
+	if err != nil || parsed.Service != "acm" || parsed.Region == "" {
+		return false
+	}
+	id, ok := strings.CutPrefix(parsed.Resource, "certificate/")
+	return ok && id != ""
+}
+
+// inRegion reports whether certificateArn belongs to region.
+func inRegion(certificateArn, region string) bool {
+	parsed, err := arn.Parse(certificateArn)
+	return err == nil && parsed.Region == region
+}
+
+// describeImported returns the detail of certificateArn, or nil if it doesn't
+// exist or wasn't imported.
+func describeImported(ctx context.Context, acmClient awsclient.ACMAPI, certificateArn string) (*types.CertificateDetail, error) {
+	output, err := acmClient.DescribeCertificate(ctx, &acm.DescribeCertificateInput{CertificateArn: aws.String(certificateArn)})
+	if err != nil {
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if output.Certificate == nil || output.Certificate.Type != types.CertificateTypeImported {
+		return nil, nil
+	}
+	return output.Certificate, nil
+}
@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ARNKey identifies a synced certificate: the region it lives in and the
+// Secret it was synced from.
+type ARNKey struct {
+	Region string
+	Secret types.NamespacedName
+}
+
+// ARNStore remembers the ACM certificate each Secret was synced to in each
+// region, so reconciles can find it without scanning the account.
+type ARNStore interface {
+	// Get returns the ARN stored for key and whether there is one.
+	Get(ctx context.Context, key ARNKey) (string, bool, error)
+	// Set stores certificateArn for key.
+	Set(ctx context.Context, key ARNKey, certificateArn string) error
+	// Delete forgets key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key ARNKey) error
+}
+
+// MemoryARNStore is an ARNStore kept in memory. It is the default and is
+// lost on restart. The zero value is ready to use.
+type MemoryARNStore struct {
+	mu   sync.Mutex
+	arns map[ARNKey]string
+}
+
+// NewMemoryARNStore returns an empty MemoryARNStore.
+func NewMemoryARNStore() *MemoryARNStore {
+	return &MemoryARNStore{}
+}
+
+func (s *MemoryARNStore) Get(_ context.Context, key ARNKey) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	certificateArn, ok := s.arns[key]
+	return certificateArn, ok, nil
+}
+
+func (s *MemoryARNStore) Set(_ context.Context, key ARNKey, certificateArn string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.arns == nil {
+		s.arns = map[ARNKey]string{}
+	}
+	s.arns[key] = certificateArn
+	return nil
+}
+
+func (s *MemoryARNStore) Delete(_ context.Context, key ARNKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.arns, key)
+	return nil
+}
+
+// ConfigMapARNStore is an ARNStore persisted in a ConfigMap, so the mapping
+// survives restarts and is shared by replicas taking over leadership. Gets
+// are served from a copy read on first use. Every change re-reads the
+// ConfigMap and updates it at the resourceVersion read, retrying on conflict,
+// so entries written by another replica or by hand aren't overwritten.
+type ConfigMapARNStore struct {
+	Client client.Client
+	// Reader loads the ConfigMap. Defaults to Client; pass the manager's API
+	// reader to avoid caching every ConfigMap in the cluster.
+	Reader client.Reader
+	// ConfigMap is the ConfigMap the ARNs are kept in.
+	ConfigMap types.NamespacedName
+
+	mu     sync.Mutex
+	loaded bool
+	data   map[string]string
+}
+
+// NewConfigMapARNStore returns a ConfigMapARNStore keeping ARNs in configMap.
+func NewConfigMapARNStore(c client.Client, reader client.Reader, configMap types.NamespacedName) *ConfigMapARNStore {
+	return &ConfigMapARNStore{
+		Client:    c,
+		Reader:    reader,
+		ConfigMap: configMap,
+	}
+}
+
+func (s *ConfigMapARNStore) Get(ctx context.Context, key ARNKey) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(ctx); err != nil {
+		return "", false, err
+	}
+	certificateArn, ok := s.data[configMapKey(key)]
+	return certificateArn, ok, nil
+}
+
+func (s *ConfigMapARNStore) Set(ctx context.Context, key ARNKey, certificateArn string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(ctx); err != nil {
+		return err
+	}
+	if current, ok := s.data[configMapKey(key)]; ok && current == certificateArn {
+		return nil
+	}
+	return s.write(ctx, func(data map[string]string) {
+		data[configMapKey(key)] = certificateArn
+	})
+}
+
+func (s *ConfigMapARNStore) Delete(ctx context.Context, key ARNKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.load(ctx); err != nil {
+		return err
+	}
+	if _, ok := s.data[configMapKey(key)]; !ok {
+		return nil
+	}
+	return s.write(ctx, func(data map[string]string) {
+		delete(data, configMapKey(key))
+	})
+}
+
+// load reads the ConfigMap the first time it is called. A missing ConfigMap
+// is an empty store.
+func (s *ConfigMapARNStore) load(ctx context.Context) error {
+	if s.loaded {
+		return nil
+	}
+	var configMap corev1.ConfigMap
+	if err := s.reader().Get(ctx, s.ConfigMap, &configMap); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	s.remember(configMap.Data)
+	return nil
+}
+
+// write re-reads the ConfigMap, applies change to its data and updates it at
+// the resourceVersion read, creating it if it is missing. A conflicting
+// concurrent write, or a ConfigMap created in the meantime, starts over from
+// a fresh read.
+func (s *ConfigMapARNStore) write(ctx context.Context, change func(data map[string]string)) error {
+	return retry.OnError(retry.DefaultRetry, func(err error) bool {
+		return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+	}, func() error {
+		var configMap corev1.ConfigMap
+		err := s.reader().Get(ctx, s.ConfigMap, &configMap)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		missing := apierrors.IsNotFound(err)
+		if configMap.Data == nil {
+			configMap.Data = map[string]string{}
+		}
+		change(configMap.Data)
+
+		if missing {
+			configMap = corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: s.ConfigMap.Namespace, Name: s.ConfigMap.Name},
+				Data:       configMap.Data,
+			}
+			err = s.Client.Create(ctx, &configMap)
+		} else {
+			err = s.Client.Update(ctx, &configMap)
+		}
+		if err != nil {
+			return err
+		}
+		s.remember(configMap.Data)
+		return nil
+	})
+}
+
+// remember replaces the copy Gets are served from with data.
+func (s *ConfigMapARNStore) remember(data map[string]string) {
+	s.data = make(map[string]string, len(data))
+	for k, v := range data {
+		s.data[k] = v
+	}
+	s.loaded = true
+}
+
+// reader returns the Reader, defaulting to Client.
+func (s *ConfigMapARNStore) reader() client.Reader {
+	if s.Reader != nil {
+		return s.Reader
+	}
+	return s.Client
+}
+
+// configMapKey returns the ConfigMap data key for key. Underscores can't
+// appear in namespaces, names or regions, so the key is unambiguous.
+func configMapKey(key ARNKey) string {
+	return strings.Join([]string{key.Region, key.Secret.Namespace, key.Secret.Name}, "_")
+}
+
+// arnStore returns the configured ARNStore, or the in-memory default.
+func (r *SecretReconciler) arnStore() ARNStore {
+	if r.ARNStore != nil {
+		return r.ARNStore
+	}
+	return &r.defaultARNStore
+}
@@ -0,0 +1,183 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var _ = Describe("ARNStore", func() {
+	var (
+		ctx       context.Context
+		key       ARNKey
+		configMap k8stypes.NamespacedName
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		key = ARNKey{Region: "us-east-1", Secret: k8stypes.NamespacedName{Namespace: "prod", Name: "web-tls"}}
+		configMap = k8stypes.NamespacedName{Namespace: "cert-sync", Name: "arns"}
+	})
+
+	storeBehaviour := func(newStore func() ARNStore) {
+		It("gets what was set and forgets what was deleted", func() {
+			store := newStore()
+			_, ok, err := store.Get(ctx, key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+
+			Expect(store.Set(ctx, key, "arn:aws:acm:us-east-1:123456789012:certificate/a")).To(Succeed())
+			certificateArn, ok, err := store.Get(ctx, key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(certificateArn).To(Equal("arn:aws:acm:us-east-1:123456789012:certificate/a"))
+
+			other := ARNKey{Region: "eu-west-1", Secret: key.Secret}
+			_, ok, err = store.Get(ctx, other)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+
+			Expect(store.Delete(ctx, key)).To(Succeed())
+			Expect(store.Delete(ctx, key)).To(Succeed())
+			_, ok, err = store.Get(ctx, key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+	}
+
+	Context("in memory", func() {
+		storeBehaviour(func() ARNStore { return NewMemoryARNStore() })
+	})
+
+	Context("in a ConfigMap", func() {
+		var k8s client.Client
+
+		BeforeEach(func() {
+			k8s = fake.NewClientBuilder().Build()
+		})
+
+		storeBehaviour(func() ARNStore { return NewConfigMapARNStore(k8s, nil, configMap) })
+
+		It("recovers the ARNs after a restart", func() {
+			Expect(NewConfigMapARNStore(k8s, nil, configMap).Set(ctx, key, "arn:aws:acm:us-east-1:123456789012:certificate/a")).To(Succeed())
+
+			var stored corev1.ConfigMap
+			Expect(k8s.Get(ctx, configMap, &stored)).To(Succeed())
+			Expect(stored.Data).To(HaveKeyWithValue("us-east-1_prod_web-tls", "arn:aws:acm:us-east-1:123456789012:certificate/a"))
+
+			certificateArn, ok, err := NewConfigMapARNStore(k8s, nil, configMap).Get(ctx, key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeTrue())
+			Expect(certificateArn).To(Equal("arn:aws:acm:us-east-1:123456789012:certificate/a"))
+		})
+
+		It("keeps entries written by another replica after it loaded the ConfigMap", func() {
+			other := ARNKey{Region: "eu-west-1", Secret: key.Secret}
+			stale := NewConfigMapARNStore(k8s, nil, configMap)
+			_, _, err := stale.Get(ctx, key)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(NewConfigMapARNStore(k8s, nil, configMap).Set(ctx, other, "arn:aws:acm:eu-west-1:123456789012:certificate/b")).To(Succeed())
+			Expect(stale.Set(ctx, key, "arn:aws:acm:us-east-1:123456789012:certificate/a")).To(Succeed())
+
+			var stored corev1.ConfigMap
+			Expect(k8s.Get(ctx, configMap, &stored)).To(Succeed())
+			Expect(stored.Data).To(HaveKeyWithValue("us-east-1_prod_web-tls", "arn:aws:acm:us-east-1:123456789012:certificate/a"))
+			Expect(stored.Data).To(HaveKeyWithValue("eu-west-1_prod_web-tls", "arn:aws:acm:eu-west-1:123456789012:certificate/b"))
+		})
+
+		It("retries a write that conflicts with a concurrent update", func() {
+			Expect(k8s.Create(ctx, &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: configMap.Namespace, Name: configMap.Name},
+				Data:       map[string]string{"eu-west-1_prod_web-tls": "arn:aws:acm:eu-west-1:123456789012:certificate/b"},
+			})).To(Succeed())
+			conflicts := 1
+			k8s = interceptor.NewClient(k8s.(client.WithWatch), interceptor.Funcs{
+				Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+					if conflicts > 0 {
+						conflicts--
+						return apierrors.NewConflict(corev1.Resource("configmaps"), obj.GetName(), errors.New("modified"))
+					}
+					return c.Update(ctx, obj, opts...)
+				},
+			})
+
+			Expect(NewConfigMapARNStore(k8s, nil, configMap).Set(ctx, key, "arn:aws:acm:us-east-1:123456789012:certificate/a")).To(Succeed())
+			var stored corev1.ConfigMap
+			Expect(k8s.Get(ctx, configMap, &stored)).To(Succeed())
+			Expect(stored.Data).To(HaveLen(2))
+			Expect(conflicts).To(BeZero())
+		})
+	})
+
+	Context("in findCertificate", func() {
+		var (
+			acmFake *fakeACM
+			secret  *corev1.Secret
+			k8s     client.Client
+		)
+
+		BeforeEach(func() {
+			acmFake = newFakeACM()
+			acmFake.region = "us-east-1"
+			acmFake.add("example.com", &fakeCertificate{Detail: types.CertificateDetail{
+				Type:                    types.CertificateTypeImported,
+				SubjectAlternativeNames: []string{"example.com"},
+			}})
+			secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web-tls"}}
+			k8s = fake.NewClientBuilder().Build()
+		})
+
+		It("uses the stored ARN of a restarted controller without listing certificates", func() {
+			stored := acmFake.add("example.com", &fakeCertificate{Detail: types.CertificateDetail{Type: types.CertificateTypeImported}})
+			Expect(NewConfigMapARNStore(k8s, nil, configMap).Set(ctx, key, stored)).To(Succeed())
+
+			r := &SecretReconciler{Log: logr.Discard(), ARNStore: NewConfigMapARNStore(k8s, nil, configMap)}
+			certificate, err := r.findCertificate(ctx, acmFake, secret, "example.com")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(aws.ToString(certificate.CertificateArn)).To(Equal(stored))
+			Expect(acmFake.called("ListCertificates")).To(Equal(0))
+		})
+
+		It("forgets a stored ARN that is gone and scans instead", func() {
+			r := &SecretReconciler{Log: logr.Discard()}
+			Expect(r.arnStore().Set(ctx, key, "arn:aws:acm:us-east-1:123456789012:certificate/deleted")).To(Succeed())
+
+			certificate, err := r.findCertificate(ctx, acmFake, secret, "example.com")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(certificate).NotTo(BeNil())
+			Expect(acmFake.called("ListCertificates")).To(Equal(1))
+			_, ok, err := r.arnStore().Get(ctx, key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ok).To(BeFalse())
+		})
+	})
+})
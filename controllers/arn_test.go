@@ -0,0 +1,253 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("findCertificate", func() {
+	var (
+		ctx     context.Context
+		acmFake *fakeACM
+		secret  *corev1.Secret
+		r       *SecretReconciler
+		byScan  string
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		acmFake = newFakeACM()
+		byScan = acmFake.add("example.com", &fakeCertificate{Detail: types.CertificateDetail{
+			Type:                    types.CertificateTypeImported,
+			SubjectAlternativeNames: []string{"example.com"},
+		}})
+		secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web-tls", Annotations: map[string]string{}}}
+		r = &SecretReconciler{Log: logr.Discard()}
+	})
+
+	It("uses the recorded ARN without listing certificates", func() {
+		recorded := acmFake.add("example.com", &fakeCertificate{
+			Detail: types.CertificateDetail{Type: types.CertificateTypeImported},
+			Tags:   []types.Tag{{Key: aws.String(secretTagKey), Value: aws.String("prod/web-tls")}},
+		})
+		secret.Annotations[arnAnnotation] = recorded
+
+		certificate, err := r.findCertificate(ctx, acmFake, secret, "example.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(aws.ToString(certificate.CertificateArn)).To(Equal(recorded))
+		Expect(acmFake.called("ListCertificates")).To(Equal(0))
+	})
+
+	It("falls back to a domain scan when the recorded ARN is gone", func() {
+		secret.Annotations[arnAnnotation] = "arn:aws:acm:us-east-1:123456789012:certificate/deleted"
+
+		certificate, err := r.findCertificate(ctx, acmFake, secret, "example.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(aws.ToString(certificate.CertificateArn)).To(Equal(byScan))
+		Expect(acmFake.called("ListCertificates")).To(Equal(1))
+	})
+
+	It("falls back to a domain scan when the recorded ARN isn't imported", func() {
+		secret.Annotations[arnAnnotation] = acmFake.add("example.com", &fakeCertificate{Detail: types.CertificateDetail{Type: types.CertificateTypeAmazonIssued}})
+
+		certificate, err := r.findCertificate(ctx, acmFake, secret, "example.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(aws.ToString(certificate.CertificateArn)).To(Equal(byScan))
+	})
+
+	Context("with ReuseTaggedCertificates", func() {
+		var regional *fakeACM
+
+		BeforeEach(func() {
+			r.ReuseTaggedCertificates = true
+			regional = newFakeACM()
+			regional.region = "eu-west-1"
+			regional.add("old.example.com", &fakeCertificate{Detail: types.CertificateDetail{Type: types.CertificateTypeImported}})
+		})
+
+		It("reuses the certificate tagged for the Secret in that region", func() {
+			tagged := regional.add("old.example.com", &fakeCertificate{
+				Detail: types.CertificateDetail{Type: types.CertificateTypeImported},
+				Tags:   []types.Tag{{Key: aws.String(secretTagKey), Value: aws.String("prod/web-tls")}},
+			})
+
+			certificate, err := r.findCertificate(ctx, regional, secret, "example.com")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(aws.ToString(certificate.CertificateArn)).To(Equal(tagged))
+		})
+
+		It("imports anew when the region has no certificate tagged for the Secret", func() {
+			regional.add("old.example.com", &fakeCertificate{
+				Detail: types.CertificateDetail{Type: types.CertificateTypeImported},
+				Tags:   []types.Tag{{Key: aws.String(secretTagKey), Value: aws.String("prod/other-tls")}},
+			})
+
+			certificate, err := r.findCertificate(ctx, regional, secret, "example.com")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(certificate).To(BeNil())
+		})
+
+		It("doesn't look at tags when disabled", func() {
+			r.ReuseTaggedCertificates = false
+			regional.add("old.example.com", &fakeCertificate{
+				Detail: types.CertificateDetail{Type: types.CertificateTypeImported},
+				Tags:   []types.Tag{{Key: aws.String(secretTagKey), Value: aws.String("prod/web-tls")}},
+			})
+
+			certificate, err := r.findCertificate(ctx, regional, secret, "example.com")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(certificate).To(BeNil())
+			Expect(regional.called("ListTagsForCertificate")).To(Equal(0))
+		})
+	})
+})
+
+var _ = Describe("recorded ARNs", func() {
+	const (
+		east = "arn:aws:acm:us-east-1:123456789012:certificate/1"
+		west = "arn:aws:acm:eu-west-1:123456789012:certificate/2"
+	)
+
+	It("picks the ARN of the region", func() {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{arnAnnotation: east + ", " + west}}}
+		Expect(recordedArn(secret, "us-east-1")).To(Equal(east))
+		Expect(recordedArn(secret, "eu-west-1")).To(Equal(west))
+		Expect(recordedArn(secret, "ap-southeast-2")).To(BeEmpty())
+	})
+
+	It("ignores malformed ARNs", func() {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			arnAnnotation: "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/web/1,certificate/1," + west,
+		}}}
+		Expect(recordedArn(secret, "us-east-1")).To(BeEmpty())
+		Expect(recordedArn(secret, "eu-west-1")).To(Equal(west))
+		Expect(isCertificateArn("arn:aws:acm:us-east-1:123456789012:certificate/")).To(BeFalse())
+	})
+
+	It("replaces only the ARN of the same region", func() {
+		replaced := "arn:aws:acm:us-east-1:123456789012:certificate/3"
+		Expect(withRecordedArn(east+","+west, replaced)).To(Equal(west + "," + replaced))
+		Expect(withRecordedArn("", east)).To(Equal(east))
+	})
+})
+
+var _ = Describe("targeting an ACM certificate by ARN", func() {
+	var (
+		ctx     context.Context
+		acmFake *fakeACM
+		secret  *corev1.Secret
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		acmFake = newFakeACM()
+		_, intermediate, leaf := newTestChain("example.com")
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "prod",
+				Name:      "web-tls",
+				Annotations: map[string]string{
+					"sync-to-acm":                 "true",
+					"cert-manager.io/common-name": "example.com",
+				},
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       append(append([]byte{}, leaf.PEM...), intermediate.PEM...),
+				corev1.TLSPrivateKeyKey: leaf.keyPEM(),
+			},
+		}
+	})
+
+	reconcileSecret := func() {
+		r := &SecretReconciler{Client: fake.NewClientBuilder().WithObjects(secret).Build(), Log: logr.Discard(), ACM: acmFake}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	It("re-imports into the annotated certificate", func() {
+		// Pinned to a load balancer under another name, so a domain search
+		// wouldn't find it
+		pinned := acmFake.add("legacy.example.org", &fakeCertificate{Detail: types.CertificateDetail{
+			Type:    types.CertificateTypeImported,
+			Serial:  aws.String("01"),
+			InUseBy: []string{"arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/web/1"},
+		}, Tags: []types.Tag{{Key: aws.String(secretTagKey), Value: aws.String("prod/web-tls")}}})
+		secret.Annotations[arnAnnotation] = pinned
+
+		reconcileSecret()
+		Expect(acmFake.called("ImportCertificate")).To(Equal(1))
+		Expect(acmFake.certs).To(HaveLen(1))
+		Expect(acmFake.certs[0].Detail.DomainName).To(Equal(aws.String("example.com")))
+		Expect(aws.ToString(acmFake.certs[0].Detail.CertificateArn)).To(Equal(pinned))
+	})
+
+	Context("when the annotated certificate wasn't imported from the Secret", func() {
+		var pinned string
+
+		BeforeEach(func() {
+			pinned = acmFake.add("legacy.example.org", &fakeCertificate{Detail: types.CertificateDetail{
+				Type:   types.CertificateTypeImported,
+				Serial: aws.String("01"),
+			}, Tags: []types.Tag{{Key: aws.String(secretTagKey), Value: aws.String("payments/legacy-tls")}}})
+			secret.Annotations[arnAnnotation] = pinned
+		})
+
+		It("leaves it alone and imports anew", func() {
+			recorder := record.NewFakeRecorder(10)
+			r := &SecretReconciler{Client: fake.NewClientBuilder().WithObjects(secret).Build(), Log: logr.Discard(), ACM: acmFake, Recorder: recorder}
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(acmFake.certs).To(HaveLen(2))
+			Expect(aws.ToString(acmFake.certs[0].Detail.CertificateArn)).To(Equal(pinned))
+			Expect(acmFake.certs[0].Detail.Serial).To(Equal(aws.String("01")))
+			Expect(recorder.Events).To(Receive(And(HavePrefix("Warning "+reasonUnownedCertificate), ContainSubstring(pinned))))
+		})
+
+		It("re-imports into it with AllowPinnedARNs", func() {
+			r := &SecretReconciler{Client: fake.NewClientBuilder().WithObjects(secret).Build(), Log: logr.Discard(), ACM: acmFake, AllowPinnedARNs: true}
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(acmFake.certs).To(HaveLen(1))
+			Expect(acmFake.certs[0].Detail.DomainName).To(Equal(aws.String("example.com")))
+		})
+	})
+
+	It("imports anew when the annotated certificate is gone", func() {
+		secret.Annotations[arnAnnotation] = "arn:aws:acm:us-east-1:123456789012:certificate/deleted"
+
+		reconcileSecret()
+		Expect(acmFake.called("ImportCertificate")).To(Equal(1))
+		Expect(acmFake.certs).To(HaveLen(1))
+		Expect(aws.ToString(acmFake.certs[0].Detail.CertificateArn)).NotTo(HaveSuffix("/deleted"))
+	})
+})
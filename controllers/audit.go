@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// AuditAction is a mutating ACM operation recorded in the audit log.
+type AuditAction string
+
+const (
+	AuditActionImport AuditAction = "import"
+	AuditActionUpdate AuditAction = "update"
+	AuditActionDelete AuditAction = "delete"
+)
+
+const (
+	auditResultSuccess = "success"
+	auditResultFailure = "failure"
+)
+
+// AuditEntry is a single audit record. It must never carry key material.
+type AuditEntry struct {
+	Timestamp      time.Time   `json:"timestamp"`
+	Action         AuditAction `json:"action"`
+	Result         string      `json:"result"`
+	Secret         string      `json:"secret"`
+	Domain         string      `json:"domain"`
+	CertificateArn string      `json:"certificateArn,omitempty"`
+	Region         string      `json:"region,omitempty"`
+	Error          string      `json:"error,omitempty"`
+}
+
+// AuditLogger writes one JSON object per line for every mutating ACM call so
+// the stream can be shipped to a SIEM independently of the controller logs.
+type AuditLogger struct {
+	mu  sync.Mutex
+	w   io.Writer
+	now func() time.Time
+}
+
+// NewAuditLogger returns an AuditLogger writing to w.
+func NewAuditLogger(w io.Writer) *AuditLogger {
+	return &AuditLogger{w: w, now: time.Now}
+}
+
+// Record writes entry to the audit sink, stamping the timestamp and deriving
+// the result from err. It is a no-op on a nil AuditLogger.
+func (a *AuditLogger) Record(entry AuditEntry, err error) {
+	if a == nil {
+		return
+	}
+
+	entry.Timestamp = a.now().UTC()
+	entry.Result = auditResultSuccess
+	if err != nil {
+		entry.Result = auditResultFailure
+		entry.Error = err.Error()
+	}
+
+	line, mErr := json.Marshal(entry)
+	if mErr != nil {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, _ = a.w.Write(append(line, '\n'))
+}
+
+// record reports a mutating ACM call to the audit log and session summary.
+func (r *SecretReconciler) record(entry AuditEntry, err error) {
+	r.Audit.Record(entry, err)
+	r.Summary.Record(entry, err)
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("AuditLogger", func() {
+	var (
+		buf   *bytes.Buffer
+		audit *AuditLogger
+		now   = time.Date(2024, 9, 15, 12, 0, 0, 0, time.UTC)
+	)
+
+	BeforeEach(func() {
+		buf = &bytes.Buffer{}
+		audit = NewAuditLogger(buf)
+		audit.now = func() time.Time { return now }
+	})
+
+	decode := func() map[string]interface{} {
+		var entry map[string]interface{}
+		Expect(json.Unmarshal(buf.Bytes(), &entry)).To(Succeed())
+		return entry
+	}
+
+	DescribeTable("records mutating actions",
+		func(action AuditAction, err error, result string) {
+			audit.Record(AuditEntry{
+				Action:         action,
+				Secret:         "prod/web-tls",
+				Domain:         "example.com",
+				CertificateArn: "arn:aws:acm:us-east-1:123456789012:certificate/abc",
+				Region:         "us-east-1",
+			}, err)
+
+			entry := decode()
+			Expect(entry).To(HaveKeyWithValue("timestamp", "2024-09-15T12:00:00Z"))
+			Expect(entry).To(HaveKeyWithValue("action", string(action)))
+			Expect(entry).To(HaveKeyWithValue("result", result))
+			Expect(entry).To(HaveKeyWithValue("secret", "prod/web-tls"))
+			Expect(entry).To(HaveKeyWithValue("domain", "example.com"))
+			Expect(entry).To(HaveKeyWithValue("certificateArn", "arn:aws:acm:us-east-1:123456789012:certificate/abc"))
+			Expect(entry).To(HaveKeyWithValue("region", "us-east-1"))
+			Expect(buf.String()).NotTo(ContainSubstring("PRIVATE KEY"))
+		},
+		Entry("import", AuditActionImport, nil, "success"),
+		Entry("update", AuditActionUpdate, nil, "success"),
+		Entry("delete", AuditActionDelete, nil, "success"),
+		Entry("failed import", AuditActionImport, errors.New("boom"), "failure"),
+	)
+
+	It("includes the error on failure", func() {
+		audit.Record(AuditEntry{Action: AuditActionUpdate}, errors.New("ValidationException"))
+		Expect(decode()).To(HaveKeyWithValue("error", "ValidationException"))
+	})
+
+	It("is a no-op when nil", func() {
+		var nilAudit *AuditLogger
+		Expect(func() { nilAudit.Record(AuditEntry{}, nil) }).NotTo(Panic())
+	})
+})
+
+var _ = Describe("Reconcile auditing", func() {
+	It("records one entry per import, update and delete", func() {
+		ctx := context.Background()
+		_, intermediate, leaf := newTestChain("example.com")
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "prod",
+				Name:      "web-tls",
+				Annotations: map[string]string{
+					"sync-to-acm":                 "true",
+					"cert-manager.io/common-name": "example.com",
+				},
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       append(append([]byte{}, leaf.PEM...), intermediate.PEM...),
+				corev1.TLSPrivateKeyKey: leaf.keyPEM(),
+			},
+		}
+		key := client.ObjectKeyFromObject(secret)
+		k8s := fake.NewClientBuilder().WithObjects(secret).Build()
+		acmFake := newFakeACM()
+		buf := &bytes.Buffer{}
+		r := &SecretReconciler{Client: k8s, Log: logr.Discard(), ACM: acmFake, Audit: NewAuditLogger(buf), CleanupOnDelete: true}
+
+		reconcileSecret := func() {
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+		}
+		entries := func() []AuditEntry {
+			var entries []AuditEntry
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				var entry AuditEntry
+				Expect(json.Unmarshal([]byte(line), &entry)).To(Succeed())
+				entries = append(entries, entry)
+			}
+			return entries
+		}
+		expectEntry := func(entry AuditEntry, action AuditAction, certificateArn string) {
+			Expect(entry.Action).To(Equal(action))
+			Expect(entry.Result).To(Equal(auditResultSuccess))
+			Expect(entry.Secret).To(Equal("prod/web-tls"))
+			Expect(entry.Domain).To(Equal("example.com"))
+			Expect(entry.CertificateArn).To(Equal(certificateArn))
+			Expect(entry.Region).To(Equal("us-east-1"))
+		}
+
+		reconcileSecret()
+		Expect(acmFake.called("ImportCertificate")).To(Equal(1))
+		Expect(acmFake.certs).To(HaveLen(1))
+		certificateArn := aws.ToString(acmFake.certs[0].Detail.CertificateArn)
+		Expect(entries()).To(HaveLen(1))
+		expectEntry(entries()[0], AuditActionImport, certificateArn)
+
+		_, intermediate, renewed := newTestChain("example.com")
+		Expect(k8s.Get(ctx, key, secret)).To(Succeed())
+		secret.Data[corev1.TLSCertKey] = append(append([]byte{}, renewed.PEM...), intermediate.PEM...)
+		secret.Data[corev1.TLSPrivateKeyKey] = renewed.keyPEM()
+		Expect(k8s.Update(ctx, secret)).To(Succeed())
+		reconcileSecret()
+		Expect(acmFake.called("ImportCertificate")).To(Equal(2))
+		Expect(entries()).To(HaveLen(2))
+		expectEntry(entries()[1], AuditActionUpdate, certificateArn)
+
+		Expect(k8s.Delete(ctx, secret)).To(Succeed())
+		reconcileSecret()
+		Expect(acmFake.called("DeleteCertificate")).To(Equal(1))
+		Expect(entries()).To(HaveLen(3))
+		expectEntry(entries()[2], AuditActionDelete, certificateArn)
+	})
+})
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// cacheSyncRecheckInterval is how soon a reconcile that ran before the
+// Secret cache synced is retried.
+const cacheSyncRecheckInterval = time.Second
+
+// watchCacheSync makes Reconcile wait for the Secret informer of mgr to sync
+// when WaitForCacheSync is set.
+func (r *SecretReconciler) watchCacheSync(ctx context.Context, mgr ctrl.Manager) error {
+	if !r.WaitForCacheSync {
+		return nil
+	}
+	informer, err := mgr.GetCache().GetInformer(ctx, &corev1.Secret{})
+	if err != nil {
+		return err
+	}
+	r.cacheSynced = informer.HasSynced
+	return nil
+}
+
+// awaitingCacheSync reports whether the Secret cache hasn't synced yet, in
+// which case a Secret missing from it may well exist and the reconcile is
+// better retried than treated as a deletion.
+func (r *SecretReconciler) awaitingCacheSync(log logr.Logger) bool {
+	if r.cacheSynced == nil || r.cacheSynced() {
+		return false
+	}
+	log.Info("Secret cache has not synced yet; requeueing")
+	return true
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+var _ = Describe("reconciling before the cache synced", func() {
+	var (
+		ctx    context.Context
+		r      *SecretReconciler
+		synced bool
+		gets   int
+		req    ctrl.Request
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		synced, gets = false, 0
+		req = ctrl.Request{NamespacedName: client.ObjectKey{Namespace: "prod", Name: "web-tls"}}
+		k8s := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				gets++
+				return c.Get(ctx, key, obj, opts...)
+			},
+		}).Build()
+		r = &SecretReconciler{Client: k8s, Log: logr.Discard(), cacheSynced: func() bool { return synced }}
+	})
+
+	It("requeues without reading the Secret", func() {
+		secondsSinceLastSuccess.markSuccess(req.NamespacedName)
+
+		result, err := r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(ctrl.Result{RequeueAfter: cacheSyncRecheckInterval}))
+		Expect(gets).To(Equal(0))
+		// The Secret isn't taken for deleted
+		Expect(secondsSinceLastSuccess.last).To(HaveKey(req.NamespacedName))
+		secondsSinceLastSuccess.forget(req.NamespacedName)
+	})
+
+	It("reconciles once the cache synced", func() {
+		synced = true
+		result, err := r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(ctrl.Result{}))
+		Expect(gets).To(Equal(1))
+	})
+
+	It("does not wait unless enabled", func() {
+		r.cacheSynced = nil
+		_, err := r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gets).To(Equal(1))
+	})
+})
+
+var _ = Describe("watchCacheSync", func() {
+	It("does nothing unless enabled", func() {
+		r := &SecretReconciler{}
+		Expect(r.watchCacheSync(context.Background(), nil)).To(Succeed())
+		Expect(r.cacheSynced).To(BeNil())
+	})
+})
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/smithy-go"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+// isChainError reports whether err is ACM rejecting the certificate chain of
+// an import.
+func isChainError(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "ValidationException", "InvalidParameterException":
+		return strings.Contains(strings.ToLower(apiErr.ErrorMessage()), "chain")
+	}
+	return false
+}
+
+// importCertificate imports input into ACM. With RepairChainOnError, an
+// import ACM rejects because of its chain is retried once with the chain in
+// canonical order (see canonicalChain), so chain ordering is only looked at
+// when it actually causes a failure.
+func (r *SecretReconciler) importCertificate(ctx context.Context, acmClient awsclient.ACMAPI, input *acm.ImportCertificateInput) (*acm.ImportCertificateOutput, error) {
+	output, err := r.importThrottled(ctx, acmClient, input)
+	if err == nil || !r.RepairChainOnError || !isChainError(err) {
+		return output, err
+	}
+
+	leaf, parseErr := parseLeafCertificate(input.Certificate)
+	if parseErr != nil {
+		return nil, err
+	}
+	repaired, repairErr := canonicalChain(leaf, input.CertificateChain)
+	if repairErr != nil || bytes.Equal(repaired, input.CertificateChain) {
+		return nil, err
+	}
+
+	r.Log.Info("ACM rejected the certificate chain; retrying with a repaired chain", "certificateArn", aws.ToString(input.CertificateArn), "reason", err.Error())
+	retry := *input
+	retry.CertificateChain = repaired
+	return r.importThrottled(ctx, acmClient, &retry)
+}
+
+// importThrottled calls ImportCertificate, retrying it up to
+// ImportThrottleRetries times while ACM throttles it.
+func (r *SecretReconciler) importThrottled(ctx context.Context, acmClient awsclient.ACMAPI, input *acm.ImportCertificateInput) (*acm.ImportCertificateOutput, error) {
+	return retryThrottled(ctx, r, "ImportCertificate", r.ImportThrottleRetries, func() (*acm.ImportCertificateOutput, error) {
+		return acmClient.ImportCertificate(ctx, input)
+	})
+}
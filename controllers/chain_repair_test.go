@@ -0,0 +1,95 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/smithy-go"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("chain repair on import errors", func() {
+	var (
+		ctx                      context.Context
+		acmFake                  *fakeACM
+		secret                   *corev1.Secret
+		r                        *SecretReconciler
+		root, intermediate, leaf *testCert
+		misordered               []byte
+	)
+
+	chainErr := &smithy.GenericAPIError{Code: "ValidationException", Message: "Could not validate the certificate with the certificate chain."}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		acmFake = newFakeACM()
+		root, intermediate, leaf = newTestChain("example.com")
+		misordered = append(append([]byte{}, root.PEM...), intermediate.PEM...)
+		secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web-tls"}}
+		r = &SecretReconciler{Log: logr.Discard(), RepairChainOnError: true}
+	})
+
+	It("retries once with the repaired chain", func() {
+		acmFake.importErrs = []error{chainErr}
+
+		arn, err := r.importToAcm(ctx, acmFake, secret, leaf.PEM, misordered, []byte("key"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acmFake.called("ImportCertificate")).To(Equal(2))
+		stored, err := acmFake.get(aws.String(arn))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(certificateDERs([]byte(stored.Chain))).To(Equal([][]byte{intermediate.Cert.Raw}))
+	})
+
+	It("repairs the chain on update too", func() {
+		arn := acmFake.add("example.com", &fakeCertificate{})
+		acmFake.importErrs = []error{chainErr}
+
+		Expect(r.updateToAcm(ctx, acmFake, secret, aws.String(arn), leaf.PEM, misordered, []byte("key"))).To(Succeed())
+		Expect(acmFake.called("ImportCertificate")).To(Equal(2))
+	})
+
+	It("gives up after one retry", func() {
+		acmFake.importErrs = []error{chainErr, chainErr}
+
+		_, err := r.importToAcm(ctx, acmFake, secret, leaf.PEM, misordered, []byte("key"))
+		Expect(err).To(HaveOccurred())
+		Expect(acmFake.called("ImportCertificate")).To(Equal(2))
+	})
+
+	It("doesn't retry other errors", func() {
+		acmFake.importErrs = []error{&smithy.GenericAPIError{Code: "LimitExceededException", Message: "too many certificates"}}
+
+		_, err := r.importToAcm(ctx, acmFake, secret, leaf.PEM, misordered, []byte("key"))
+		Expect(err).To(HaveOccurred())
+		Expect(acmFake.called("ImportCertificate")).To(Equal(1))
+	})
+
+	It("doesn't retry when disabled", func() {
+		r.RepairChainOnError = false
+		acmFake.importErrs = []error{chainErr}
+
+		_, err := r.importToAcm(ctx, acmFake, secret, leaf.PEM, misordered, []byte("key"))
+		Expect(err).To(HaveOccurred())
+		Expect(acmFake.called("ImportCertificate")).To(Equal(1))
+	})
+})
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+// acmClientPool caches ACM clients by credential context.
+type acmClientPool struct {
+	mu      sync.Mutex
+	clients map[awsclient.ClientKey]*list.Element
+	// lru orders the pooled clients from most to least recently used.
+	lru *list.List
+}
+
+type pooledClient struct {
+	key    awsclient.ClientKey
+	client awsclient.ACMAPI
+}
+
+// get returns the pooled client for key, building it with newClient on a
+// miss and then evicting the least recently used clients beyond size. A size
+// of zero or less keeps every client. Clients are built under the pool lock
+// so concurrent reconciles for the same context don't each build one.
+func (p *acmClientPool) get(ctx context.Context, key awsclient.ClientKey, size int, newClient func(context.Context, awsclient.ClientKey) (awsclient.ACMAPI, error)) (awsclient.ACMAPI, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.clients == nil {
+		p.clients = map[awsclient.ClientKey]*list.Element{}
+		p.lru = list.New()
+	}
+
+	if element, ok := p.clients[key]; ok {
+		p.lru.MoveToFront(element)
+		return element.Value.(*pooledClient).client, nil
+	}
+
+	acmClient, err := newClient(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	p.clients[key] = p.lru.PushFront(&pooledClient{key: key, client: acmClient})
+	for size > 0 && p.lru.Len() > size {
+		oldest := p.lru.Back()
+		p.lru.Remove(oldest)
+		delete(p.clients, oldest.Value.(*pooledClient).key)
+	}
+	return acmClient, nil
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+var _ = Describe("acmClientPool", func() {
+	var (
+		ctx   context.Context
+		pool  *acmClientPool
+		built []awsclient.ClientKey
+	)
+
+	newClient := func(_ context.Context, key awsclient.ClientKey) (awsclient.ACMAPI, error) {
+		built = append(built, key)
+		acmFake := newFakeACM()
+		acmFake.region = key.Region
+		return acmFake, nil
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		pool = &acmClientPool{}
+		built = nil
+	})
+
+	prod := awsclient.ClientKey{Region: "us-east-1", RoleARN: "arn:aws:iam::111111111111:role/cert-sync"}
+	staging := awsclient.ClientKey{Region: "us-east-1", RoleARN: "arn:aws:iam::222222222222:role/cert-sync"}
+	profiled := awsclient.ClientKey{Region: "us-east-1", Profile: "staging"}
+
+	It("reuses the client of a credential context", func() {
+		first, err := pool.get(ctx, prod, 0, newClient)
+		Expect(err).NotTo(HaveOccurred())
+		second, err := pool.get(ctx, prod, 0, newClient)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second).To(BeIdenticalTo(first))
+		Expect(built).To(Equal([]awsclient.ClientKey{prod}))
+	})
+
+	It("builds a client per role, profile and region", func() {
+		for _, key := range []awsclient.ClientKey{prod, staging, profiled, {Region: "eu-west-1", RoleARN: prod.RoleARN}} {
+			_, err := pool.get(ctx, key, 0, newClient)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(built).To(HaveLen(4))
+	})
+
+	It("evicts the least recently used client", func() {
+		for _, key := range []awsclient.ClientKey{prod, staging, prod, profiled} {
+			_, err := pool.get(ctx, key, 2, newClient)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(built).To(Equal([]awsclient.ClientKey{prod, staging, profiled}))
+
+		// staging was evicted, prod wasn't
+		_, err := pool.get(ctx, prod, 2, newClient)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = pool.get(ctx, staging, 2, newClient)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(built).To(Equal([]awsclient.ClientKey{prod, staging, profiled, staging}))
+	})
+
+	It("doesn't pool failed builds", func() {
+		_, err := pool.get(ctx, prod, 0, func(context.Context, awsclient.ClientKey) (awsclient.ACMAPI, error) {
+			return nil, errors.New("no credentials")
+		})
+		Expect(err).To(HaveOccurred())
+
+		_, err = pool.get(ctx, prod, 0, newClient)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(built).To(Equal([]awsclient.ClientKey{prod}))
+	})
+
+	Context("with credential annotations", func() {
+		var (
+			r      *SecretReconciler
+			secret *corev1.Secret
+		)
+
+		BeforeEach(func() {
+			r = &SecretReconciler{
+				Log:                   logr.Discard(),
+				NewACMClient:          newClient,
+				CredentialAnnotations: true,
+				AllowedProfiles:       []string{"staging"},
+				AllowedRoleARNs:       []string{"arn:aws:iam::111111111111:role/*"},
+			}
+			secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+				Namespace: "prod",
+				Name:      "web-tls",
+				Annotations: map[string]string{
+					regionsAnnotation: "us-east-1",
+					roleARNAnnotation: prod.RoleARN,
+					profileAnnotation: "staging",
+				},
+			}}
+		})
+
+		It("builds clients for the Secret's role and profile", func() {
+			_, err := r.acmClientsFor(ctx, secret)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(built).To(Equal([]awsclient.ClientKey{{Region: "us-east-1", Profile: "staging", RoleARN: prod.RoleARN}}))
+		})
+
+		It("refuses a role that isn't allowed", func() {
+			recorder := record.NewFakeRecorder(1)
+			r.Recorder = recorder
+			r.AllowedRoleARNs = []string{"arn:aws:iam::222222222222:role/*"}
+
+			_, err := r.acmClientsFor(ctx, secret)
+			Expect(err).To(MatchError(reconcile.TerminalError(nil)))
+			Expect(built).To(BeEmpty())
+			Expect(recorder.Events).To(Receive(ContainSubstring(reasonCredentialsRejected)))
+		})
+
+		It("refuses a profile that isn't allowed", func() {
+			r.AllowedProfiles = nil
+
+			_, err := r.acmClientsFor(ctx, secret)
+			Expect(err).To(MatchError(ContainSubstring(`AWS profile "staging" is not allowed`)))
+			Expect(built).To(BeEmpty())
+		})
+
+		It("ignores them unless enabled", func() {
+			r.CredentialAnnotations = false
+			_, err := r.acmClientsFor(ctx, secret)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(built).To(Equal([]awsclient.ClientKey{{Region: "us-east-1"}}))
+		})
+	})
+})
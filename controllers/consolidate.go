@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	corev1 "k8s.io/api/core/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+// consolidationKey identifies a Secret in one region for consolidateDuplicates.
+type consolidationKey struct {
+	secret k8stypes.NamespacedName
+	region string
+}
+
+// duplicateCertificate is an ACM certificate holding the Secret's content.
+type duplicateCertificate struct {
+	detail *types.CertificateDetail
+	// owner is the value of its secretTagKey tag, if any.
+	owner string
+}
+
+// consolidateDuplicates deletes the extra copies left in ACM by past
+// duplicate imports of the Secret's leaf and chain for domainName, and
+// returns the certificate kept in their place, or found when there is nothing
+// to consolidate. The survivor is the one in use, then the one tagged for the
+// Secret, then found; it is tagged for the Secret if it isn't yet. Copies
+// that are in use or tagged for another Secret are never deleted. Like
+// adoptIdentical it runs once per Secret and region after the controller
+// starts.
+func (r *SecretReconciler) consolidateDuplicates(ctx context.Context, acmClient awsclient.ACMAPI, secret *corev1.Secret, domainName string, found *types.CertificateDetail, leafPEM, chainPEM []byte) (*types.CertificateDetail, error) {
+	if !r.ConsolidateDuplicates {
+		return found, nil
+	}
+	checked := consolidationKey{secret: client.ObjectKeyFromObject(secret), region: acmClient.Options().Region}
+	if _, seen := r.consolidateChecked.LoadOrStore(checked, struct{}{}); seen {
+		return found, nil
+	}
+
+	survivor, err := r.consolidate(ctx, acmClient, secret, domainName, found, leafPEM, chainPEM)
+	if err != nil {
+		// Try again on the next reconcile
+		r.consolidateChecked.Delete(checked)
+		return nil, err
+	}
+	return survivor, nil
+}
+
+func (r *SecretReconciler) consolidate(ctx context.Context, acmClient awsclient.ACMAPI, secret *corev1.Secret, domainName string, found *types.CertificateDetail, leafPEM, chainPEM []byte) (*types.CertificateDetail, error) {
+	key := client.ObjectKeyFromObject(secret)
+	log := r.Log.WithValues("secret", key)
+
+	duplicates, err := r.identicalCertificates(ctx, acmClient, domainName, leafPEM, chainPEM)
+	if err != nil || len(duplicates) < 2 {
+		return found, err
+	}
+
+	// Rank the copies: in use, then tagged for the Secret, then found
+	rank := func(d duplicateCertificate) int {
+		score := 0
+		if len(d.detail.InUseBy) > 0 {
+			score += 4
+		}
+		if d.owner == key.String() {
+			score += 2
+		}
+		if aws.ToString(d.detail.CertificateArn) == aws.ToString(found.CertificateArn) {
+			score++
+		}
+		return score
+	}
+	survivor := slices.MaxFunc(duplicates, func(a, b duplicateCertificate) int { return rank(a) - rank(b) })
+	survivorArn := aws.ToString(survivor.detail.CertificateArn)
+
+	var deleted []string
+	for _, d := range duplicates {
+		certificateArn := aws.ToString(d.detail.CertificateArn)
+		switch {
+		case certificateArn == survivorArn:
+			continue
+		case len(d.detail.InUseBy) > 0:
+			log.Info("Duplicate ACM certificate is in use; leaving it in place", "certificateArn", certificateArn, "inUseBy", d.detail.InUseBy)
+			continue
+		case d.owner != "" && d.owner != key.String():
+			log.Info("Duplicate ACM certificate belongs to another Secret; leaving it in place", "certificateArn", certificateArn, "owner", d.owner)
+			continue
+		}
+
+		_, err := acmClient.DeleteCertificate(ctx, &acm.DeleteCertificateInput{CertificateArn: d.detail.CertificateArn})
+		r.record(AuditEntry{
+			Action:         AuditActionDelete,
+			Secret:         key.String(),
+			Domain:         domainName,
+			CertificateArn: certificateArn,
+			Region:         acmClient.Options().Region,
+		}, err)
+		if err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, certificateArn)
+	}
+
+	if survivor.owner != key.String() {
+		_, err := acmClient.AddTagsToCertificate(ctx, &acm.AddTagsToCertificateInput{
+			CertificateArn: survivor.detail.CertificateArn,
+			Tags:           r.certificateTags(secret, r.contentHashTags(leafPEM, chainPEM), r.templateTags(secret)),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if slices.Contains(deleted, recordedArn(secret, acmClient.Options().Region)) {
+		original := secret.DeepCopy()
+		secret.Annotations[arnAnnotation] = withRecordedArn(secret.Annotations[arnAnnotation], survivorArn)
+		if err := r.writeBack(ctx, original, secret); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Info("Consolidated duplicate ACM certificates", "certificateArn", survivorArn, "deleted", deleted)
+	return survivor.detail, nil
+}
+
+// identicalCertificates returns the imported certificates for domainName that
+// hold exactly leafPEM and chainPEM.
+func (r *SecretReconciler) identicalCertificates(ctx context.Context, acmClient awsclient.ACMAPI, domainName string, leafPEM, chainPEM []byte) ([]duplicateCertificate, error) {
+	var duplicates []duplicateCertificate
+	err := r.forEachImported(ctx, acmClient, func(summary types.CertificateSummary) (bool, error) {
+		certificate, err := describeImported(ctx, acmClient, aws.ToString(summary.CertificateArn))
+		if err != nil || certificate == nil || !certMatchesDomain(certificate, domainName) {
+			return false, err
+		}
+
+		stored, err := acmClient.GetCertificate(ctx, &acm.GetCertificateInput{CertificateArn: certificate.CertificateArn})
+		if err != nil {
+			return false, err
+		}
+		if !sameCertificateContent(aws.ToString(stored.Certificate), aws.ToString(stored.CertificateChain), leafPEM, chainPEM) {
+			return false, nil
+		}
+
+		tags, err := acmClient.ListTagsForCertificate(ctx, &acm.ListTagsForCertificateInput{CertificateArn: certificate.CertificateArn})
+		if err != nil {
+			return false, err
+		}
+		duplicate := duplicateCertificate{detail: certificate}
+		for _, tag := range tags.Tags {
+			if aws.ToString(tag.Key) == secretTagKey {
+				duplicate.owner = aws.ToString(tag.Value)
+			}
+		}
+		duplicates = append(duplicates, duplicate)
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return duplicates, nil
+}
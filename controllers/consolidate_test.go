@@ -0,0 +1,164 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("consolidateDuplicates", func() {
+	var (
+		ctx                context.Context
+		acmFake            *fakeACM
+		secret             *corev1.Secret
+		r                  *SecretReconciler
+		intermediate, leaf *testCert
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		acmFake = newFakeACM()
+		_, intermediate, leaf = newTestChain("example.com")
+		secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web-tls"}}
+		r = &SecretReconciler{
+			Client:                fake.NewClientBuilder().WithObjects(secret).Build(),
+			Log:                   logr.Discard(),
+			ConsolidateDuplicates: true,
+		}
+	})
+
+	addCopy := func(tags ...types.Tag) string {
+		return acmFake.add("example.com", &fakeCertificate{
+			Detail: types.CertificateDetail{Type: types.CertificateTypeImported, SubjectAlternativeNames: []string{"example.com"}},
+			Cert:   string(leaf.PEM),
+			Chain:  string(intermediate.PEM),
+			Tags:   tags,
+		})
+	}
+	detail := func(arn string) *types.CertificateDetail {
+		c, err := acmFake.get(aws.String(arn))
+		Expect(err).NotTo(HaveOccurred())
+		return &c.Detail
+	}
+	arns := func() []string {
+		var out []string
+		for _, c := range acmFake.certs {
+			out = append(out, aws.ToString(c.Detail.CertificateArn))
+		}
+		return out
+	}
+	ownerTag := func(owner string) types.Tag {
+		return types.Tag{Key: aws.String(secretTagKey), Value: aws.String(owner)}
+	}
+
+	It("keeps the copy in use and deletes the rest", func() {
+		found := addCopy()
+		inUse := addCopy()
+		addCopy()
+		detail(inUse).InUseBy = []string{"arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/web"}
+
+		survivor, err := r.consolidateDuplicates(ctx, acmFake, secret, "example.com", detail(found), leaf.PEM, intermediate.PEM)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(aws.ToString(survivor.CertificateArn)).To(Equal(inUse))
+		Expect(arns()).To(ConsistOf(inUse))
+		Expect(acmFake.tag(inUse, secretTagKey)).To(Equal("prod/web-tls"))
+	})
+
+	It("prefers the copy tagged for the Secret", func() {
+		found := addCopy()
+		owned := addCopy(ownerTag("prod/web-tls"))
+
+		survivor, err := r.consolidateDuplicates(ctx, acmFake, secret, "example.com", detail(found), leaf.PEM, intermediate.PEM)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(aws.ToString(survivor.CertificateArn)).To(Equal(owned))
+		Expect(arns()).To(ConsistOf(owned))
+		Expect(acmFake.called("AddTagsToCertificate")).To(Equal(0))
+	})
+
+	It("never deletes copies in use or tagged for another Secret", func() {
+		found := addCopy(ownerTag("prod/web-tls"))
+		inUse := addCopy()
+		other := addCopy(ownerTag("prod/other-tls"))
+		detail(inUse).InUseBy = []string{"arn:aws:cloudfront::123456789012:distribution/E1"}
+		detail(found).InUseBy = []string{"arn:aws:cloudfront::123456789012:distribution/E2"}
+
+		_, err := r.consolidateDuplicates(ctx, acmFake, secret, "example.com", detail(found), leaf.PEM, intermediate.PEM)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(arns()).To(ConsistOf(found, inUse, other))
+		Expect(acmFake.called("DeleteCertificate")).To(Equal(0))
+	})
+
+	It("leaves certificates with other content alone", func() {
+		found := addCopy()
+		_, otherIntermediate, otherLeaf := newTestChain("example.com")
+		different := acmFake.add("example.com", &fakeCertificate{
+			Detail: types.CertificateDetail{Type: types.CertificateTypeImported, SubjectAlternativeNames: []string{"example.com"}},
+			Cert:   string(otherLeaf.PEM),
+			Chain:  string(otherIntermediate.PEM),
+		})
+
+		survivor, err := r.consolidateDuplicates(ctx, acmFake, secret, "example.com", detail(found), leaf.PEM, intermediate.PEM)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(aws.ToString(survivor.CertificateArn)).To(Equal(found))
+		Expect(arns()).To(ConsistOf(found, different))
+	})
+
+	It("points the ARN annotation at the survivor", func() {
+		found := addCopy()
+		owned := addCopy(ownerTag("prod/web-tls"))
+		secret.Annotations = map[string]string{arnAnnotation: found}
+
+		_, err := r.consolidateDuplicates(ctx, acmFake, secret, "example.com", detail(found), leaf.PEM, intermediate.PEM)
+		Expect(err).NotTo(HaveOccurred())
+
+		var stored corev1.Secret
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(secret), &stored)).To(Succeed())
+		Expect(stored.Annotations).To(HaveKeyWithValue(arnAnnotation, owned))
+	})
+
+	It("only runs once per Secret and region", func() {
+		found := addCopy()
+		_, err := r.consolidateDuplicates(ctx, acmFake, secret, "example.com", detail(found), leaf.PEM, intermediate.PEM)
+		Expect(err).NotTo(HaveOccurred())
+
+		addCopy()
+		_, err = r.consolidateDuplicates(ctx, acmFake, secret, "example.com", detail(found), leaf.PEM, intermediate.PEM)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acmFake.certs).To(HaveLen(2))
+	})
+
+	It("does nothing when disabled", func() {
+		r.ConsolidateDuplicates = false
+		found := addCopy()
+		addCopy()
+
+		survivor, err := r.consolidateDuplicates(ctx, acmFake, secret, "example.com", detail(found), leaf.PEM, intermediate.PEM)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(aws.ToString(survivor.CertificateArn)).To(Equal(found))
+		Expect(acmFake.called("ListCertificates")).To(Equal(0))
+	})
+})
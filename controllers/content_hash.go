@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"math/big"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	corev1 "k8s.io/api/core/v1"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+// contentHashTagKey records the contentHash of the leaf and chain last
+// imported into an ACM certificate.
+const contentHashTagKey = "cert-sync/content-sha"
+
+// contentHash returns the SHA-256 of the DER bytes of the certificates in
+// leafPEM and chainPEM, so PEM formatting doesn't change it.
+func contentHash(leafPEM, chainPEM []byte) string {
+	h := sha256.New()
+	for _, der := range certificateDERs(leafPEM) {
+		h.Write(der)
+	}
+	for _, der := range certificateDERs(chainPEM) {
+		h.Write(der)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// contentHashTags returns the content hash tag for leafPEM and chainPEM, or
+// nil when ContentHashTags is disabled.
+func (r *SecretReconciler) contentHashTags(leafPEM, chainPEM []byte) []types.Tag {
+	if !r.ContentHashTags {
+		return nil
+	}
+	return []types.Tag{{Key: aws.String(contentHashTagKey), Value: aws.String(contentHash(leafPEM, chainPEM))}}
+}
+
+// contentChanged reports whether the Secret's certificate differs from the one
+// held in ACM, without downloading it, and whether the certificate already
+// records the current version of secret. With SourceVersionTags, a
+// certificate last synced from the current version of secret is unchanged
+// without further checks. Otherwise the serial number in detail catches a
+// reissued leaf and, with ContentHashTags, the recorded hash a changed chain.
+// Without either tag to go on the content is assumed unchanged. It never
+// writes to ACM; see recordSourceVersion.
+func (r *SecretReconciler) contentChanged(ctx context.Context, acmClient awsclient.ACMAPI, detail *types.CertificateDetail, secret *corev1.Secret, leaf *x509.Certificate, leafPEM, chainPEM []byte) (changed, current bool, err error) {
+	var tags []types.Tag
+	if r.ContentHashTags || r.SourceVersionTags {
+		output, err := acmClient.ListTagsForCertificate(ctx, &acm.ListTagsForCertificateInput{CertificateArn: detail.CertificateArn})
+		if err != nil {
+			return false, false, err
+		}
+		tags = output.Tags
+	}
+	if r.SourceVersionTags && sourceUnchanged(tags, secret) {
+		return false, true, nil
+	}
+
+	if detail.Serial != nil && !serialMatches(aws.ToString(detail.Serial), leaf) {
+		return true, false, nil
+	}
+	if r.ContentHashTags {
+		for _, tag := range tags {
+			if aws.ToString(tag.Key) == contentHashTagKey && aws.ToString(tag.Value) != contentHash(leafPEM, chainPEM) {
+				return true, false, nil
+			}
+		}
+	}
+	return false, false, nil
+}
+
+// serialMatches reports whether serial, in ACM's colon-separated hex form,
+// is the serial number of leaf.
+func serialMatches(serial string, leaf *x509.Certificate) bool {
+	n, ok := new(big.Int).SetString(strings.ReplaceAll(serial, ":", ""), 16)
+	return ok && n.Cmp(leaf.SerialNumber) == 0
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("content hash tag", func() {
+	var (
+		ctx                context.Context
+		acmFake            *fakeACM
+		secret             *corev1.Secret
+		r                  *SecretReconciler
+		intermediate, leaf *testCert
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		acmFake = newFakeACM()
+		_, intermediate, leaf = newTestChain("example.com")
+		secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web-tls"}}
+		r = &SecretReconciler{Log: logr.Discard(), ContentHashTags: true}
+	})
+
+	It("ignores PEM formatting", func() {
+		reformatted := append([]byte("\n"), leaf.PEM...)
+		Expect(contentHash(reformatted, intermediate.PEM)).To(Equal(contentHash(leaf.PEM, intermediate.PEM)))
+		Expect(contentHash(leaf.PEM, nil)).NotTo(Equal(contentHash(leaf.PEM, intermediate.PEM)))
+	})
+
+	It("is set on import", func() {
+		arn, err := r.importToAcm(ctx, acmFake, secret, leaf.PEM, intermediate.PEM, []byte("key"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acmFake.tag(arn, contentHashTagKey)).To(Equal(contentHash(leaf.PEM, intermediate.PEM)))
+	})
+
+	It("is replaced on update", func() {
+		arn := acmFake.add("example.com", &fakeCertificate{
+			Detail: types.CertificateDetail{Type: types.CertificateTypeImported},
+			Cert:   string(leaf.PEM),
+			Chain:  string(intermediate.PEM),
+			Tags:   []types.Tag{{Key: aws.String(contentHashTagKey), Value: aws.String(contentHash(leaf.PEM, intermediate.PEM))}},
+		})
+		_, newIntermediate, newLeaf := newTestChain("example.com")
+
+		Expect(r.updateToAcm(ctx, acmFake, secret, aws.String(arn), newLeaf.PEM, newIntermediate.PEM, []byte("key"))).To(Succeed())
+		Expect(acmFake.tag(arn, contentHashTagKey)).To(Equal(contentHash(newLeaf.PEM, newIntermediate.PEM)))
+	})
+
+	It("is compared instead of fetching the certificate", func() {
+		arn := aws.String(acmFake.add("example.com", &fakeCertificate{
+			Detail: types.CertificateDetail{Type: types.CertificateTypeImported},
+			Cert:   string(leaf.PEM),
+			Chain:  string(intermediate.PEM),
+			Tags:   []types.Tag{{Key: aws.String(contentHashTagKey), Value: aws.String(contentHash(leaf.PEM, intermediate.PEM))}},
+		}))
+
+		matches, err := r.acmContentMatches(ctx, acmFake, arn, leaf.PEM, intermediate.PEM)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(matches).To(BeTrue())
+
+		_, otherIntermediate, otherLeaf := newTestChain("example.com")
+		matches, err = r.acmContentMatches(ctx, acmFake, arn, otherLeaf.PEM, otherIntermediate.PEM)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(matches).To(BeFalse())
+		Expect(acmFake.called("GetCertificate")).To(Equal(0))
+	})
+
+	It("falls back to fetching the certificate when the tag is missing", func() {
+		arn := aws.String(acmFake.add("example.com", &fakeCertificate{
+			Detail: types.CertificateDetail{Type: types.CertificateTypeImported},
+			Cert:   string(leaf.PEM),
+			Chain:  string(intermediate.PEM),
+		}))
+
+		matches, err := r.acmContentMatches(ctx, acmFake, arn, leaf.PEM, intermediate.PEM)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(matches).To(BeTrue())
+		Expect(acmFake.called("GetCertificate")).To(Equal(1))
+	})
+
+	It("is not set when disabled", func() {
+		r.ContentHashTags = false
+		arn, err := r.importToAcm(ctx, acmFake, secret, leaf.PEM, intermediate.PEM, []byte("key"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acmFake.tag(arn, contentHashTagKey)).To(BeEmpty())
+	})
+
+	Context("when reconciling an unexpired certificate", func() {
+		BeforeEach(func() {
+			r.ACM = acmFake
+			secret.Annotations = map[string]string{
+				"sync-to-acm":                 "true",
+				"cert-manager.io/common-name": "example.com",
+			}
+			secret.Type = corev1.SecretTypeTLS
+			secret.Data = map[string][]byte{
+				corev1.TLSCertKey:       append(append([]byte{}, leaf.PEM...), intermediate.PEM...),
+				corev1.TLSPrivateKeyKey: leaf.keyPEM(),
+			}
+			r.Client = fake.NewClientBuilder().WithObjects(secret).Build()
+		})
+
+		reconcileSecret := func() {
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		updateSecret := func(certPEM []byte, key *testCert) {
+			var stored corev1.Secret
+			Expect(r.Get(ctx, client.ObjectKeyFromObject(secret), &stored)).To(Succeed())
+			stored.Data[corev1.TLSCertKey] = certPEM
+			stored.Data[corev1.TLSPrivateKeyKey] = key.keyPEM()
+			Expect(r.Update(ctx, &stored)).To(Succeed())
+		}
+
+		It("imports it the first time", func() {
+			reconcileSecret()
+			Expect(acmFake.called("ImportCertificate")).To(Equal(1))
+			Expect(acmFake.certs).To(HaveLen(1))
+		})
+
+		It("skips the import when nothing changed", func() {
+			reconcileSecret()
+			reconcileSecret()
+			Expect(acmFake.called("ImportCertificate")).To(Equal(1))
+			Expect(acmFake.called("GetCertificate")).To(Equal(0))
+		})
+
+		It("re-imports a reissued leaf", func() {
+			reconcileSecret()
+			arn := acmFake.certs[0].Detail.CertificateArn
+			reissued := newTestCert("example.com", intermediate, testCertOptions{DNSNames: []string{"example.com"}})
+			updateSecret(append(append([]byte{}, reissued.PEM...), intermediate.PEM...), reissued)
+
+			reconcileSecret()
+			Expect(acmFake.called("ImportCertificate")).To(Equal(2))
+			Expect(acmFake.certs).To(HaveLen(1))
+			Expect(acmFake.certs[0].Detail.CertificateArn).To(Equal(arn))
+			Expect(acmFake.certs[0].Cert).To(Equal(string(reissued.PEM)))
+		})
+
+		It("imports a new certificate rather than overwrite one it didn't import", func() {
+			other := acmFake.add("example.com", &fakeCertificate{
+				Detail: types.CertificateDetail{Type: types.CertificateTypeImported, Serial: aws.String("01")},
+				Tags:   []types.Tag{{Key: aws.String(secretTagKey), Value: aws.String("prod/other-tls")}},
+			})
+
+			reconcileSecret()
+			Expect(acmFake.called("ImportCertificate")).To(Equal(1))
+			Expect(acmFake.certs).To(HaveLen(2))
+			Expect(aws.ToString(acmFake.certs[0].Detail.CertificateArn)).To(Equal(other))
+			Expect(acmFake.certs[0].Detail.Serial).To(Equal(aws.String("01")))
+		})
+
+		It("re-imports a changed chain by its hash tag", func() {
+			reconcileSecret()
+			updateSecret(leaf.PEM, leaf)
+
+			reconcileSecret()
+			Expect(acmFake.called("ImportCertificate")).To(Equal(2))
+			Expect(acmFake.certs[0].Chain).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,49 @@
+package controllers
+
+import "sync"
+
+// domainLocks serializes reconciles claiming the same domain, so two Secrets
+// for one domain don't race to import or re-import the same ACM certificate
+// when reconciles run concurrently. Locks for different domains are
+// independent.
+type domainLocks struct {
+	mu    sync.Mutex
+	locks map[string]*domainLock
+}
+
+type domainLock struct {
+	mu sync.Mutex
+	// waiters counts the holder and everyone waiting, so the lock can be
+	// dropped once nobody needs it.
+	waiters int
+}
+
+// lock blocks until domain is free and returns the function releasing it.
+// Domains are compared as domainMatches compares names, ignoring case and a
+// trailing dot.
+func (l *domainLocks) lock(domain string) (unlock func()) {
+	domain = normalizeDomain(domain)
+
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = map[string]*domainLock{}
+	}
+	dl, ok := l.locks[domain]
+	if !ok {
+		dl = &domainLock{}
+		l.locks[domain] = dl
+	}
+	dl.waiters++
+	l.mu.Unlock()
+
+	dl.mu.Lock()
+	return func() {
+		dl.mu.Unlock()
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		dl.waiters--
+		if dl.waiters == 0 {
+			delete(l.locks, domain)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("domainLocks", func() {
+	It("serializes holders of the same domain", func() {
+		var locks domainLocks
+
+		var inFlight, peak int32
+		var wg sync.WaitGroup
+		for _, domain := range []string{"example.com", "Example.com", "example.com.", "EXAMPLE.COM"} {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				defer locks.lock(domain)()
+
+				n := atomic.AddInt32(&inFlight, 1)
+				for {
+					p := atomic.LoadInt32(&peak)
+					if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				atomic.AddInt32(&inFlight, -1)
+			}()
+		}
+		wg.Wait()
+
+		Expect(peak).To(BeEquivalentTo(1))
+		Expect(locks.locks).To(BeEmpty())
+	})
+
+	It("doesn't block other domains", func() {
+		var locks domainLocks
+		unlock := locks.lock("example.com")
+		defer unlock()
+
+		done := make(chan struct{})
+		go func() {
+			locks.lock("example.org")()
+			close(done)
+		}()
+		Eventually(done).Should(BeClosed())
+	})
+
+	It("blocks a second holder until the first releases", func() {
+		var locks domainLocks
+		unlock := locks.lock("example.com")
+
+		acquired := make(chan struct{})
+		go func() {
+			locks.lock("example.com")()
+			close(acquired)
+		}()
+		Consistently(acquired, 50*time.Millisecond).ShouldNot(BeClosed())
+
+		unlock()
+		Eventually(acquired).Should(BeClosed())
+		Expect(locks.locks).To(BeEmpty())
+	})
+
+	It("is held while a deleted Secret's certificate is cleaned up", func() {
+		_, intermediate, leaf := newTestChain("example.com")
+		now := metav1.Now()
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         "prod",
+				Name:              "web-tls",
+				Annotations:       map[string]string{"sync-to-acm": "true", "cert-manager.io/common-name": "example.com"},
+				Finalizers:        []string{secretFinalizer},
+				DeletionTimestamp: &now,
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       append(append([]byte{}, leaf.PEM...), intermediate.PEM...),
+				corev1.TLSPrivateKeyKey: leaf.keyPEM(),
+			},
+		}
+		r := &SecretReconciler{
+			Client: fake.NewClientBuilder().WithObjects(secret).Build(),
+			Log:    logr.Discard(),
+			ACM:    newFakeACM(),
+		}
+		unlock := r.domainLocks.lock("example.com")
+
+		finalized := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+			Expect(err).NotTo(HaveOccurred())
+			close(finalized)
+		}()
+		Consistently(finalized, 50*time.Millisecond).ShouldNot(BeClosed())
+
+		unlock()
+		Eventually(finalized).Should(BeClosed())
+	})
+})
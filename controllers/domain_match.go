@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"crypto/x509"
+	"strings"
+)
+
+// domainMatches reports whether name, which may be a wildcard such as
+// *.example.com, covers domain. A wildcard covers exactly one label, so
+// *.example.com covers www.example.com but neither example.com nor
+// a.b.example.com. Case and a trailing dot are ignored.
+func domainMatches(name, domain string) bool {
+	name, domain = normalizeDomain(name), normalizeDomain(domain)
+	if name == domain {
+		return true
+	}
+	parent, ok := strings.CutPrefix(name, "*.")
+	if !ok {
+		return false
+	}
+	label, rest, ok := strings.Cut(domain, ".")
+	return ok && label != "" && label != "*" && rest == parent
+}
+
+// sameDomain reports whether a and b name the same domain, ignoring case and
+// a trailing dot.
+func sameDomain(a, b string) bool {
+	return normalizeDomain(a) == normalizeDomain(b)
+}
+
+func normalizeDomain(domain string) string {
+	return strings.ToLower(strings.TrimSuffix(domain, "."))
+}
+
+// leafCoversDomain reports whether the subject common name or one of the DNS
+// names of leaf covers domain.
+func leafCoversDomain(leaf *x509.Certificate, domain string) bool {
+	if leaf.Subject.CommonName != "" && domainMatches(leaf.Subject.CommonName, domain) {
+		return true
+	}
+	for _, name := range leaf.DNSNames {
+		if domainMatches(name, domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("domain matching", func() {
+	DescribeTable("domainMatches",
+		func(name, domain string, expected bool) {
+			Expect(domainMatches(name, domain)).To(Equal(expected))
+		},
+		Entry("exact", "example.com", "example.com", true),
+		Entry("case and trailing dot", "Example.COM.", "example.com", true),
+		Entry("wildcard one label up", "*.example.com", "foo.example.com", true),
+		Entry("wildcard against itself", "*.example.com", "*.example.com", true),
+		Entry("wildcard two labels up", "*.example.com", "a.b.example.com", false),
+		Entry("wildcard against its parent", "*.example.com", "example.com", false),
+		Entry("different domain", "example.com", "example.org", false),
+		Entry("suffix of another label", "*.example.com", "foo.badexample.com", false),
+	)
+
+	Context("when reconciling", func() {
+		var (
+			acmFake  *fakeACM
+			recorder *record.FakeRecorder
+		)
+
+		BeforeEach(func() {
+			acmFake = newFakeACM()
+			recorder = record.NewFakeRecorder(10)
+		})
+
+		reconcileAnnotated := func(domain string, leaf, intermediate *testCert) {
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "prod",
+					Name:      "web-tls",
+					Annotations: map[string]string{
+						"sync-to-acm":                 "true",
+						"cert-manager.io/common-name": domain,
+					},
+				},
+				Type: corev1.SecretTypeTLS,
+				Data: map[string][]byte{
+					corev1.TLSCertKey:       append(append([]byte{}, leaf.PEM...), intermediate.PEM...),
+					corev1.TLSPrivateKeyKey: leaf.keyPEM(),
+				},
+			}
+			r := &SecretReconciler{Client: fake.NewClientBuilder().WithObjects(secret).Build(), Log: logr.Discard(), ACM: acmFake, Recorder: recorder}
+			_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		It("imports when the annotation names the certificate", func() {
+			_, intermediate, leaf := newTestChain("example.com")
+			reconcileAnnotated("example.com", leaf, intermediate)
+			Expect(acmFake.called("ImportCertificate")).To(Equal(1))
+		})
+
+		It("imports when a wildcard in the certificate covers the annotation", func() {
+			root := newTestCert("Test Root", nil, testCertOptions{IsCA: true})
+			intermediate := newTestCert("Test Intermediate", root, testCertOptions{IsCA: true})
+			leaf := newTestCert("*.example.com", intermediate, testCertOptions{DNSNames: []string{"*.example.com"}})
+			reconcileAnnotated("foo.example.com", leaf, intermediate)
+			Expect(acmFake.called("ImportCertificate")).To(Equal(1))
+		})
+
+		Context("with a wildcard certificate in ACM", func() {
+			var wildcardArn string
+
+			BeforeEach(func() {
+				wildcardArn = acmFake.add("*.example.com", &fakeCertificate{Detail: types.CertificateDetail{
+					Type:                    types.CertificateTypeImported,
+					SubjectAlternativeNames: []string{"*.example.com"},
+					Serial:                  aws.String("01"),
+				}, Tags: []types.Tag{{Key: aws.String(secretTagKey), Value: aws.String("prod/web-tls")}}})
+			})
+
+			It("leaves it in place for a Secret naming one host", func() {
+				_, intermediate, leaf := newTestChain("app.example.com")
+				reconcileAnnotated("app.example.com", leaf, intermediate)
+				Expect(acmFake.called("ImportCertificate")).To(BeZero())
+				Expect(acmFake.certs).To(HaveLen(1))
+				Expect(acmFake.certs[0].Detail.SubjectAlternativeNames).To(Equal([]string{"*.example.com"}))
+			})
+
+			It("updates it for a Secret carrying the same wildcard", func() {
+				root := newTestCert("Test Root", nil, testCertOptions{IsCA: true})
+				intermediate := newTestCert("Test Intermediate", root, testCertOptions{IsCA: true})
+				leaf := newTestCert("*.example.com", intermediate, testCertOptions{DNSNames: []string{"*.example.com"}})
+				reconcileAnnotated("app.example.com", leaf, intermediate)
+				Expect(acmFake.called("ImportCertificate")).To(Equal(1))
+				Expect(acmFake.certs).To(HaveLen(1))
+				Expect(aws.ToString(acmFake.certs[0].Detail.CertificateArn)).To(Equal(wildcardArn))
+			})
+		})
+
+		It("skips with a warning when the annotation is stale", func() {
+			_, intermediate, leaf := newTestChain("example.com")
+			reconcileAnnotated("old.example.org", leaf, intermediate)
+			Expect(acmFake.called("ImportCertificate")).To(BeZero())
+			Expect(recorder.Events).To(Receive(And(
+				HavePrefix("Warning "+reasonDomainMismatch),
+				ContainSubstring("old.example.org"),
+			)))
+		})
+	})
+})
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// DomainResolver is the subset of net.Resolver used to validate domains.
+type DomainResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// DomainValidator guards against importing certificates for mistyped domains
+// by checking that the domain falls within an expected zone and/or resolves.
+type DomainValidator struct {
+	// Resolver, when set, must resolve the domain for it to be accepted.
+	Resolver DomainResolver
+	// Timeout bounds each lookup.
+	Timeout time.Duration
+	// Zones, when non-empty, lists the DNS zones the domain must belong to.
+	Zones []string
+	// CheckSANs extends Zones to every subject alternative name of the leaf
+	// certificate, so a certificate can't carry names outside the zones
+	// alongside an allowed domain.
+	CheckSANs bool
+}
+
+// NewDomainResolver returns a resolver that queries the DNS server at addr
+// (host:port), or the system resolver when addr is empty.
+func NewDomainResolver(addr string) DomainResolver {
+	if addr == "" {
+		return net.DefaultResolver
+	}
+
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// Validate returns an error if domain is outside the expected zones or does
+// not resolve. Wildcard domains are checked against their parent.
+func (v *DomainValidator) Validate(ctx context.Context, domain string) error {
+	host := strings.TrimSuffix(strings.TrimPrefix(domain, "*."), ".")
+
+	if len(v.Zones) > 0 && !inZones(host, v.Zones) {
+		return fmt.Errorf("domain %q is not within the expected zones %v", domain, v.Zones)
+	}
+
+	if v.Resolver == nil {
+		return nil
+	}
+
+	if v.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, v.Timeout)
+		defer cancel()
+	}
+
+	addrs, err := v.Resolver.LookupHost(ctx, host)
+	if err != nil {
+		return fmt.Errorf("domain %q does not resolve: %w", domain, err)
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("domain %q does not resolve to any address", domain)
+	}
+
+	return nil
+}
+
+// ValidateSANs returns an error if CheckSANs is set and leaf has a subject
+// alternative name outside Zones. IP address, email and URI SANs never belong
+// to a zone and are always rejected.
+func (v *DomainValidator) ValidateSANs(leaf *x509.Certificate) error {
+	if !v.CheckSANs || len(v.Zones) == 0 {
+		return nil
+	}
+
+	var disallowed []string
+	for _, name := range leaf.DNSNames {
+		if !inZones(strings.TrimSuffix(strings.TrimPrefix(name, "*."), "."), v.Zones) {
+			disallowed = append(disallowed, name)
+		}
+	}
+	for _, ip := range leaf.IPAddresses {
+		disallowed = append(disallowed, ip.String())
+	}
+	disallowed = append(disallowed, leaf.EmailAddresses...)
+	for _, uri := range leaf.URIs {
+		disallowed = append(disallowed, uri.String())
+	}
+
+	if len(disallowed) > 0 {
+		return fmt.Errorf("certificate names %v are not within the expected zones %v", disallowed, v.Zones)
+	}
+	return nil
+}
+
+// inZones reports whether host equals or is a subdomain of one of zones.
+func inZones(host string, zones []string) bool {
+	host = strings.ToLower(host)
+	for _, zone := range zones {
+		zone = strings.ToLower(strings.Trim(zone, "."))
+		if host == zone || strings.HasSuffix(host, "."+zone) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// fakeResolver resolves only the hosts it knows about.
+type fakeResolver struct {
+	hosts   map[string][]string
+	lookups []string
+}
+
+func (f *fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	f.lookups = append(f.lookups, host)
+	if addrs, ok := f.hosts[host]; ok {
+		return addrs, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+var _ = Describe("DomainValidator", func() {
+	var resolver *fakeResolver
+
+	BeforeEach(func() {
+		resolver = &fakeResolver{hosts: map[string][]string{
+			"example.com": {"93.184.216.34"},
+		}}
+	})
+
+	It("accepts a resolvable domain", func() {
+		v := &DomainValidator{Resolver: resolver, Timeout: time.Second}
+		Expect(v.Validate(context.Background(), "example.com")).To(Succeed())
+	})
+
+	It("rejects an unresolvable domain", func() {
+		v := &DomainValidator{Resolver: resolver, Timeout: time.Second}
+		Expect(v.Validate(context.Background(), "exmaple.com")).NotTo(Succeed())
+	})
+
+	It("resolves the parent of a wildcard domain", func() {
+		v := &DomainValidator{Resolver: resolver}
+		Expect(v.Validate(context.Background(), "*.example.com")).To(Succeed())
+		Expect(resolver.lookups).To(Equal([]string{"example.com"}))
+	})
+
+	It("rejects domains outside the expected zones without resolving", func() {
+		v := &DomainValidator{Resolver: resolver, Zones: []string{"example.org"}}
+		Expect(v.Validate(context.Background(), "example.com")).NotTo(Succeed())
+		Expect(resolver.lookups).To(BeEmpty())
+	})
+
+	It("accepts subdomains of an expected zone", func() {
+		v := &DomainValidator{Zones: []string{"example.com."}}
+		Expect(v.Validate(context.Background(), "api.Example.com")).To(Succeed())
+	})
+
+	Context("checking SANs", func() {
+		v := &DomainValidator{Zones: []string{"example.com"}, CheckSANs: true}
+
+		It("accepts a certificate whose names are all allowed", func() {
+			leaf := newTestCert("example.com", nil, testCertOptions{DNSNames: []string{"example.com", "*.api.example.com"}})
+			Expect(v.ValidateSANs(leaf.Cert)).To(Succeed())
+		})
+
+		It("rejects a certificate with a disallowed SAN", func() {
+			leaf := newTestCert("example.com", nil, testCertOptions{DNSNames: []string{"example.com", "login.example.net"}})
+			err := v.ValidateSANs(leaf.Cert)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("login.example.net"))
+		})
+
+		It("rejects IP address SANs", func() {
+			leaf := newTestCert("example.com", nil, testCertOptions{DNSNames: []string{"example.com"}})
+			leaf.Cert.IPAddresses = []net.IP{net.ParseIP("10.0.0.1")}
+			Expect(v.ValidateSANs(leaf.Cert)).NotTo(Succeed())
+		})
+
+		It("ignores SANs unless enabled", func() {
+			leaf := newTestCert("example.com", nil, testCertOptions{DNSNames: []string{"login.example.net"}})
+			Expect((&DomainValidator{Zones: []string{"example.com"}}).ValidateSANs(leaf.Cert)).To(Succeed())
+		})
+	})
+})
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+// dryRunACM passes ACM reads through and logs writes instead of making them,
+// so lookups and comparisons stay accurate while ACM is left untouched.
+type dryRunACM struct {
+	awsclient.ACMAPI
+	log logr.Logger
+}
+
+// NewDryRunACM wraps acmClient so that it logs to log instead of importing,
+// deleting or tagging certificates.
+func NewDryRunACM(acmClient awsclient.ACMAPI, log logr.Logger) awsclient.ACMAPI {
+	return &dryRunACM{ACMAPI: acmClient, log: log.WithValues("region", acmClient.Options().Region)}
+}
+
+func (d *dryRunACM) ImportCertificate(_ context.Context, params *acm.ImportCertificateInput, _ ...func(*acm.Options)) (*acm.ImportCertificateOutput, error) {
+	d.log.Info("Dry run: would import certificate", "certificateArn", aws.ToString(params.CertificateArn))
+	return &acm.ImportCertificateOutput{CertificateArn: params.CertificateArn}, nil
+}
+
+func (d *dryRunACM) DeleteCertificate(_ context.Context, params *acm.DeleteCertificateInput, _ ...func(*acm.Options)) (*acm.DeleteCertificateOutput, error) {
+	d.log.Info("Dry run: would delete certificate", "certificateArn", aws.ToString(params.CertificateArn))
+	return &acm.DeleteCertificateOutput{}, nil
+}
+
+func (d *dryRunACM) AddTagsToCertificate(_ context.Context, params *acm.AddTagsToCertificateInput, _ ...func(*acm.Options)) (*acm.AddTagsToCertificateOutput, error) {
+	d.log.Info("Dry run: would tag certificate", "certificateArn", aws.ToString(params.CertificateArn), "tags", len(params.Tags))
+	return &acm.AddTagsToCertificateOutput{}, nil
+}
+
+func (d *dryRunACM) RemoveTagsFromCertificate(_ context.Context, params *acm.RemoveTagsFromCertificateInput, _ ...func(*acm.Options)) (*acm.RemoveTagsFromCertificateOutput, error) {
+	d.log.Info("Dry run: would remove tags from certificate", "certificateArn", aws.ToString(params.CertificateArn), "tags", len(params.Tags))
+	return &acm.RemoveTagsFromCertificateOutput{}, nil
+}
+
+// dryRunImport logs and records the import or update of secret's
+// certificate that DryRun skips. certificateArn is empty for a new import.
+func (r *SecretReconciler) dryRunImport(log logr.Logger, acmClient awsclient.ACMAPI, secret *corev1.Secret, certificateArn string) {
+	region := acmClient.Options().Region
+	if certificateArn == "" {
+		log.Info("Dry run: would import certificate into ACM")
+		r.eventf(secret, corev1.EventTypeNormal, reasonDryRunImport, "Would import certificate into %s", region)
+		return
+	}
+	log.Info("Dry run: would update certificate in ACM", "certificateArn", certificateArn)
+	r.eventf(secret, corev1.EventTypeNormal, reasonDryRunImport, "Would update certificate %s in %s", certificateArn, region)
+}
@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DefaultKeyPassphraseField is the Secret field the passphrase of an
+// encrypted tls.key is read from.
+const DefaultKeyPassphraseField = "tls.key.passphrase"
+
+// maxPBKDF2Iterations bounds the work a Secret can make the controller do to
+// decrypt its key.
+const maxPBKDF2Iterations = 10_000_000
+
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAES128CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// errWrongPassphrase is returned when an encrypted key doesn't decrypt to a
+// valid private key.
+var errWrongPassphrase = errors.New("failed to decrypt private key: wrong passphrase or corrupt key")
+
+// encryptedPrivateKeyInfo is the PKCS#8 EncryptedPrivateKeyInfo structure.
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+// pbes2Params are the PBES2 parameters of RFC 8018.
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+// pbkdf2Params are the PBKDF2 parameters of RFC 8018.
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// isEncryptedKeyPEM reports whether keyPEM holds an encrypted PKCS#8 key.
+func isEncryptedKeyPEM(keyPEM []byte) bool {
+	rest := keyPEM
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return false
+		}
+		if block.Type == "ENCRYPTED PRIVATE KEY" {
+			return true
+		}
+	}
+}
+
+// keyPassphraseField returns the Secret field holding the key passphrase.
+func (r *SecretReconciler) keyPassphraseField() string {
+	if r.KeyPassphraseField != "" {
+		return r.KeyPassphraseField
+	}
+	return DefaultKeyPassphraseField
+}
+
+// decryptSecretKey decrypts keyPEM with the passphrase stored in secret.
+func (r *SecretReconciler) decryptSecretKey(secret *corev1.Secret, keyPEM []byte) ([]byte, error) {
+	passphrase, ok := secret.Data[r.keyPassphraseField()]
+	if !ok {
+		return nil, fmt.Errorf("private key is encrypted but the Secret has no %s field", r.keyPassphraseField())
+	}
+	return decryptPrivateKeyPEM(keyPEM, bytes.TrimRight(passphrase, "\r\n"))
+}
+
+// decryptPrivateKeyPEM decrypts the first encrypted PKCS#8 block in keyPEM and
+// returns it as an unencrypted PKCS#8 PEM block, which ACM accepts.
+func decryptPrivateKeyPEM(keyPEM, passphrase []byte) ([]byte, error) {
+	rest := keyPEM
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, fmt.Errorf("no encrypted private key found in PEM data")
+		}
+		if block.Type != "ENCRYPTED PRIVATE KEY" {
+			continue
+		}
+
+		der, err := decryptPKCS8(block.Bytes, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := x509.ParsePKCS8PrivateKey(der); err != nil {
+			return nil, errWrongPassphrase
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+	}
+}
+
+// decryptPKCS8 decrypts a DER EncryptedPrivateKeyInfo. Only PBES2 with PBKDF2
+// and AES-CBC is supported, which is what OpenSSL produces by default.
+func decryptPKCS8(der, passphrase []byte) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse encrypted private key: %w", err)
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("unsupported private key encryption %s, only PBES2 is supported", info.Algorithm.Algorithm)
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("failed to parse PBES2 parameters: %w", err)
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("unsupported key derivation function %s, only PBKDF2 is supported", params.KeyDerivationFunc.Algorithm)
+	}
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, fmt.Errorf("failed to parse PBKDF2 parameters: %w", err)
+	}
+	if kdf.IterationCount <= 0 || kdf.IterationCount > maxPBKDF2Iterations {
+		return nil, fmt.Errorf("unsupported PBKDF2 iteration count %d", kdf.IterationCount)
+	}
+
+	var prf func() hash.Hash
+	switch algorithm := kdf.PRF.Algorithm; {
+	case len(algorithm) == 0, algorithm.Equal(oidHMACWithSHA1):
+		prf = sha1.New
+	case algorithm.Equal(oidHMACWithSHA256):
+		prf = sha256.New
+	case algorithm.Equal(oidHMACWithSHA512):
+		prf = sha512.New
+	default:
+		return nil, fmt.Errorf("unsupported PBKDF2 pseudorandom function %s", algorithm)
+	}
+
+	var keyLength int
+	switch algorithm := params.EncryptionScheme.Algorithm; {
+	case algorithm.Equal(oidAES128CBC):
+		keyLength = 16
+	case algorithm.Equal(oidAES192CBC):
+		keyLength = 24
+	case algorithm.Equal(oidAES256CBC):
+		keyLength = 32
+	default:
+		return nil, fmt.Errorf("unsupported private key cipher %s, only AES-CBC is supported", algorithm)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, fmt.Errorf("failed to parse cipher parameters: %w", err)
+	}
+
+	block, err := aes.NewCipher(pbkdf2Key(prf, passphrase, kdf.Salt, kdf.IterationCount, keyLength))
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != block.BlockSize() || len(info.EncryptedData) == 0 || len(info.EncryptedData)%block.BlockSize() != 0 {
+		return nil, fmt.Errorf("malformed encrypted private key")
+	}
+	plain := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, info.EncryptedData)
+	return unpad(plain, block.BlockSize())
+}
+
+// unpad strips PKCS#7 padding. A bad padding almost always means a wrong
+// passphrase.
+func unpad(data []byte, blockSize int) ([]byte, error) {
+	n := int(data[len(data)-1])
+	if n == 0 || n > blockSize || n > len(data) {
+		return nil, errWrongPassphrase
+	}
+	for _, b := range data[len(data)-n:] {
+		if int(b) != n {
+			return nil, errWrongPassphrase
+		}
+	}
+	return data[:len(data)-n], nil
+}
+
+// pbkdf2Key derives a keyLength byte key from password as specified by
+// RFC 8018.
+func pbkdf2Key(prf func() hash.Hash, password, salt []byte, iterations, keyLength int) []byte {
+	mac := hmac.New(prf, password)
+	size := mac.Size()
+	var derived []byte
+	u := make([]byte, size)
+	for i := uint32(1); len(derived) < keyLength; i++ {
+		mac.Reset()
+		mac.Write(salt)
+		mac.Write(binary.BigEndian.AppendUint32(nil, i))
+		derived = mac.Sum(derived)
+		t := derived[len(derived)-size:]
+		copy(u, t)
+		for range iterations - 1 {
+			mac.Reset()
+			mac.Write(u)
+			u = mac.Sum(u[:0])
+			for j := range u {
+				t[j] ^= u[j]
+			}
+		}
+	}
+	return derived[:keyLength]
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// encryptKeyPEM encrypts the PKCS#8 key in keyPEM with PBES2, PBKDF2 with
+// HMAC-SHA256 and AES-256-CBC, like openssl pkcs8 -topk8 does by default.
+func encryptKeyPEM(keyPEM, passphrase []byte) []byte {
+	block, _ := pem.Decode(keyPEM)
+	Expect(block).NotTo(BeNil())
+
+	salt := make([]byte, 16)
+	iv := make([]byte, aes.BlockSize)
+	_, err := rand.Read(salt)
+	Expect(err).NotTo(HaveOccurred())
+	_, err = rand.Read(iv)
+	Expect(err).NotTo(HaveOccurred())
+
+	aesBlock, err := aes.NewCipher(pbkdf2Key(sha256.New, passphrase, salt, 2048, 32))
+	Expect(err).NotTo(HaveOccurred())
+	padding := aes.BlockSize - len(block.Bytes)%aes.BlockSize
+	plain := append(append([]byte{}, block.Bytes...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	encrypted := make([]byte, len(plain))
+	cipher.NewCBCEncrypter(aesBlock, iv).CryptBlocks(encrypted, plain)
+
+	marshal := func(v any) asn1.RawValue {
+		der, err := asn1.Marshal(v)
+		Expect(err).NotTo(HaveOccurred())
+		return asn1.RawValue{FullBytes: der}
+	}
+	der, err := asn1.Marshal(encryptedPrivateKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{
+			Algorithm: oidPBES2,
+			Parameters: marshal(pbes2Params{
+				KeyDerivationFunc: pkix.AlgorithmIdentifier{
+					Algorithm: oidPBKDF2,
+					Parameters: marshal(pbkdf2Params{
+						Salt:           salt,
+						IterationCount: 2048,
+						PRF:            pkix.AlgorithmIdentifier{Algorithm: oidHMACWithSHA256, Parameters: asn1.NullRawValue},
+					}),
+				},
+				EncryptionScheme: pkix.AlgorithmIdentifier{Algorithm: oidAES256CBC, Parameters: marshal(iv)},
+			}),
+		},
+		EncryptedData: encrypted,
+	})
+	Expect(err).NotTo(HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: der})
+}
+
+var _ = Describe("pbkdf2Key", func() {
+	It("matches the RFC 6070 test vectors", func() {
+		Expect(hex.EncodeToString(pbkdf2Key(sha1.New, []byte("password"), []byte("salt"), 2, 20))).To(Equal("ea6c014dc72d6f8ccd1ed92ace1d41f0d8de8957"))
+		Expect(hex.EncodeToString(pbkdf2Key(sha1.New, []byte("passwordPASSWORDpassword"), []byte("saltSALTsaltSALTsaltSALTsaltSALTsalt"), 4096, 25))).
+			To(Equal("3d2eec4fe41c849b80c8d83662c0e44a8b291a964cf2f07038"))
+	})
+})
+
+var _ = Describe("encrypted private keys", func() {
+	var cert *testCert
+
+	BeforeEach(func() {
+		cert = newTestCert("example.com", nil, testCertOptions{})
+	})
+
+	It("detects encrypted keys", func() {
+		Expect(isEncryptedKeyPEM(encryptKeyPEM(cert.keyPEM(), []byte("s3cret")))).To(BeTrue())
+		Expect(isEncryptedKeyPEM(cert.keyPEM())).To(BeFalse())
+	})
+
+	It("decrypts a key with the right passphrase", func() {
+		keyPEM, err := decryptPrivateKeyPEM(encryptKeyPEM(cert.keyPEM(), []byte("s3cret")), []byte("s3cret"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keyPEM).To(Equal(cert.keyPEM()))
+		Expect(checkKeyMatchesCertificate(keyPEM, cert.Cert)).To(Succeed())
+	})
+
+	It("rejects a wrong passphrase", func() {
+		_, err := decryptPrivateKeyPEM(encryptKeyPEM(cert.keyPEM(), []byte("s3cret")), []byte("guess"))
+		Expect(err).To(MatchError(errWrongPassphrase))
+	})
+
+	It("reads the passphrase from the configured Secret field", func() {
+		secret := &corev1.Secret{Data: map[string][]byte{"passphrase": []byte("s3cret\n")}}
+		r := &SecretReconciler{KeyPassphraseField: "passphrase"}
+		keyPEM, err := r.decryptSecretKey(secret, encryptKeyPEM(cert.keyPEM(), []byte("s3cret")))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(keyPEM).To(Equal(cert.keyPEM()))
+	})
+
+	It("fails clearly when the passphrase is missing", func() {
+		_, err := (&SecretReconciler{}).decryptSecretKey(&corev1.Secret{}, encryptKeyPEM(cert.keyPEM(), []byte("s3cret")))
+		Expect(err).To(MatchError(ContainSubstring("no " + DefaultKeyPassphraseField + " field")))
+	})
+})
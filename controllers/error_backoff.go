@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// errorBackoffBase is the requeue delay after a Secret's first failed
+// reconcile. It doubles with every further consecutive failure, up to
+// ErrorBackoffMax.
+const errorBackoffBase = 10 * time.Second
+
+// DefaultErrorBackoffMax is the default cap of the per-Secret back-off.
+const DefaultErrorBackoffMax = 5 * time.Minute
+
+// errorBackoff counts consecutive failed reconciles per Secret.
+type errorBackoff struct {
+	mu       sync.Mutex
+	failures map[types.NamespacedName]int
+}
+
+// failed records a failed reconcile of key and returns how long to wait
+// before trying it again.
+func (b *errorBackoff) failed(key types.NamespacedName, maxDelay time.Duration) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures == nil {
+		b.failures = map[types.NamespacedName]int{}
+	}
+
+	delay := errorBackoffBase
+	for range b.failures[key] {
+		if delay >= maxDelay {
+			break
+		}
+		delay *= 2
+	}
+	b.failures[key]++
+	return min(delay, maxDelay)
+}
+
+// reset forgets the failures recorded for key.
+func (b *errorBackoff) reset(key types.NamespacedName) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.failures, key)
+}
+
+// errorBackoffMax returns ErrorBackoffMax, or DefaultErrorBackoffMax when
+// unset.
+func (r *SecretReconciler) errorBackoffMax() time.Duration {
+	if r.ErrorBackoffMax > 0 {
+		return r.ErrorBackoffMax
+	}
+	return DefaultErrorBackoffMax
+}
+
+// rateLimiter returns the work queue rate limiter of the controller. Its
+// per-Secret delays follow the same doubling as errorBackoff, so the retry
+// announced by backOff is when the failed Secret is retried. The bucket keeps
+// the controller's default overall limit.
+func (r *SecretReconciler) rateLimiter() workqueue.TypedRateLimiter[reconcile.Request] {
+	return workqueue.NewTypedMaxOfRateLimiter(
+		workqueue.NewTypedItemExponentialFailureRateLimiter[reconcile.Request](errorBackoffBase, r.errorBackoffMax()),
+		&workqueue.TypedBucketRateLimiter[reconcile.Request]{Limiter: rate.NewLimiter(rate.Limit(10), 100)},
+	)
+}
+
+// backOff records the outcome of a reconcile of key. A failure is logged and
+// recorded as an event on secret, when it was read, announcing the retry after
+// the Secret's next back-off delay; the error is returned so the work queue
+// applies that delay and callers such as RunOnce see it. Any other outcome
+// resets the delay. Terminal errors aren't retried and reset it too.
+func (r *SecretReconciler) backOff(log logr.Logger, key types.NamespacedName, secret *corev1.Secret, result ctrl.Result, err error) (ctrl.Result, error) {
+	if err == nil || errors.Is(err, reconcile.TerminalError(nil)) {
+		r.errorBackoff.reset(key)
+		return result, err
+	}
+
+	delay := r.errorBackoff.failed(key, r.errorBackoffMax())
+	log.Error(err, "Reconcile failed; backing off", "retryAfter", delay)
+	if secret.Name != "" {
+		r.eventf(secret, corev1.EventTypeWarning, reasonBackingOff, "Sync failed, retrying at %s: %v", time.Now().Add(delay).UTC().Format(time.RFC3339), err)
+	}
+	// The work queue ignores RequeueAfter alongside an error
+	return ctrl.Result{}, err
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("--error-backoff-max", func() {
+	var (
+		ctx      context.Context
+		acmFake  *fakeACM
+		recorder *record.FakeRecorder
+		r        *SecretReconciler
+		req      reconcile.Request
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		acmFake = newFakeACM()
+		recorder = record.NewFakeRecorder(20)
+		_, intermediate, leaf := newTestChain("example.com")
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "prod",
+				Name:      "web-tls",
+				Annotations: map[string]string{
+					"sync-to-acm":                 "true",
+					"cert-manager.io/common-name": "example.com",
+				},
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       append(append([]byte{}, leaf.PEM...), intermediate.PEM...),
+				corev1.TLSPrivateKeyKey: leaf.keyPEM(),
+			},
+		}
+		r = &SecretReconciler{
+			Client:          fake.NewClientBuilder().WithObjects(secret).Build(),
+			Log:             logr.Discard(),
+			ACM:             acmFake,
+			Recorder:        recorder,
+			ErrorBackoffMax: time.Minute,
+		}
+		req = reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)}
+	})
+
+	It("backs off consecutive failures with increasing delays up to the cap", func() {
+		acmFake.importErrs = []error{errors.New("quota exceeded"), errors.New("quota exceeded"), errors.New("quota exceeded"), errors.New("quota exceeded")}
+
+		for range 4 {
+			_, err := r.Reconcile(ctx, req)
+			Expect(err).To(MatchError(ContainSubstring("quota exceeded")))
+		}
+		Expect(r.errorBackoff.failures[req.NamespacedName]).To(Equal(4))
+		Expect(recorder.Events).To(Receive(ContainSubstring("Warning " + reasonImportFailed)))
+		Expect(recorder.Events).To(Receive(And(HavePrefix("Warning "+reasonBackingOff), ContainSubstring("retrying at"), ContainSubstring("quota exceeded"))))
+
+		limiter := r.rateLimiter()
+		var delays []time.Duration
+		for range 4 {
+			delays = append(delays, limiter.When(req))
+		}
+		Expect(delays).To(Equal([]time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute}))
+	})
+
+	It("resets the delay after a successful reconcile", func() {
+		acmFake.importErrs = []error{errors.New("quota exceeded"), errors.New("quota exceeded")}
+		for range 2 {
+			_, err := r.Reconcile(ctx, req)
+			Expect(err).To(HaveOccurred())
+		}
+
+		result, err := r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(BeNumerically(">", time.Minute), "a successful sync requeues at its usual interval")
+		Expect(r.errorBackoff.failures).NotTo(HaveKey(req.NamespacedName))
+	})
+
+	It("passes terminal errors through", func() {
+		key := types.NamespacedName{Namespace: "prod", Name: "web-tls"}
+		terminal := reconcile.TerminalError(errors.New("rejected"))
+		_, err := r.backOff(logr.Discard(), key, &corev1.Secret{}, ctrl.Result{}, terminal)
+		Expect(err).To(MatchError(terminal))
+	})
+
+	It("defaults the cap", func() {
+		r.ErrorBackoffMax = 0
+		Expect(r.errorBackoffMax()).To(Equal(DefaultErrorBackoffMax))
+	})
+})
@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Reasons of the events recorded on Secrets.
+const (
+	reasonImported             = "ImportedToACM"
+	reasonRenewed              = "RenewedInACM"
+	reasonSkippedValid         = "SkippedValid"
+	reasonImportFailed         = "ImportFailed"
+	reasonKeyMismatch          = "KeyMismatch"
+	reasonExpired              = "CertificateExpired"
+	reasonKeyDecryptFailed     = "KeyDecryptFailed"
+	reasonDryRunImport         = "DryRunImport"
+	reasonBackingOff           = "BackingOff"
+	reasonSyncPaused           = "SyncPaused"
+	reasonDomainMismatch       = "DomainMismatch"
+	reasonUnsupportedKey       = "UnsupportedKey"
+	reasonUnownedCertificate   = "UnownedCertificate"
+	reasonCredentialsRejected  = "CredentialsRejected"
+	reasonIntermediateExpiring = "IntermediateExpiring"
+)
+
+// eventf records an event on secret when a Recorder is configured, so
+// kubectl describe shows its sync history.
+func (r *SecretReconciler) eventf(secret *corev1.Secret, eventType, reason, messageFmt string, args ...interface{}) {
+	if r.Recorder == nil {
+		return
+	}
+	r.Recorder.Eventf(secret, eventType, reason, messageFmt, args...)
+}
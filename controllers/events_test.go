@@ -0,0 +1,174 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Secret events", func() {
+	var (
+		ctx      context.Context
+		acmFake  *fakeACM
+		recorder *record.FakeRecorder
+		r        *SecretReconciler
+		req      reconcile.Request
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		acmFake = newFakeACM()
+		acmFake.region = "us-east-1"
+		recorder = record.NewFakeRecorder(10)
+		_, intermediate, leaf := newTestChain("example.com")
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "prod",
+				Name:      "web-tls",
+				Annotations: map[string]string{
+					"sync-to-acm":                 "true",
+					"cert-manager.io/common-name": "example.com",
+				},
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       append(append([]byte{}, leaf.PEM...), intermediate.PEM...),
+				corev1.TLSPrivateKeyKey: leaf.keyPEM(),
+			},
+		}
+		r = &SecretReconciler{
+			Client:   fake.NewClientBuilder().WithObjects(secret).Build(),
+			Log:      logr.Discard(),
+			ACM:      acmFake,
+			Recorder: recorder,
+		}
+		req = reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)}
+	})
+
+	reasons := func() []string {
+		var got []string
+		for len(recorder.Events) > 0 {
+			got = append(got, <-recorder.Events)
+		}
+		return got
+	}
+
+	It("records the import and then the skip", func() {
+		_, err := r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		events := reasons()
+		Expect(events).To(HaveLen(1))
+		Expect(events[0]).To(HavePrefix("Normal " + reasonImported))
+		Expect(events[0]).To(ContainSubstring("arn:aws:acm:us-east-1:"))
+
+		_, err = r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reasons()).To(ConsistOf(HavePrefix("Normal " + reasonSkippedValid)))
+	})
+
+	It("records a failed import as a warning", func() {
+		acmFake.importErrs = []error{errors.New("access denied")}
+		_, err := r.Reconcile(ctx, req)
+		Expect(err).To(HaveOccurred())
+		Expect(reasons()).To(ConsistOf(
+			And(HavePrefix("Warning "+reasonImportFailed), ContainSubstring("access denied")),
+			HavePrefix("Warning "+reasonBackingOff),
+		))
+	})
+
+	It("warns and skips the import when the certificate has expired", func() {
+		leaf := newTestCert("example.com", nil, testCertOptions{DNSNames: []string{"example.com"}, NotBefore: time.Now().Add(-90 * 24 * time.Hour), NotAfter: time.Now().Add(-time.Hour)})
+		var secret corev1.Secret
+		Expect(r.Get(ctx, req.NamespacedName, &secret)).To(Succeed())
+		secret.Data[corev1.TLSCertKey] = leaf.PEM
+		secret.Data[corev1.TLSPrivateKeyKey] = leaf.keyPEM()
+		Expect(r.Update(ctx, &secret)).To(Succeed())
+
+		result, err := r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(expiredRecheckInterval))
+		Expect(reasons()).To(ConsistOf(HavePrefix("Warning " + reasonExpired)))
+		Expect(acmFake.called("ImportCertificate")).To(Equal(0))
+	})
+
+	It("imports a certificate whose key is encrypted", func() {
+		var secret corev1.Secret
+		Expect(r.Get(ctx, req.NamespacedName, &secret)).To(Succeed())
+		secret.Data[corev1.TLSPrivateKeyKey] = encryptKeyPEM(secret.Data[corev1.TLSPrivateKeyKey], []byte("s3cret"))
+		secret.Data[DefaultKeyPassphraseField] = []byte("s3cret")
+		Expect(r.Update(ctx, &secret)).To(Succeed())
+
+		_, err := r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reasons()).To(ConsistOf(HavePrefix("Normal " + reasonImported)))
+	})
+
+	It("warns and skips the import when the key can't be decrypted", func() {
+		var secret corev1.Secret
+		Expect(r.Get(ctx, req.NamespacedName, &secret)).To(Succeed())
+		secret.Data[corev1.TLSPrivateKeyKey] = encryptKeyPEM(secret.Data[corev1.TLSPrivateKeyKey], []byte("s3cret"))
+		secret.Data[DefaultKeyPassphraseField] = []byte("guess")
+		Expect(r.Update(ctx, &secret)).To(Succeed())
+
+		_, err := r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reasons()).To(ConsistOf(And(HavePrefix("Warning "+reasonKeyDecryptFailed), ContainSubstring("wrong passphrase"))))
+		Expect(acmFake.called("ImportCertificate")).To(Equal(0))
+	})
+
+	It("warns and skips the import when ACM doesn't accept the key", func() {
+		weak, err := rsa.GenerateKey(rand.Reader, 1024)
+		Expect(err).NotTo(HaveOccurred())
+		var secret corev1.Secret
+		Expect(r.Get(ctx, req.NamespacedName, &secret)).To(Succeed())
+		secret.Data[corev1.TLSPrivateKeyKey] = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(weak)})
+		Expect(r.Update(ctx, &secret)).To(Succeed())
+
+		_, err = r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reasons()).To(ConsistOf(And(HavePrefix("Warning "+reasonUnsupportedKey), ContainSubstring("1024 bits"))))
+		Expect(acmFake.called("ImportCertificate")).To(Equal(0))
+	})
+
+	It("warns and skips the import when the key doesn't match the certificate", func() {
+		var secret corev1.Secret
+		Expect(r.Get(ctx, req.NamespacedName, &secret)).To(Succeed())
+		secret.Data[corev1.TLSPrivateKeyKey] = newTestCert("other.example.com", nil, testCertOptions{}).keyPEM()
+		Expect(r.Update(ctx, &secret)).To(Succeed())
+
+		_, err := r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reasons()).To(ConsistOf(And(HavePrefix("Warning "+reasonKeyMismatch), ContainSubstring("doesn't match"))))
+		Expect(acmFake.called("ImportCertificate")).To(Equal(0))
+	})
+})
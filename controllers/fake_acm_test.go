@@ -0,0 +1,321 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+)
+
+// fakeCertificate is a certificate held by fakeACM.
+type fakeCertificate struct {
+	Detail types.CertificateDetail
+	Cert   string
+	Chain  string
+	Tags   []types.Tag
+	// NilDetail makes DescribeCertificate return no certificate detail.
+	NilDetail bool
+}
+
+// fakeACM is an in-memory ACM that records every call made to it.
+type fakeACM struct {
+	mu     sync.Mutex
+	region string
+	certs  []*fakeCertificate
+	calls  []string
+	nextID int
+	// describeErrs are returned, in order, by the next DescribeCertificate
+	// calls; nil entries let a call through.
+	describeErrs []error
+	// importErrs does the same for ImportCertificate.
+	importErrs []error
+	// listErrs does the same for ListCertificates.
+	listErrs []error
+	// tagErrs does the same for ListTagsForCertificate.
+	tagErrs []error
+	// listInputs holds the input of every ListCertificates call.
+	listInputs []*acm.ListCertificatesInput
+}
+
+func newFakeACM() *fakeACM {
+	return &fakeACM{region: "us-east-1"}
+}
+
+// add stores a certificate for domain and returns its ARN.
+func (f *fakeACM) add(domain string, cert *fakeCertificate) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	arn := fmt.Sprintf("arn:aws:acm:%s:123456789012:certificate/%d", f.region, f.nextID)
+	cert.Detail.CertificateArn = aws.String(arn)
+	if cert.Detail.DomainName == nil {
+		cert.Detail.DomainName = aws.String(domain)
+	}
+	f.certs = append(f.certs, cert)
+	return arn
+}
+
+func (f *fakeACM) get(arn *string) (*fakeCertificate, error) {
+	for _, c := range f.certs {
+		if aws.ToString(c.Detail.CertificateArn) == aws.ToString(arn) {
+			return c, nil
+		}
+	}
+	return nil, &types.ResourceNotFoundException{Message: aws.String("certificate not found")}
+}
+
+// called returns how many times the named operation was invoked.
+func (f *fakeACM) called(op string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, c := range f.calls {
+		if c == op {
+			n++
+		}
+	}
+	return n
+}
+
+func (f *fakeACM) record(op string) {
+	f.calls = append(f.calls, op)
+}
+
+func (f *fakeACM) ListCertificates(_ context.Context, params *acm.ListCertificatesInput, _ ...func(*acm.Options)) (*acm.ListCertificatesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("ListCertificates")
+	f.listInputs = append(f.listInputs, params)
+	if len(f.listErrs) > 0 {
+		err := f.listErrs[0]
+		f.listErrs = f.listErrs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	out := &acm.ListCertificatesOutput{}
+	for _, c := range f.certs {
+		out.CertificateSummaryList = append(out.CertificateSummaryList, types.CertificateSummary{
+			CertificateArn:                  c.Detail.CertificateArn,
+			DomainName:                      c.Detail.DomainName,
+			SubjectAlternativeNameSummaries: c.Detail.SubjectAlternativeNames,
+			Type:                            c.Detail.Type,
+		})
+	}
+	return out, nil
+}
+
+func (f *fakeACM) DescribeCertificate(_ context.Context, params *acm.DescribeCertificateInput, _ ...func(*acm.Options)) (*acm.DescribeCertificateOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("DescribeCertificate")
+	if len(f.describeErrs) > 0 {
+		err := f.describeErrs[0]
+		f.describeErrs = f.describeErrs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	c, err := f.get(params.CertificateArn)
+	if err != nil {
+		return nil, err
+	}
+	if c.NilDetail {
+		return &acm.DescribeCertificateOutput{}, nil
+	}
+	detail := c.Detail
+	return &acm.DescribeCertificateOutput{Certificate: &detail}, nil
+}
+
+func (f *fakeACM) GetCertificate(_ context.Context, params *acm.GetCertificateInput, _ ...func(*acm.Options)) (*acm.GetCertificateOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("GetCertificate")
+	c, err := f.get(params.CertificateArn)
+	if err != nil {
+		return nil, err
+	}
+	return &acm.GetCertificateOutput{Certificate: aws.String(c.Cert), CertificateChain: aws.String(c.Chain)}, nil
+}
+
+func (f *fakeACM) ImportCertificate(_ context.Context, params *acm.ImportCertificateInput, _ ...func(*acm.Options)) (*acm.ImportCertificateOutput, error) {
+	f.mu.Lock()
+	f.record("ImportCertificate")
+	if len(f.importErrs) > 0 {
+		err := f.importErrs[0]
+		f.importErrs = f.importErrs[1:]
+		if err != nil {
+			f.mu.Unlock()
+			return nil, err
+		}
+	}
+	if params.CertificateArn != nil {
+		defer f.mu.Unlock()
+		c, err := f.get(params.CertificateArn)
+		if err != nil {
+			return nil, err
+		}
+		c.Cert, c.Chain = string(params.Certificate), string(params.CertificateChain)
+		c.Detail = detailFromPEM(params.Certificate, c.Detail.CertificateArn)
+		return &acm.ImportCertificateOutput{CertificateArn: params.CertificateArn}, nil
+	}
+	f.mu.Unlock()
+
+	detail := detailFromPEM(params.Certificate, nil)
+	arn := f.add("", &fakeCertificate{
+		Detail: detail,
+		Cert:   string(params.Certificate),
+		Chain:  string(params.CertificateChain),
+		Tags:   params.Tags,
+	})
+	return &acm.ImportCertificateOutput{CertificateArn: aws.String(arn)}, nil
+}
+
+func (f *fakeACM) DeleteCertificate(_ context.Context, params *acm.DeleteCertificateInput, _ ...func(*acm.Options)) (*acm.DeleteCertificateOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("DeleteCertificate")
+	for i, c := range f.certs {
+		if aws.ToString(c.Detail.CertificateArn) == aws.ToString(params.CertificateArn) {
+			f.certs = append(f.certs[:i], f.certs[i+1:]...)
+			return &acm.DeleteCertificateOutput{}, nil
+		}
+	}
+	return nil, &types.ResourceNotFoundException{Message: aws.String("certificate not found")}
+}
+
+func (f *fakeACM) ListTagsForCertificate(_ context.Context, params *acm.ListTagsForCertificateInput, _ ...func(*acm.Options)) (*acm.ListTagsForCertificateOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("ListTagsForCertificate")
+	if len(f.tagErrs) > 0 {
+		err := f.tagErrs[0]
+		f.tagErrs = f.tagErrs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	c, err := f.get(params.CertificateArn)
+	if err != nil {
+		return nil, err
+	}
+	return &acm.ListTagsForCertificateOutput{Tags: c.Tags}, nil
+}
+
+func (f *fakeACM) AddTagsToCertificate(_ context.Context, params *acm.AddTagsToCertificateInput, _ ...func(*acm.Options)) (*acm.AddTagsToCertificateOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("AddTagsToCertificate")
+	c, err := f.get(params.CertificateArn)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range params.Tags {
+		replaced := false
+		for i := range c.Tags {
+			if aws.ToString(c.Tags[i].Key) == aws.ToString(tag.Key) {
+				c.Tags[i].Value = tag.Value
+				replaced = true
+			}
+		}
+		if !replaced {
+			c.Tags = append(c.Tags, tag)
+		}
+	}
+	return &acm.AddTagsToCertificateOutput{}, nil
+}
+
+func (f *fakeACM) RemoveTagsFromCertificate(_ context.Context, params *acm.RemoveTagsFromCertificateInput, _ ...func(*acm.Options)) (*acm.RemoveTagsFromCertificateOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.record("RemoveTagsFromCertificate")
+	c, err := f.get(params.CertificateArn)
+	if err != nil {
+		return nil, err
+	}
+	kept := c.Tags[:0]
+	for _, tag := range c.Tags {
+		removed := false
+		for _, remove := range params.Tags {
+			if aws.ToString(tag.Key) == aws.ToString(remove.Key) {
+				removed = true
+			}
+		}
+		if !removed {
+			kept = append(kept, tag)
+		}
+	}
+	c.Tags = kept
+	return &acm.RemoveTagsFromCertificateOutput{}, nil
+}
+
+// tag returns the value of the tag key on the certificate arn.
+func (f *fakeACM) tag(arn, key string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, err := f.get(aws.String(arn))
+	if err != nil {
+		return ""
+	}
+	for _, t := range c.Tags {
+		if aws.ToString(t.Key) == key {
+			return aws.ToString(t.Value)
+		}
+	}
+	return ""
+}
+
+// detailFromPEM describes an imported certificate the way ACM would.
+func detailFromPEM(certPEM []byte, arn *string) types.CertificateDetail {
+	detail := types.CertificateDetail{CertificateArn: arn, Type: types.CertificateTypeImported}
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return detail
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return detail
+	}
+	detail.DomainName = aws.String(cert.Subject.CommonName)
+	detail.SubjectAlternativeNames = cert.DNSNames
+	detail.NotBefore = aws.Time(cert.NotBefore)
+	detail.NotAfter = aws.Time(cert.NotAfter)
+	detail.Serial = aws.String(acmSerial(cert))
+	return detail
+}
+
+func (f *fakeACM) Options() acm.Options {
+	return acm.Options{Region: f.region}
+}
+
+// acmSerial formats the serial number of cert the way ACM reports it.
+func acmSerial(cert *x509.Certificate) string {
+	serial := cert.SerialNumber.Bytes()
+	parts := make([]string, len(serial))
+	for i, b := range serial {
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(parts, ":")
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+const (
+	// secretFinalizer lets the controller delete the imported ACM certificate
+	// before a synced Secret goes away. It is only added when CleanupOnDelete
+	// is enabled.
+	secretFinalizer = "cert-sync.denyshubh.github.io/finalizer"
+
+	// deleteProtectionAnnotation, when "true", keeps the ACM certificate (left
+	// orphaned on purpose) when the Secret is deleted.
+	deleteProtectionAnnotation = "cert-sync.denyshubh.github.io/delete-protection"
+
+	// secretTagKey is the ACM tag identifying the Secret a certificate was
+	// imported from, as "<namespace>/<name>".
+	secretTagKey = "kubernetes-secrets"
+)
+
+// ensureFinalizer adds secretFinalizer to secret when cleanup is enabled,
+// outside of a dry run.
+func (r *SecretReconciler) ensureFinalizer(ctx context.Context, secret *corev1.Secret) error {
+	if !r.CleanupOnDelete || r.DryRun || !controllerutil.AddFinalizer(secret, secretFinalizer) {
+		return nil
+	}
+	return r.Update(ctx, secret)
+}
+
+// finalizeSecret deletes the ACM certificates imported from secret in every
+// region, unless it is delete-protected, and then releases the finalizer.
+// With InUseWait it reports waiting instead, keeping the finalizer, while any
+// of the certificates is still in use.
+func (r *SecretReconciler) finalizeSecret(ctx context.Context, acmClients []awsclient.ACMAPI, secret *corev1.Secret) (waiting bool, err error) {
+	log := r.Log.WithValues("secret", client.ObjectKeyFromObject(secret))
+
+	switch domainName, _ := r.secretDomain(secret); {
+	case secret.Annotations[deleteProtectionAnnotation] == "true":
+		log.Info("Secret is delete-protected; leaving ACM certificate in place")
+	case r.syncPaused(log, secret, "cleanup on delete"):
+		// A paused Secret keeps its certificate like a delete-protected one
+	case domainName != "":
+		for _, acmClient := range acmClients {
+			inUse, err := r.deleteFromAcm(ctx, acmClient, secret, domainName)
+			if err != nil {
+				return false, err
+			}
+			waiting = waiting || inUse
+			if !inUse {
+				key := ARNKey{Region: acmClient.Options().Region, Secret: client.ObjectKeyFromObject(secret)}
+				if err := r.arnStore().Delete(ctx, key); err != nil {
+					return false, err
+				}
+			}
+		}
+	}
+
+	if waiting && r.OnInUse == InUseWait {
+		log.Info("Waiting for ACM certificate to be detached before releasing Secret", "recheckAfter", inUseRecheckInterval)
+		return true, nil
+	}
+
+	controllerutil.RemoveFinalizer(secret, secretFinalizer)
+	return false, r.Update(ctx, secret)
+}
+
+// deleteFromAcm deletes the certificate for domainName if it was imported from
+// secret and is not attached to any AWS resource, reporting whether it was
+// kept because it is in use.
+func (r *SecretReconciler) deleteFromAcm(ctx context.Context, acmClient awsclient.ACMAPI, secret *corev1.Secret, domainName string) (bool, error) {
+	log := r.Log.WithValues("secret", client.ObjectKeyFromObject(secret))
+
+	certificate, err := r.findSecretByDomain(ctx, acmClient, domainName)
+	if err != nil || certificate == nil {
+		return false, err
+	}
+	certificateArn := aws.ToString(certificate.CertificateArn)
+
+	owned, err := ownedBySecret(ctx, acmClient, certificate.CertificateArn, secret)
+	if err != nil {
+		return false, err
+	}
+	if !owned {
+		log.Info("ACM certificate was not imported from this Secret; leaving it in place", "certificateArn", certificateArn)
+		return false, nil
+	}
+	if len(certificate.InUseBy) > 0 {
+		log.Info("ACM certificate is still in use; leaving it in place", "certificateArn", certificateArn, "inUseBy", certificate.InUseBy)
+		return true, nil
+	}
+
+	_, err = acmClient.DeleteCertificate(ctx, &acm.DeleteCertificateInput{CertificateArn: certificate.CertificateArn})
+	r.record(AuditEntry{
+		Action:         AuditActionDelete,
+		Secret:         client.ObjectKeyFromObject(secret).String(),
+		Domain:         domainName,
+		CertificateArn: certificateArn,
+		Region:         acmClient.Options().Region,
+	}, err)
+	if err != nil {
+		return false, err
+	}
+
+	log.Info("Deleted ACM certificate for deleted Secret", "certificateArn", certificateArn)
+	return false, nil
+}
+
+// ownedBySecret reports whether the certificate carries the identity tag of secret.
+func ownedBySecret(ctx context.Context, acmClient awsclient.ACMAPI, certificateArn *string, secret *corev1.Secret) (bool, error) {
+	output, err := acmClient.ListTagsForCertificate(ctx, &acm.ListTagsForCertificateInput{CertificateArn: certificateArn})
+	if err != nil {
+		return false, err
+	}
+
+	identity := secret.Namespace + "/" + secret.Name
+	for _, tag := range output.Tags {
+		if aws.ToString(tag.Key) == secretTagKey && aws.ToString(tag.Value) == identity {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+var _ = Describe("Secret deletion cleanup", func() {
+	var (
+		ctx     context.Context
+		acmFake *fakeACM
+		secret  *corev1.Secret
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		acmFake = newFakeACM()
+		acmFake.add("example.com", &fakeCertificate{
+			Detail: types.CertificateDetail{SubjectAlternativeNames: []string{"example.com"}},
+			Tags:   []types.Tag{{Key: aws.String(secretTagKey), Value: aws.String("prod/web-tls")}},
+		})
+
+		now := metav1.Now()
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "web-tls",
+				Namespace:         "prod",
+				Finalizers:        []string{secretFinalizer},
+				DeletionTimestamp: &now,
+				Annotations: map[string]string{
+					"sync-to-acm":                 "true",
+					"cert-manager.io/common-name": "example.com",
+				},
+			},
+			Type: corev1.SecretTypeTLS,
+		}
+	})
+
+	finalize := func() {
+		k8s := fake.NewClientBuilder().WithObjects(secret).Build()
+		r := &SecretReconciler{Client: k8s, Log: logr.Discard()}
+
+		waiting, err := r.finalizeSecret(ctx, []awsclient.ACMAPI{acmFake}, secret)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(waiting).To(BeFalse())
+		err = k8s.Get(ctx, client.ObjectKeyFromObject(secret), &corev1.Secret{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue(), "finalizer should be released")
+	}
+
+	It("deletes the ACM certificate of an unprotected Secret", func() {
+		finalize()
+		Expect(acmFake.called("DeleteCertificate")).To(Equal(1))
+		Expect(acmFake.certs).To(BeEmpty())
+	})
+
+	It("keeps the ACM certificate of a delete-protected Secret", func() {
+		secret.Annotations[deleteProtectionAnnotation] = "true"
+		finalize()
+		Expect(acmFake.called("DeleteCertificate")).To(Equal(0))
+		Expect(acmFake.certs).To(HaveLen(1))
+	})
+
+	It("keeps certificates imported from another Secret", func() {
+		acmFake.certs[0].Tags[0].Value = aws.String("prod/other-tls")
+		finalize()
+		Expect(acmFake.called("DeleteCertificate")).To(Equal(0))
+	})
+
+	It("keeps certificates that are still in use", func() {
+		acmFake.certs[0].Detail.InUseBy = []string{"arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/web"}
+		finalize()
+		Expect(acmFake.called("DeleteCertificate")).To(Equal(0))
+	})
+
+	Context("when waiting for in-use certificates", func() {
+		var (
+			k8s client.Client
+			r   *SecretReconciler
+		)
+
+		BeforeEach(func() {
+			acmFake.certs[0].Detail.InUseBy = []string{"arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/web"}
+			k8s = fake.NewClientBuilder().WithObjects(secret).Build()
+			r = &SecretReconciler{Client: k8s, Log: logr.Discard(), OnInUse: InUseWait}
+		})
+
+		It("keeps the finalizer while the certificate is in use", func() {
+			waiting, err := r.finalizeSecret(ctx, []awsclient.ACMAPI{acmFake}, secret)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(waiting).To(BeTrue())
+			Expect(acmFake.called("DeleteCertificate")).To(Equal(0))
+
+			var stored corev1.Secret
+			Expect(k8s.Get(ctx, client.ObjectKeyFromObject(secret), &stored)).To(Succeed())
+			Expect(stored.Finalizers).To(ContainElement(secretFinalizer))
+		})
+
+		It("deletes the certificate once it is detached", func() {
+			waiting, err := r.finalizeSecret(ctx, []awsclient.ACMAPI{acmFake}, secret)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(waiting).To(BeTrue())
+
+			acmFake.certs[0].Detail.InUseBy = nil
+			waiting, err = r.finalizeSecret(ctx, []awsclient.ACMAPI{acmFake}, secret)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(waiting).To(BeFalse())
+			Expect(acmFake.certs).To(BeEmpty())
+			err = k8s.Get(ctx, client.ObjectKeyFromObject(secret), &corev1.Secret{})
+			Expect(apierrors.IsNotFound(err)).To(BeTrue(), "finalizer should be released")
+		})
+
+		It("requeues the deleted Secret", func() {
+			r.NewACMClient = func(context.Context, awsclient.ClientKey) (awsclient.ACMAPI, error) { return acmFake, nil }
+
+			result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(inUseRecheckInterval))
+		})
+	})
+})
@@ -0,0 +1,179 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("findSecretByDomain", func() {
+	var (
+		ctx     context.Context
+		acmFake *fakeACM
+		r       *SecretReconciler
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		acmFake = newFakeACM()
+		r = &SecretReconciler{Log: logr.Discard()}
+	})
+
+	It("skips summaries whose detail is nil and keeps scanning", func() {
+		acmFake.add("example.com", &fakeCertificate{NilDetail: true})
+		arn := acmFake.add("example.com", &fakeCertificate{Detail: types.CertificateDetail{
+			SubjectAlternativeNames: []string{"example.com"},
+		}})
+
+		certificate, err := r.findSecretByDomain(ctx, acmFake, "example.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(certificate).NotTo(BeNil())
+		Expect(aws.ToString(certificate.CertificateArn)).To(Equal(arn))
+		Expect(acmFake.called("DescribeCertificate")).To(Equal(2))
+	})
+
+	It("matches a wildcard certificate one label up", func() {
+		arn := acmFake.add("*.example.com", &fakeCertificate{Detail: types.CertificateDetail{
+			SubjectAlternativeNames: []string{"*.example.com"},
+		}})
+
+		certificate, err := r.findSecretByDomain(ctx, acmFake, "app.example.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(certificate).NotTo(BeNil())
+		Expect(aws.ToString(certificate.CertificateArn)).To(Equal(arn))
+	})
+
+	It("doesn't match a wildcard certificate more than one label up", func() {
+		acmFake.add("*.example.com", &fakeCertificate{Detail: types.CertificateDetail{
+			SubjectAlternativeNames: []string{"*.example.com"},
+		}})
+
+		certificate, err := r.findSecretByDomain(ctx, acmFake, "a.b.example.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(certificate).To(BeNil())
+		Expect(acmFake.called("DescribeCertificate")).To(BeZero())
+	})
+
+	It("prefers a certificate naming the domain over a wildcard", func() {
+		acmFake.add("*.example.com", &fakeCertificate{Detail: types.CertificateDetail{
+			SubjectAlternativeNames: []string{"*.example.com"},
+		}})
+		exact := acmFake.add("app.example.com", &fakeCertificate{Detail: types.CertificateDetail{
+			SubjectAlternativeNames: []string{"app.example.com"},
+		}})
+
+		certificate, err := r.findSecretByDomain(ctx, acmFake, "app.example.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(aws.ToString(certificate.CertificateArn)).To(Equal(exact))
+	})
+
+	It("matches a certificate by its common name alone", func() {
+		arn := acmFake.add("example.com", &fakeCertificate{Detail: types.CertificateDetail{
+			DomainName: aws.String("example.com"),
+		}})
+
+		certificate, err := r.findSecretByDomain(ctx, acmFake, "example.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(certificate).NotTo(BeNil())
+		Expect(aws.ToString(certificate.CertificateArn)).To(Equal(arn))
+	})
+
+	Context("with detached duplicates", func() {
+		var detached, inUse string
+
+		BeforeEach(func() {
+			detached = acmFake.add("example.com", &fakeCertificate{Detail: types.CertificateDetail{
+				SubjectAlternativeNames: []string{"example.com"},
+			}})
+			inUse = acmFake.add("example.com", &fakeCertificate{Detail: types.CertificateDetail{
+				SubjectAlternativeNames: []string{"example.com"},
+				InUseBy:                 []string{"arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/web/1"},
+			}})
+			acmFake.add("example.com", &fakeCertificate{Detail: types.CertificateDetail{
+				SubjectAlternativeNames: []string{"example.com"},
+			}})
+		})
+
+		It("prefers the certificate in use", func() {
+			r.PreferInUse = true
+			certificate, err := r.findSecretByDomain(ctx, acmFake, "example.com")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(aws.ToString(certificate.CertificateArn)).To(Equal(inUse))
+		})
+
+		It("falls back to the first match when none is in use", func() {
+			r.PreferInUse = true
+			acmFake.certs[1].Detail.InUseBy = nil
+			certificate, err := r.findSecretByDomain(ctx, acmFake, "example.com")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(aws.ToString(certificate.CertificateArn)).To(Equal(detached))
+			Expect(acmFake.called("DescribeCertificate")).To(Equal(3))
+		})
+
+		It("takes the first match when disabled", func() {
+			certificate, err := r.findSecretByDomain(ctx, acmFake, "example.com")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(aws.ToString(certificate.CertificateArn)).To(Equal(detached))
+			Expect(acmFake.called("DescribeCertificate")).To(Equal(1))
+		})
+	})
+})
+
+var _ = Describe("summaryMayMatchDomain", func() {
+	It("rules out certificates whose listed names don't match", func() {
+		Expect(summaryMayMatchDomain(types.CertificateSummary{DomainName: aws.String("other.com"), SubjectAlternativeNameSummaries: []string{"other.com"}}, "example.com")).To(BeFalse())
+	})
+
+	It("keeps certificates whose domain or a listed SAN matches", func() {
+		Expect(summaryMayMatchDomain(types.CertificateSummary{DomainName: aws.String("example.com")}, "example.com")).To(BeTrue())
+		Expect(summaryMayMatchDomain(types.CertificateSummary{DomainName: aws.String("other.com"), SubjectAlternativeNameSummaries: []string{"other.com", "example.com"}}, "example.com")).To(BeTrue())
+	})
+
+	It("keeps certificates it can't rule out", func() {
+		Expect(summaryMayMatchDomain(types.CertificateSummary{}, "example.com")).To(BeTrue())
+		Expect(summaryMayMatchDomain(types.CertificateSummary{DomainName: aws.String("other.com"), HasAdditionalSubjectAlternativeNames: aws.Bool(true)}, "example.com")).To(BeTrue())
+	})
+
+	It("only describes the matching certificate among many", func() {
+		acmFake := newFakeACM()
+		for i := range 49 {
+			domain := fmt.Sprintf("host%d.example.org", i)
+			acmFake.add(domain, &fakeCertificate{Detail: types.CertificateDetail{Type: types.CertificateTypeImported, SubjectAlternativeNames: []string{domain}}})
+		}
+		target := acmFake.add("example.com", &fakeCertificate{Detail: types.CertificateDetail{Type: types.CertificateTypeImported, SubjectAlternativeNames: []string{"example.com"}}})
+
+		certificate, err := (&SecretReconciler{Log: logr.Discard()}).findSecretByDomain(context.Background(), acmFake, "example.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(aws.ToString(certificate.CertificateArn)).To(Equal(target))
+		Expect(acmFake.called("DescribeCertificate")).To(Equal(1))
+	})
+})
+
+var _ = Describe("certMatchesDomain", func() {
+	It("compares the common name by value", func() {
+		detail := &types.CertificateDetail{DomainName: aws.String("example.com")}
+		Expect(certMatchesDomain(detail, "example.com")).To(BeTrue())
+		Expect(certMatchesDomain(detail, "other.example.com")).To(BeFalse())
+	})
+})
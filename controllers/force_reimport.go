@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// forceReimportAnnotation holds an arbitrary token. Setting it to a new value
+// re-imports the certificate once, even when ACM already holds a valid,
+// identical copy, e.g. to repair a certificate changed by hand in ACM.
+const forceReimportAnnotation = "cert-sync.denyshubh.github.io/force-reimport"
+
+// forceReimportProcessedAnnotation records the last forceReimportAnnotation
+// token acted on in each region, as comma-separated region=token pairs, so
+// each token triggers a single re-import per region even while another
+// region keeps failing. A bare token, as recorded by earlier versions, covers
+// every region.
+const forceReimportProcessedAnnotation = "cert-sync.denyshubh.github.io/force-reimport-processed"
+
+// forceReimportRequested reports whether secret carries a force-reimport
+// token that hasn't been processed in region yet.
+func forceReimportRequested(secret *corev1.Secret, region string) bool {
+	token := secret.Annotations[forceReimportAnnotation]
+	return token != "" && token != processedForceReimport(secret, region)
+}
+
+// processedForceReimport returns the force-reimport token last processed in
+// region.
+func processedForceReimport(secret *corev1.Secret, region string) string {
+	for _, entry := range processedEntries(secret) {
+		entryRegion, token, ok := strings.Cut(entry, "=")
+		if !ok {
+			return entry
+		}
+		if entryRegion == region {
+			return token
+		}
+	}
+	return ""
+}
+
+// recordForceReimport marks secret's force-reimport token as processed in
+// regions, the regions synced by this reconcile. Regions that failed keep it
+// pending. A dry run leaves it pending so the re-import happens once DryRun
+// is off.
+func (r *SecretReconciler) recordForceReimport(ctx context.Context, secret *corev1.Secret, regions []string) error {
+	token := secret.Annotations[forceReimportAnnotation]
+	if r.DryRun || token == "" {
+		return nil
+	}
+
+	var entries []string
+	for _, entry := range processedEntries(secret) {
+		// Entries for earlier tokens no longer matter
+		region, processed, ok := strings.Cut(entry, "=")
+		if ok && processed == token && !slices.Contains(regions, region) {
+			entries = append(entries, entry)
+		}
+	}
+	for _, region := range regions {
+		entries = append(entries, region+"="+token)
+	}
+	value := strings.Join(entries, ",")
+	if value == secret.Annotations[forceReimportProcessedAnnotation] {
+		return nil
+	}
+
+	original := secret.DeepCopy()
+	secret.Annotations[forceReimportProcessedAnnotation] = value
+	return r.writeBack(ctx, original, secret)
+}
+
+// processedEntries splits secret's forceReimportProcessedAnnotation into its
+// entries.
+func processedEntries(secret *corev1.Secret) []string {
+	var entries []string
+	for _, entry := range strings.Split(secret.Annotations[forceReimportProcessedAnnotation], ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+var _ = Describe("Force re-import", func() {
+	var (
+		ctx     context.Context
+		acmFake *fakeACM
+		k8s     client.Client
+		r       *SecretReconciler
+		key     client.ObjectKey
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		acmFake = newFakeACM()
+		_, intermediate, leaf := newTestChain("example.com")
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "prod",
+				Name:      "web-tls",
+				Annotations: map[string]string{
+					"sync-to-acm":                 "true",
+					"cert-manager.io/common-name": "example.com",
+				},
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       append(append([]byte{}, leaf.PEM...), intermediate.PEM...),
+				corev1.TLSPrivateKeyKey: leaf.keyPEM(),
+			},
+		}
+		key = client.ObjectKeyFromObject(secret)
+		k8s = fake.NewClientBuilder().WithObjects(secret).Build()
+		r = &SecretReconciler{Client: k8s, Log: logr.Discard(), ACM: acmFake}
+	})
+
+	reconcileSecret := func() {
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	setToken := func(token string) {
+		var secret corev1.Secret
+		Expect(k8s.Get(ctx, key, &secret)).To(Succeed())
+		secret.Annotations[forceReimportAnnotation] = token
+		Expect(k8s.Update(ctx, &secret)).To(Succeed())
+	}
+
+	It("re-imports an unchanged certificate once per token", func() {
+		reconcileSecret()
+		reconcileSecret()
+		Expect(acmFake.called("ImportCertificate")).To(Equal(1))
+
+		setToken("1")
+		reconcileSecret()
+		Expect(acmFake.called("ImportCertificate")).To(Equal(2))
+		reconcileSecret()
+		Expect(acmFake.called("ImportCertificate")).To(Equal(2), "a processed token must not re-import again")
+
+		var secret corev1.Secret
+		Expect(k8s.Get(ctx, key, &secret)).To(Succeed())
+		Expect(secret.Annotations).To(HaveKeyWithValue(forceReimportProcessedAnnotation, "us-east-1=1"))
+
+		setToken("2")
+		reconcileSecret()
+		reconcileSecret()
+		Expect(acmFake.called("ImportCertificate")).To(Equal(3))
+	})
+
+	It("leaves the token pending in a dry run", func() {
+		reconcileSecret()
+		setToken("1")
+		r.DryRun = true
+		reconcileSecret()
+		Expect(acmFake.called("ImportCertificate")).To(Equal(1))
+
+		r.DryRun = false
+		reconcileSecret()
+		Expect(acmFake.called("ImportCertificate")).To(Equal(2))
+	})
+
+	It("treats a token recorded by earlier versions as processed everywhere", func() {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			forceReimportAnnotation:          "1",
+			forceReimportProcessedAnnotation: "1",
+		}}}
+		Expect(forceReimportRequested(secret, "us-east-1")).To(BeFalse())
+		Expect(forceReimportRequested(secret, "eu-west-1")).To(BeFalse())
+	})
+
+	Context("in two regions", func() {
+		var regional map[string]*fakeACM
+
+		BeforeEach(func() {
+			var secret corev1.Secret
+			Expect(k8s.Get(ctx, key, &secret)).To(Succeed())
+			secret.Annotations[regionsAnnotation] = "us-east-1,eu-west-1"
+			Expect(k8s.Update(ctx, &secret)).To(Succeed())
+
+			regional = map[string]*fakeACM{}
+			for _, region := range []string{"us-east-1", "eu-west-1"} {
+				regional[region] = newFakeACM()
+				regional[region].region = region
+			}
+			r.ACM = nil
+			r.NewACMClient = func(_ context.Context, clientKey awsclient.ClientKey) (awsclient.ACMAPI, error) {
+				return regional[clientKey.Region], nil
+			}
+		})
+
+		It("re-imports only into the region that failed when retried", func() {
+			reconcileSecret()
+			setToken("1")
+			regional["eu-west-1"].importErrs = []error{errors.New("access denied")}
+
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).To(HaveOccurred())
+			Expect(regional["us-east-1"].called("ImportCertificate")).To(Equal(2))
+			Expect(regional["eu-west-1"].called("ImportCertificate")).To(Equal(2))
+
+			var secret corev1.Secret
+			Expect(k8s.Get(ctx, key, &secret)).To(Succeed())
+			Expect(secret.Annotations).To(HaveKeyWithValue(forceReimportProcessedAnnotation, "us-east-1=1"))
+
+			reconcileSecret()
+			Expect(regional["us-east-1"].called("ImportCertificate")).To(Equal(2), "the token was processed in us-east-1")
+			Expect(regional["eu-west-1"].called("ImportCertificate")).To(Equal(3))
+
+			Expect(k8s.Get(ctx, key, &secret)).To(Succeed())
+			Expect(secret.Annotations).To(HaveKeyWithValue(forceReimportProcessedAnnotation, "us-east-1=1,eu-west-1=1"))
+
+			reconcileSecret()
+			Expect(regional["us-east-1"].called("ImportCertificate")).To(Equal(2))
+			Expect(regional["eu-west-1"].called("ImportCertificate")).To(Equal(3))
+		})
+	})
+})
@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+)
+
+// InUsePolicy controls what happens with CleanupOnDelete when a deleted
+// Secret's ACM certificate is still attached to AWS resources (InUseBy).
+type InUsePolicy string
+
+const (
+	// InUseOrphan leaves the certificate in ACM and releases the Secret.
+	InUseOrphan InUsePolicy = "orphan"
+	// InUseWait keeps the finalizer and rechecks every inUseRecheckInterval,
+	// deleting the certificate once it is no longer in use.
+	InUseWait InUsePolicy = "wait"
+)
+
+// inUseRecheckInterval is how often a Secret waiting under InUseWait checks
+// whether its certificate has been detached.
+const inUseRecheckInterval = 5 * time.Minute
+
+// ParseInUsePolicy validates s as an InUsePolicy.
+func ParseInUsePolicy(s string) (InUsePolicy, error) {
+	switch p := InUsePolicy(s); p {
+	case InUseOrphan, InUseWait:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid in-use policy %q: must be one of orphan, wait", s)
+}
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// DefaultInitialSyncInterval is the default pause between two Secrets
+// enqueued by InitialSync.
+const DefaultInitialSyncInterval = 100 * time.Millisecond
+
+// InitialSync enqueues every Secret asking to be synced once when the manager
+// starts, so a certificate that expired or changed while the controller was
+// down is synced without waiting for an unrelated update to its Secret. It
+// honours the reconciler's label selector; the namespaces are those of the
+// manager cache. Enqueues are spaced by Interval so a large cluster doesn't
+// flood ACM right after a restart.
+type InitialSync struct {
+	// Reconciler provides the client and the sync and selector settings.
+	Reconciler *SecretReconciler
+	// Events is the channel the reconciler's Triggers read from.
+	Events chan<- event.GenericEvent
+	// Interval is the pause between two enqueued Secrets. Defaults to
+	// DefaultInitialSyncInterval.
+	Interval time.Duration
+	Log      logr.Logger
+}
+
+// Start lists the Secrets to sync and enqueues them, returning once all are
+// enqueued or ctx is cancelled. It implements manager.Runnable.
+func (s *InitialSync) Start(ctx context.Context) error {
+	var secrets corev1.SecretList
+	if err := s.Reconciler.List(ctx, &secrets); err != nil {
+		return err
+	}
+
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultInitialSyncInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	enqueued := 0
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if secret.Type != corev1.SecretTypeTLS || !s.Reconciler.syncRequested(secret) || !s.Reconciler.selected(secret) {
+			continue
+		}
+		if enqueued > 0 {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		trigger := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: secret.Namespace, Name: secret.Name}}
+		select {
+		case s.Events <- event.GenericEvent{Object: trigger}:
+			enqueued++
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	s.Log.Info("Initial sync enqueued Secrets", "count", enqueued)
+	return nil
+}
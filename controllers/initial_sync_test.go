@@ -0,0 +1,128 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+var _ = Describe("InitialSync", func() {
+	secretWith := func(namespace, name string, secretType corev1.SecretType, annotations, secretLabels map[string]string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Annotations: annotations, Labels: secretLabels},
+			Type:       secretType,
+		}
+	}
+	synced := map[string]string{"sync-to-acm": "true"}
+	web := map[string]string{"team": "web"}
+
+	enqueued := func(events <-chan event.GenericEvent) []types.NamespacedName {
+		var keys []types.NamespacedName
+		for {
+			select {
+			case e := <-events:
+				keys = append(keys, client.ObjectKeyFromObject(e.Object))
+			default:
+				return keys
+			}
+		}
+	}
+
+	var objects []client.Object
+
+	BeforeEach(func() {
+		objects = []client.Object{
+			secretWith("prod", "web-tls", corev1.SecretTypeTLS, synced, web),
+			secretWith("staging", "web-tls", corev1.SecretTypeTLS, synced, web),
+			secretWith("prod", "api-tls", corev1.SecretTypeTLS, synced, map[string]string{"team": "api"}),
+			secretWith("prod", "unsynced-tls", corev1.SecretTypeTLS, nil, web),
+			secretWith("prod", "paused-tls", corev1.SecretTypeTLS, map[string]string{"sync-to-acm": "false"}, web),
+			secretWith("prod", "opaque", corev1.SecretTypeOpaque, synced, web),
+		}
+	})
+
+	It("enqueues every Secret asking to be synced", func() {
+		events := make(chan event.GenericEvent, len(objects))
+		s := &InitialSync{
+			Reconciler: &SecretReconciler{Client: fake.NewClientBuilder().WithObjects(objects...).Build()},
+			Events:     events,
+			Interval:   time.Millisecond,
+			Log:        logr.Discard(),
+		}
+		Expect(s.Start(context.Background())).To(Succeed())
+		Expect(enqueued(events)).To(ConsistOf(
+			types.NamespacedName{Namespace: "prod", Name: "web-tls"},
+			types.NamespacedName{Namespace: "staging", Name: "web-tls"},
+			types.NamespacedName{Namespace: "prod", Name: "api-tls"},
+		))
+	})
+
+	It("respects the label selector", func() {
+		selector, err := labels.Parse("team=web")
+		Expect(err).NotTo(HaveOccurred())
+
+		events := make(chan event.GenericEvent, len(objects))
+		s := &InitialSync{
+			Reconciler: &SecretReconciler{Client: fake.NewClientBuilder().WithObjects(objects...).Build(), SecretSelector: selector},
+			Events:     events,
+			Interval:   time.Millisecond,
+			Log:        logr.Discard(),
+		}
+		Expect(s.Start(context.Background())).To(Succeed())
+		Expect(enqueued(events)).To(ConsistOf(
+			types.NamespacedName{Namespace: "prod", Name: "web-tls"},
+			types.NamespacedName{Namespace: "staging", Name: "web-tls"},
+		))
+	})
+
+	It("spaces out enqueues", func() {
+		events := make(chan event.GenericEvent, len(objects))
+		s := &InitialSync{
+			Reconciler: &SecretReconciler{Client: fake.NewClientBuilder().WithObjects(objects...).Build()},
+			Events:     events,
+			Interval:   20 * time.Millisecond,
+			Log:        logr.Discard(),
+		}
+		start := time.Now()
+		Expect(s.Start(context.Background())).To(Succeed())
+		Expect(enqueued(events)).To(HaveLen(3))
+		Expect(time.Since(start)).To(BeNumerically(">=", 40*time.Millisecond))
+	})
+
+	It("stops when the context is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		s := &InitialSync{
+			Reconciler: &SecretReconciler{Client: fake.NewClientBuilder().WithObjects(objects...).Build()},
+			Events:     make(chan event.GenericEvent),
+			Log:        logr.Discard(),
+		}
+		Expect(s.Start(ctx)).To(Succeed())
+	})
+})
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// KeyUsagePolicy controls what happens to a leaf certificate whose key usage
+// doesn't allow TLS server authentication.
+type KeyUsagePolicy string
+
+const (
+	// KeyUsageIgnore doesn't check key usage.
+	KeyUsageIgnore KeyUsagePolicy = "ignore"
+	// KeyUsageWarn logs a warning and imports the certificate anyway.
+	KeyUsageWarn KeyUsagePolicy = "warn"
+	// KeyUsageSkip refuses to import the certificate.
+	KeyUsageSkip KeyUsagePolicy = "skip"
+)
+
+// ParseKeyUsagePolicy validates s as a KeyUsagePolicy.
+func ParseKeyUsagePolicy(s string) (KeyUsagePolicy, error) {
+	switch p := KeyUsagePolicy(s); p {
+	case KeyUsageIgnore, KeyUsageWarn, KeyUsageSkip:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid key usage policy %q: must be one of ignore, warn, skip", s)
+}
+
+// checkTLSKeyUsage returns an error if leaf can't be used as a TLS server
+// certificate: it needs digitalSignature, RSA keys also need keyEncipherment
+// for RSA key exchange, and a present extended key usage must allow server
+// auth. A missing key usage extension allows every usage.
+func checkTLSKeyUsage(leaf *x509.Certificate) error {
+	var missing []string
+	if leaf.KeyUsage != 0 {
+		if leaf.KeyUsage&x509.KeyUsageDigitalSignature == 0 {
+			missing = append(missing, "digitalSignature")
+		}
+		if _, isRSA := leaf.PublicKey.(*rsa.PublicKey); isRSA && leaf.KeyUsage&x509.KeyUsageKeyEncipherment == 0 {
+			missing = append(missing, "keyEncipherment")
+		}
+	}
+	if len(leaf.ExtKeyUsage) > 0 &&
+		!slices.Contains(leaf.ExtKeyUsage, x509.ExtKeyUsageServerAuth) &&
+		!slices.Contains(leaf.ExtKeyUsage, x509.ExtKeyUsageAny) {
+		missing = append(missing, "serverAuth")
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("certificate key usage lacks %s required for TLS", strings.Join(missing, ", "))
+	}
+	return nil
+}
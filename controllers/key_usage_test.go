@@ -0,0 +1,58 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/x509"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TLS key usage", func() {
+	It("accepts a proper TLS certificate", func() {
+		_, _, leaf := newTestChain("example.com")
+		Expect(checkTLSKeyUsage(leaf.Cert)).To(Succeed())
+	})
+
+	It("accepts an RSA certificate with digitalSignature and keyEncipherment", func() {
+		leaf := newTestCert("example.com", nil, testCertOptions{RSA: true, KeyUsage: x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment})
+		Expect(checkTLSKeyUsage(leaf.Cert)).To(Succeed())
+	})
+
+	It("rejects an RSA certificate missing keyEncipherment", func() {
+		leaf := newTestCert("example.com", nil, testCertOptions{RSA: true, KeyUsage: x509.KeyUsageDigitalSignature})
+		Expect(checkTLSKeyUsage(leaf.Cert)).To(MatchError(ContainSubstring("keyEncipherment")))
+	})
+
+	It("rejects a certificate missing digitalSignature", func() {
+		leaf := newTestCert("example.com", nil, testCertOptions{KeyUsage: x509.KeyUsageContentCommitment})
+		Expect(checkTLSKeyUsage(leaf.Cert)).To(MatchError(ContainSubstring("digitalSignature")))
+	})
+
+	It("rejects a certificate whose extended key usage excludes server auth", func() {
+		_, _, leaf := newTestChain("example.com")
+		leaf.Cert.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+		Expect(checkTLSKeyUsage(leaf.Cert)).To(MatchError(ContainSubstring("serverAuth")))
+	})
+
+	It("parses the policy flag", func() {
+		Expect(ParseKeyUsagePolicy("skip")).To(Equal(KeyUsageSkip))
+		_, err := ParseKeyUsagePolicy("strict")
+		Expect(err).To(HaveOccurred())
+	})
+})
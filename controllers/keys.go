@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+)
+
+// Private key encodings recognised by normalizePrivateKeyPEM.
+const (
+	keyFormatPKCS1 = "PKCS#1"
+	keyFormatSEC1  = "SEC1"
+	keyFormatPKCS8 = "PKCS#8"
+)
+
+// parsePrivateKeyPEM decodes the first private key block in keyPEM and reports
+// its encoding.
+func parsePrivateKeyPEM(keyPEM []byte) (crypto.PrivateKey, string, error) {
+	rest := keyPEM
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, "", fmt.Errorf("no private key found in PEM data")
+		}
+
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+			return key, keyFormatPKCS1, err
+		case "EC PRIVATE KEY":
+			key, err := x509.ParseECPrivateKey(block.Bytes)
+			return key, keyFormatSEC1, err
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			return key, keyFormatPKCS8, err
+		}
+	}
+}
+
+// normalizePrivateKeyPEM validates keyPEM and returns it along with its
+// detected encoding. When toPKCS8 is set, PKCS#1 and SEC1 keys are re-encoded
+// as PKCS#8; otherwise the key is passed through unchanged.
+func normalizePrivateKeyPEM(keyPEM []byte, toPKCS8 bool) ([]byte, string, error) {
+	key, format, err := parsePrivateKeyPEM(keyPEM)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	if !toPKCS8 || format == keyFormatPKCS8 {
+		return keyPEM, format, nil
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to convert %s private key to PKCS#8: %w", format, err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), format, nil
+}
+
+// keyAlgorithm returns the ACM key algorithm of the private key in keyPEM,
+// e.g. RSA_2048 or EC_prime256v1, or an error for a key ACM won't import.
+// RSA keys shorter than 2048 bits are rejected as too weak.
+func keyAlgorithm(keyPEM []byte) (types.KeyAlgorithm, error) {
+	key, _, err := parsePrivateKeyPEM(keyPEM)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		switch bits := key.N.BitLen(); bits {
+		case 2048:
+			return types.KeyAlgorithmRsa2048, nil
+		case 3072:
+			return types.KeyAlgorithmRsa3072, nil
+		case 4096:
+			return types.KeyAlgorithmRsa4096, nil
+		default:
+			return "", fmt.Errorf("unsupported RSA key size %d bits; ACM accepts 2048, 3072 or 4096", bits)
+		}
+	case *ecdsa.PrivateKey:
+		switch curve := key.Curve.Params().Name; curve {
+		case "P-256":
+			return types.KeyAlgorithmEcPrime256v1, nil
+		case "P-384":
+			return types.KeyAlgorithmEcSecp384r1, nil
+		case "P-521":
+			return types.KeyAlgorithmEcSecp521r1, nil
+		default:
+			return "", fmt.Errorf("unsupported ECDSA curve %s; ACM accepts P-256, P-384 or P-521", curve)
+		}
+	default:
+		return "", fmt.Errorf("unsupported private key type %T; ACM accepts RSA and ECDSA keys", key)
+	}
+}
+
+// checkKeyMatchesCertificate verifies that keyPEM is the private key of leaf,
+// which ACM would otherwise reject with an opaque validation error.
+func checkKeyMatchesCertificate(keyPEM []byte, leaf *x509.Certificate) error {
+	key, _, err := parsePrivateKeyPEM(keyPEM)
+	if err != nil {
+		return fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		public, ok := leaf.PublicKey.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("private key is RSA but the certificate has a %s public key", leaf.PublicKeyAlgorithm)
+		}
+		if !key.PublicKey.Equal(public) {
+			return fmt.Errorf("RSA private key doesn't match the certificate's public key")
+		}
+	case *ecdsa.PrivateKey:
+		public, ok := leaf.PublicKey.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("private key is ECDSA but the certificate has a %s public key", leaf.PublicKeyAlgorithm)
+		}
+		if !key.PublicKey.Equal(public) {
+			return fmt.Errorf("ECDSA private key doesn't match the certificate's public key")
+		}
+	default:
+		return fmt.Errorf("unsupported private key type %T", key)
+	}
+	return nil
+}
+
+// isPrivateKeyBlock reports whether a PEM block type holds a private key
+// parsePrivateKeyPEM understands.
+func isPrivateKeyBlock(blockType string) bool {
+	switch blockType {
+	case "RSA PRIVATE KEY", "EC PRIVATE KEY", "PRIVATE KEY":
+		return true
+	}
+	return false
+}
+
+// splitCombinedPEM separates data holding both certificates and a private key
+// into its certificate blocks and its first private key block. ok is false
+// unless data holds both.
+func splitCombinedPEM(data []byte) (certPEM, keyPEM []byte, ok bool) {
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		switch {
+		case block.Type == "CERTIFICATE":
+			certPEM = append(certPEM, pem.EncodeToMemory(block)...)
+		case isPrivateKeyBlock(block.Type) && keyPEM == nil:
+			keyPEM = pem.EncodeToMemory(block)
+		}
+	}
+	return certPEM, keyPEM, certPEM != nil && keyPEM != nil
+}
+
+// separateCombinedPEM handles Secrets that store the certificate and private
+// key concatenated in tls.crt or tls.key, returning just the certificates and
+// just the key. A key found in tls.crt is only used when tls.key is empty,
+// and certificates found in tls.key only when tls.crt has none.
+func separateCombinedPEM(crt, key []byte) (certPEM, keyPEM []byte) {
+	if c, k, ok := splitCombinedPEM(crt); ok {
+		crt = c
+		if len(bytes.TrimSpace(key)) == 0 {
+			key = k
+		}
+	}
+	if c, k, ok := splitCombinedPEM(key); ok {
+		key = k
+		if len(certificateDERs(crt)) == 0 {
+			crt = c
+		}
+	}
+	return crt, key
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("normalizePrivateKeyPEM", func() {
+	var rsaKey *rsa.PrivateKey
+
+	BeforeEach(func() {
+		var err error
+		rsaKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	pkcs1PEM := func() []byte {
+		return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	}
+
+	It("converts PKCS#1 to PKCS#8", func() {
+		out, format, err := normalizePrivateKeyPEM(pkcs1PEM(), true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(format).To(Equal(keyFormatPKCS1))
+
+		block, _ := pem.Decode(out)
+		Expect(block.Type).To(Equal("PRIVATE KEY"))
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rsaKey.Equal(key)).To(BeTrue())
+	})
+
+	It("passes PKCS#1 through when conversion is disabled", func() {
+		in := pkcs1PEM()
+		out, format, err := normalizePrivateKeyPEM(in, false)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(format).To(Equal(keyFormatPKCS1))
+		Expect(out).To(Equal(in))
+	})
+
+	It("passes PKCS#8 through unchanged", func() {
+		in := newTestCert("example.com", nil, testCertOptions{}).keyPEM()
+		out, format, err := normalizePrivateKeyPEM(in, true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(format).To(Equal(keyFormatPKCS8))
+		Expect(out).To(Equal(in))
+	})
+
+	It("rejects data without a private key", func() {
+		_, _, err := normalizePrivateKeyPEM(newTestCert("example.com", nil, testCertOptions{}).PEM, true)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("separateCombinedPEM", func() {
+	var intermediate, leaf *testCert
+
+	BeforeEach(func() {
+		_, intermediate, leaf = newTestChain("example.com")
+	})
+
+	It("splits a certificate and key combined in tls.crt", func() {
+		combined := append(append(append([]byte{}, leaf.PEM...), intermediate.PEM...), leaf.keyPEM()...)
+
+		certPEM, keyPEM := separateCombinedPEM(combined, nil)
+		Expect(keyPEM).To(Equal(leaf.keyPEM()))
+		leafPEM, chainPEM, err := splitCertificateChain(certPEM)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(leafPEM).To(Equal(leaf.PEM))
+		Expect(chainPEM).To(Equal(intermediate.PEM))
+	})
+
+	It("splits a key and certificate combined in tls.key", func() {
+		combined := append(append([]byte{}, leaf.keyPEM()...), leaf.PEM...)
+
+		certPEM, keyPEM := separateCombinedPEM(nil, combined)
+		Expect(certPEM).To(Equal(leaf.PEM))
+		Expect(keyPEM).To(Equal(leaf.keyPEM()))
+	})
+
+	It("prefers tls.key over a key found in tls.crt", func() {
+		other := newTestCert("example.com", nil, testCertOptions{})
+		combined := append(append([]byte{}, leaf.PEM...), other.keyPEM()...)
+
+		certPEM, keyPEM := separateCombinedPEM(combined, leaf.keyPEM())
+		Expect(certPEM).To(Equal(leaf.PEM))
+		Expect(keyPEM).To(Equal(leaf.keyPEM()))
+	})
+
+	It("leaves separate fields unchanged", func() {
+		certPEM, keyPEM := separateCombinedPEM(leaf.PEM, leaf.keyPEM())
+		Expect(certPEM).To(Equal(leaf.PEM))
+		Expect(keyPEM).To(Equal(leaf.keyPEM()))
+	})
+})
+
+var _ = Describe("checkKeyMatchesCertificate", func() {
+	It("accepts an RSA certificate with its own key", func() {
+		cert := newTestCert("example.com", nil, testCertOptions{RSA: true})
+		Expect(checkKeyMatchesCertificate(cert.keyPEM(), cert.Cert)).To(Succeed())
+	})
+
+	It("accepts an ECDSA certificate with its own key", func() {
+		cert := newTestCert("example.com", nil, testCertOptions{})
+		Expect(checkKeyMatchesCertificate(cert.keyPEM(), cert.Cert)).To(Succeed())
+	})
+
+	It("rejects a key from another certificate", func() {
+		cert := newTestCert("example.com", nil, testCertOptions{RSA: true})
+		other := newTestCert("example.com", nil, testCertOptions{RSA: true})
+		Expect(checkKeyMatchesCertificate(other.keyPEM(), cert.Cert)).To(MatchError(ContainSubstring("RSA private key doesn't match")))
+	})
+
+	It("rejects an ECDSA key from another certificate", func() {
+		cert := newTestCert("example.com", nil, testCertOptions{})
+		other := newTestCert("example.com", nil, testCertOptions{})
+		Expect(checkKeyMatchesCertificate(other.keyPEM(), cert.Cert)).To(MatchError(ContainSubstring("ECDSA private key doesn't match")))
+	})
+
+	It("rejects a key of another type", func() {
+		cert := newTestCert("example.com", nil, testCertOptions{})
+		other := newTestCert("example.com", nil, testCertOptions{RSA: true})
+		Expect(checkKeyMatchesCertificate(other.keyPEM(), cert.Cert)).To(MatchError(ContainSubstring("private key is RSA")))
+	})
+})
+
+var _ = Describe("keyAlgorithm", func() {
+	It("detects a supported RSA key", func() {
+		algorithm, err := keyAlgorithm(newTestCert("example.com", nil, testCertOptions{RSA: true}).keyPEM())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(algorithm).To(Equal(types.KeyAlgorithmRsa2048))
+	})
+
+	It("detects a supported ECDSA key", func() {
+		algorithm, err := keyAlgorithm(newTestCert("example.com", nil, testCertOptions{}).keyPEM())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(algorithm).To(Equal(types.KeyAlgorithmEcPrime256v1))
+	})
+
+	It("rejects an RSA 1024 bit key", func() {
+		weak, err := rsa.GenerateKey(rand.Reader, 1024)
+		Expect(err).NotTo(HaveOccurred())
+		keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(weak)})
+
+		_, err = keyAlgorithm(keyPEM)
+		Expect(err).To(MatchError(ContainSubstring("unsupported RSA key size 1024 bits")))
+	})
+
+	It("rejects an ECDSA key on another curve", func() {
+		key, err := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+		Expect(err).NotTo(HaveOccurred())
+		der, err := x509.MarshalECPrivateKey(key)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = keyAlgorithm(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}))
+		Expect(err).To(MatchError(ContainSubstring("unsupported ECDSA curve P-224")))
+	})
+})
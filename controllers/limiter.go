@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"context"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ImportLimiter bounds the number of in-flight ACM ImportCertificate calls.
+// A single limiter is shared by every reconcile worker and target region so
+// the total load on the AWS account stays bounded.
+type ImportLimiter struct {
+	slots chan struct{}
+}
+
+// NewImportLimiter returns a limiter allowing max concurrent imports, or nil
+// (unlimited) when max is not positive.
+func NewImportLimiter(max int) *ImportLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &ImportLimiter{slots: make(chan struct{}, max)}
+}
+
+// Acquire blocks until a slot is free or ctx is done. A nil limiter never
+// blocks.
+func (l *ImportLimiter) Acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot taken by Acquire.
+func (l *ImportLimiter) Release() {
+	if l == nil {
+		return
+	}
+	<-l.slots
+}
+
+// SyncLimiter caps the number of distinct Secrets being synced at once. Unlike
+// ImportLimiter it never blocks: a Secret over the cap is deferred with a short
+// requeue so workers stay free during mass events.
+type SyncLimiter struct {
+	mu     sync.Mutex
+	max    int
+	active map[types.NamespacedName]struct{}
+}
+
+// NewSyncLimiter returns a limiter allowing max concurrently synced Secrets,
+// or nil (unlimited) when max is not positive.
+func NewSyncLimiter(max int) *SyncLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &SyncLimiter{max: max, active: map[types.NamespacedName]struct{}{}}
+}
+
+// TryAcquire marks key as being synced and reports whether there was room. A
+// nil limiter always has room.
+func (l *SyncLimiter) TryAcquire(key types.NamespacedName) bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.active[key]; ok {
+		return true
+	}
+	if len(l.active) >= l.max {
+		return false
+	}
+	l.active[key] = struct{}{}
+	return true
+}
+
+// Release marks key as no longer being synced.
+func (l *SyncLimiter) Release(key types.NamespacedName) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.active, key)
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	acmtypes "github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("ImportLimiter", func() {
+	It("caps concurrent imports across workers", func() {
+		limiter := NewImportLimiter(2)
+
+		var inFlight, peak int32
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				Expect(limiter.Acquire(context.Background())).To(Succeed())
+				defer limiter.Release()
+
+				n := atomic.AddInt32(&inFlight, 1)
+				for {
+					p := atomic.LoadInt32(&peak)
+					if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				atomic.AddInt32(&inFlight, -1)
+			}()
+		}
+		wg.Wait()
+
+		Expect(peak).To(BeNumerically("<=", 2))
+	})
+
+	It("gives up when the context is cancelled", func() {
+		limiter := NewImportLimiter(1)
+		Expect(limiter.Acquire(context.Background())).To(Succeed())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		Expect(limiter.Acquire(ctx)).To(MatchError(context.Canceled))
+	})
+
+	It("is unlimited when disabled", func() {
+		limiter := NewImportLimiter(0)
+		Expect(limiter).To(BeNil())
+		Expect(limiter.Acquire(context.Background())).To(Succeed())
+		limiter.Release()
+	})
+})
+
+// slotCheckingACM records whether an ImportLimiter slot was free whenever
+// tags were added.
+type slotCheckingACM struct {
+	*fakeACM
+	limiter  *ImportLimiter
+	slotFree []bool
+}
+
+func (a *slotCheckingACM) AddTagsToCertificate(ctx context.Context, params *acm.AddTagsToCertificateInput, optFns ...func(*acm.Options)) (*acm.AddTagsToCertificateOutput, error) {
+	probe, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	free := a.limiter.Acquire(probe) == nil
+	if free {
+		a.limiter.Release()
+	}
+	a.slotFree = append(a.slotFree, free)
+	return a.fakeACM.AddTagsToCertificate(ctx, params, optFns...)
+}
+
+var _ = Describe("ImportLimiter slots", func() {
+	It("are released before the tags of an updated certificate are written", func() {
+		_, intermediate, leaf := newTestChain("example.com")
+		acmFake := newFakeACM()
+		certificateArn := aws.String(acmFake.add("example.com", &fakeCertificate{Detail: acmtypes.CertificateDetail{Type: acmtypes.CertificateTypeImported}}))
+		r := &SecretReconciler{Log: logr.Discard(), ImportLimiter: NewImportLimiter(1), ContentHashTags: true}
+		acmClient := &slotCheckingACM{fakeACM: acmFake, limiter: r.ImportLimiter}
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web-tls"}}
+
+		Expect(r.updateToAcm(context.Background(), acmClient, secret, certificateArn, leaf.PEM, intermediate.PEM, leaf.keyPEM())).To(Succeed())
+		Expect(acmClient.slotFree).NotTo(BeEmpty())
+		Expect(acmClient.slotFree).NotTo(ContainElement(false))
+	})
+})
+
+var _ = Describe("SyncLimiter", func() {
+	a := types.NamespacedName{Namespace: "prod", Name: "a"}
+	b := types.NamespacedName{Namespace: "prod", Name: "b"}
+	c := types.NamespacedName{Namespace: "prod", Name: "c"}
+
+	It("defers Secrets over the cap until a slot is released", func() {
+		limiter := NewSyncLimiter(2)
+		Expect(limiter.TryAcquire(a)).To(BeTrue())
+		Expect(limiter.TryAcquire(b)).To(BeTrue())
+		Expect(limiter.TryAcquire(c)).To(BeFalse())
+
+		limiter.Release(a)
+		Expect(limiter.TryAcquire(c)).To(BeTrue())
+	})
+
+	It("does not count the same Secret twice", func() {
+		limiter := NewSyncLimiter(1)
+		Expect(limiter.TryAcquire(a)).To(BeTrue())
+		Expect(limiter.TryAcquire(a)).To(BeTrue())
+		Expect(limiter.TryAcquire(b)).To(BeFalse())
+	})
+
+	It("is unlimited when disabled", func() {
+		limiter := NewSyncLimiter(0)
+		Expect(limiter.TryAcquire(a)).To(BeTrue())
+		limiter.Release(a)
+	})
+})
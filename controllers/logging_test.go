@@ -0,0 +1,100 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+var _ = Describe("JSON logs", func() {
+	fieldName := regexp.MustCompile(`^[a-z][A-Za-z0-9]*$`)
+
+	It("emit one object per line with plain field names", func() {
+		ctx := context.Background()
+		_, intermediate, leaf := newTestChain("example.com")
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "prod",
+				Name:      "web-tls",
+				Annotations: map[string]string{
+					"sync-to-acm":                 "true",
+					"cert-manager.io/common-name": "example.com",
+				},
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       append(append([]byte{}, leaf.PEM...), intermediate.PEM...),
+				corev1.TLSPrivateKeyKey: leaf.keyPEM(),
+			},
+		}
+		acmFake := newFakeACM()
+		acmFake.add("example.com", &fakeCertificate{Detail: types.CertificateDetail{
+			Type:                    types.CertificateTypeImported,
+			SubjectAlternativeNames: []string{"example.com"},
+			NotAfter:                &leaf.Cert.NotAfter,
+		}})
+
+		var buf bytes.Buffer
+		r := &SecretReconciler{
+			Client: fake.NewClientBuilder().WithObjects(secret).Build(),
+			Log:    zap.New(zap.WriteTo(&buf), zap.JSONEncoder()),
+			NewACMClient: func(context.Context, awsclient.ClientKey) (awsclient.ACMAPI, error) {
+				return acmFake, nil
+			},
+		}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+		Expect(err).NotTo(HaveOccurred())
+
+		found := map[string]any{}
+		scanner := bufio.NewScanner(&buf)
+		lines := 0
+		for scanner.Scan() {
+			lines++
+			var entry map[string]any
+			Expect(json.Unmarshal(scanner.Bytes(), &entry)).To(Succeed(), scanner.Text())
+			Expect(entry).To(HaveKey("msg"))
+			Expect(entry).To(HaveKey("level"))
+			for key := range entry {
+				Expect(key).To(MatchRegexp(fieldName.String()))
+			}
+			if entry["msg"] == "Found certificate in ACM" {
+				found = entry
+			}
+		}
+		Expect(lines).To(BeNumerically(">", 1))
+		Expect(found).To(HaveKeyWithValue("certificateArn", HavePrefix("arn:aws:acm:")))
+		Expect(found).To(HaveKey("notAfter"))
+		Expect(found).To(HaveKeyWithValue("region", "us-east-1"))
+		Expect(found).To(HaveKey("secret"))
+	})
+})
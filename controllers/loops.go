@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// loopDetector remembers when each Secret was recently reconciled, to spot
+// Secrets that reconcile over and over, e.g. because a write-back keeps
+// changing them.
+type loopDetector struct {
+	mu     sync.Mutex
+	recent map[types.NamespacedName][]time.Time
+	now    func() time.Time
+}
+
+// observe records a reconcile of key and reports whether it is the one
+// taking key over threshold reconciles within window. The count restarts
+// after tripping, so a persistent loop is reported once per threshold
+// reconciles rather than on every one.
+func (d *loopDetector) observe(key types.NamespacedName, threshold int, window time.Duration) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.recent == nil {
+		d.recent = map[types.NamespacedName][]time.Time{}
+	}
+	now := time.Now()
+	if d.now != nil {
+		now = d.now()
+	}
+
+	recent := d.recent[key]
+	for len(recent) > 0 && now.Sub(recent[0]) > window {
+		recent = recent[1:]
+	}
+	recent = append(recent, now)
+	if len(recent) <= threshold {
+		d.recent[key] = recent
+		return false
+	}
+	delete(d.recent, key)
+	return true
+}
+
+// forget drops the history of key, e.g. once the Secret is deleted.
+func (d *loopDetector) forget(key types.NamespacedName) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.recent, key)
+}
+
+// detectLoop warns and counts a suspected reconcile loop when key reconciles
+// more than LoopThreshold times within LoopWindow.
+func (r *SecretReconciler) detectLoop(log logr.Logger, key types.NamespacedName) {
+	if r.LoopThreshold <= 0 {
+		return
+	}
+	if r.loops.observe(key, r.LoopThreshold, r.LoopWindow) {
+		log.Info("Secret is reconciling repeatedly; suspected reconcile loop", "threshold", r.LoopThreshold, "window", r.LoopWindow)
+		reconcileLoopSuspectedTotal.WithLabelValues(key.Namespace, key.Name).Inc()
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("reconcile loop detection", func() {
+	var (
+		now time.Time
+		d   *loopDetector
+		key types.NamespacedName
+	)
+
+	BeforeEach(func() {
+		now = time.Date(2024, 9, 15, 12, 0, 0, 0, time.UTC)
+		d = &loopDetector{now: func() time.Time { return now }}
+		key = types.NamespacedName{Namespace: "prod", Name: "web-tls"}
+	})
+
+	It("trips when reconciles exceed the threshold within the window", func() {
+		for range 3 {
+			Expect(d.observe(key, 3, time.Minute)).To(BeFalse())
+			now = now.Add(time.Second)
+		}
+		Expect(d.observe(key, 3, time.Minute)).To(BeTrue())
+	})
+
+	It("restarts counting after tripping", func() {
+		for range 3 {
+			d.observe(key, 2, time.Minute)
+		}
+		Expect(d.observe(key, 2, time.Minute)).To(BeFalse())
+	})
+
+	It("ignores reconciles spread beyond the window", func() {
+		for range 10 {
+			Expect(d.observe(key, 3, time.Minute)).To(BeFalse())
+			now = now.Add(30 * time.Second)
+		}
+	})
+
+	It("counts a suspected loop for rapidly reconciling Secrets", func() {
+		key = types.NamespacedName{Namespace: "prod", Name: "looping-tls"}
+		r := &SecretReconciler{
+			Client:        fake.NewClientBuilder().WithObjects(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}).Build(),
+			Log:           logr.Discard(),
+			LoopThreshold: 5,
+			LoopWindow:    time.Minute,
+		}
+
+		for range 6 {
+			_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(testutil.ToFloat64(reconcileLoopSuspectedTotal.WithLabelValues("prod", "looping-tls"))).To(Equal(1.0))
+	})
+})
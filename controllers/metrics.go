@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// skippedAmazonIssuedTotal counts ACM lookups that matched an ACM-managed
+	// certificate, which cannot be re-imported and is therefore declined.
+	skippedAmazonIssuedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "certsync_skipped_amazon_issued_total",
+		Help: "Number of ACM certificate matches declined because the certificate is AMAZON_ISSUED.",
+	})
+
+	// expiringIntermediatesTotal counts synced chains with an intermediate
+	// that expires within ChainExpiryWarning.
+	expiringIntermediatesTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "certsync_expiring_intermediates_total",
+		Help: "Number of syncs whose chain contained an intermediate certificate close to expiry.",
+	})
+
+	// regionReachable reports per region whether the last sync could reach
+	// ACM, so regional AWS outages stand out from per-Secret failures.
+	regionReachable = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "certsync_region_reachable",
+		Help: "Whether the last sync reached ACM in the region (1) or failed to connect (0).",
+	}, []string{"region"})
+
+	// reconcileLoopSuspectedTotal counts Secrets tripping the reconcile loop
+	// detector, see LoopThreshold.
+	reconcileLoopSuspectedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "certsync_reconcile_loop_suspected_total",
+		Help: "Number of times a Secret reconciled more often than the loop threshold within the loop window.",
+	}, []string{"namespace", "name"})
+
+	// terminalErrorsTotal counts syncs ACM rejected as invalid that won't be
+	// retried until the Secret changes, see TerminalACMErrors.
+	terminalErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "certsync_terminal_errors_total",
+		Help: "Number of syncs ACM rejected as invalid that are not retried until the Secret changes.",
+	})
+
+	// importsTotal counts the outcome of syncing a certificate to a region:
+	// importResultImported, importResultUpdated, importResultSkipped or
+	// importResultFailed.
+	importsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "certsync_imports_total",
+		Help: "Number of certificate syncs to ACM by result and region.",
+	}, []string{"result", "region"})
+
+	// reconcileErrorsTotal counts reconciles that returned an error.
+	reconcileErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "certsync_reconcile_errors_total",
+		Help: "Number of Secret reconciles that failed.",
+	})
+
+	// certificateExpirySeconds reports, per synced Secret, when the earliest
+	// of its certificates in ACM expires.
+	certificateExpirySeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "certsync_certificate_expiry_seconds",
+		Help: "Expiry of the Secret's certificate in ACM, as seconds since the Unix epoch.",
+	}, []string{"namespace", "name", "domain"})
+
+	// secondsSinceLastSuccess reports how long ago each Secret last synced
+	// successfully, so alerts can fire on Secrets that stopped syncing.
+	secondsSinceLastSuccess = newSyncAgeCollector()
+)
+
+// Results of certsync_imports_total.
+const (
+	importResultImported = "imported"
+	importResultUpdated  = "updated"
+	importResultSkipped  = "skipped"
+	importResultFailed   = "failed"
+)
+
+func init() {
+	// Register custom metrics with the global controller-runtime registry so
+	// they are served on the manager's metrics endpoint.
+	metrics.Registry.MustRegister(
+		skippedAmazonIssuedTotal,
+		expiringIntermediatesTotal,
+		regionReachable,
+		reconcileLoopSuspectedTotal,
+		terminalErrorsTotal,
+		importsTotal,
+		reconcileErrorsTotal,
+		certificateExpirySeconds,
+		secondsSinceLastSuccess,
+	)
+}
+
+// syncAgeCollector exports certsync_seconds_since_last_success per Secret. The
+// value is computed at scrape time so it keeps growing while a Secret fails.
+type syncAgeCollector struct {
+	mu   sync.Mutex
+	last map[types.NamespacedName]time.Time
+	now  func() time.Time
+	desc *prometheus.Desc
+}
+
+func newSyncAgeCollector() *syncAgeCollector {
+	return &syncAgeCollector{
+		last: map[types.NamespacedName]time.Time{},
+		now:  time.Now,
+		desc: prometheus.NewDesc(
+			"certsync_seconds_since_last_success",
+			"Seconds since the Secret was last synced to ACM successfully.",
+			[]string{"namespace", "name"}, nil,
+		),
+	}
+}
+
+// markSuccess records a successful sync of key.
+func (c *syncAgeCollector) markSuccess(key types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.last[key] = c.now()
+}
+
+// forget drops the series for key, e.g. once the Secret is deleted.
+func (c *syncAgeCollector) forget(key types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.last, key)
+}
+
+func (c *syncAgeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *syncAgeCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	for key, last := range c.last {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, now.Sub(last).Seconds(), key.Namespace, key.Name)
+	}
+}
+
+// setCertificateExpiry reports notAfter as the expiry of key's certificate
+// for domain, replacing any series for an earlier domain of key. Beyond
+// domainLimit distinct domains, see domainLabels, the domain is reported as
+// otherDomain.
+func setCertificateExpiry(key types.NamespacedName, domain string, notAfter time.Time, domainLimit int) {
+	forgetCertificateExpiry(key)
+	domain = expiryDomains.label(key, domain, domainLimit)
+	certificateExpirySeconds.WithLabelValues(key.Namespace, key.Name, domain).Set(float64(notAfter.Unix()))
+}
+
+// forgetCertificateExpiry drops the expiry series of key.
+func forgetCertificateExpiry(key types.NamespacedName) {
+	expiryDomains.forget(key)
+	certificateExpirySeconds.DeletePartialMatch(prometheus.Labels{"namespace": key.Namespace, "name": key.Name})
+}
+
+// otherDomain is the domain label shared by the Secrets whose domain didn't
+// fit under the domain limit.
+const otherDomain = "__other__"
+
+// DefaultMetricsDomainLimit is how many distinct domains label per-domain
+// metrics by default.
+const DefaultMetricsDomainLimit = 1000
+
+// expiryDomains bounds the domain label of certificateExpirySeconds.
+var expiryDomains = &domainLabels{}
+
+// domainLabels hands out domain label values for the Secrets reporting a
+// per-domain metric. Up to limit distinct domains are reported as they are;
+// Secrets with any further domain share otherDomain until a domain is freed
+// by the last Secret reporting it. A limit of zero or less doesn't cap them.
+type domainLabels struct {
+	mu sync.Mutex
+	// bySecret is the label value handed to each Secret.
+	bySecret map[types.NamespacedName]string
+	// secrets counts the Secrets holding each domain.
+	secrets map[string]int
+}
+
+// label returns the domain label value for key's domain.
+func (d *domainLabels) label(key types.NamespacedName, domain string, limit int) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.bySecret == nil {
+		d.bySecret = map[types.NamespacedName]string{}
+		d.secrets = map[string]int{}
+	}
+	if current, ok := d.bySecret[key]; ok && current == domain {
+		return domain
+	}
+	d.release(key)
+
+	if limit > 0 && d.secrets[domain] == 0 && len(d.secrets) >= limit {
+		domain = otherDomain
+	}
+	d.bySecret[key] = domain
+	if domain != otherDomain {
+		d.secrets[domain]++
+	}
+	return domain
+}
+
+// forget frees the domain label held by key.
+func (d *domainLabels) forget(key types.NamespacedName) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.release(key)
+}
+
+func (d *domainLabels) release(key types.NamespacedName) {
+	domain, ok := d.bySecret[key]
+	if !ok {
+		return
+	}
+	delete(d.bySecret, key)
+	if domain == otherDomain {
+		return
+	}
+	if d.secrets[domain]--; d.secrets[domain] <= 0 {
+		delete(d.secrets, domain)
+	}
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("metrics", func() {
+	var (
+		ctx     context.Context
+		acmFake *fakeACM
+		r       *SecretReconciler
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		acmFake = newFakeACM()
+		r = &SecretReconciler{Log: logr.Discard()}
+	})
+
+	It("counts AMAZON_ISSUED matches that are declined", func() {
+		acmFake.add("example.com", &fakeCertificate{Detail: types.CertificateDetail{
+			Type:                    types.CertificateTypeAmazonIssued,
+			SubjectAlternativeNames: []string{"example.com"},
+		}})
+		before := testutil.ToFloat64(skippedAmazonIssuedTotal)
+
+		certificate, err := r.findSecretByDomain(ctx, acmFake, "example.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(certificate).To(BeNil())
+		Expect(testutil.ToFloat64(skippedAmazonIssuedTotal)).To(Equal(before + 1))
+	})
+
+	It("does not count AMAZON_ISSUED certificates for other domains", func() {
+		acmFake.add("other.com", &fakeCertificate{Detail: types.CertificateDetail{
+			Type:                    types.CertificateTypeAmazonIssued,
+			SubjectAlternativeNames: []string{"other.com"},
+		}})
+		before := testutil.ToFloat64(skippedAmazonIssuedTotal)
+
+		_, err := r.findSecretByDomain(ctx, acmFake, "example.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(testutil.ToFloat64(skippedAmazonIssuedTotal)).To(Equal(before))
+	})
+
+	Context("certsync_seconds_since_last_success", func() {
+		var (
+			collector *syncAgeCollector
+			now       time.Time
+			key       = k8stypes.NamespacedName{Namespace: "prod", Name: "web-tls"}
+		)
+
+		BeforeEach(func() {
+			now = time.Date(2024, 9, 15, 12, 0, 0, 0, time.UTC)
+			collector = newSyncAgeCollector()
+			collector.now = func() time.Time { return now }
+		})
+
+		expectAge := func(seconds string) {
+			expected := `
+# HELP certsync_seconds_since_last_success Seconds since the Secret was last synced to ACM successfully.
+# TYPE certsync_seconds_since_last_success gauge
+certsync_seconds_since_last_success{name="web-tls",namespace="prod"} ` + seconds + "\n"
+			Expect(testutil.CollectAndCompare(collector, strings.NewReader(expected))).To(Succeed())
+		}
+
+		It("resets on success and grows while stale", func() {
+			collector.markSuccess(key)
+			expectAge("0")
+
+			now = now.Add(90 * time.Second)
+			expectAge("90")
+
+			collector.markSuccess(key)
+			expectAge("0")
+		})
+
+		It("drops the series when the Secret is deleted", func() {
+			collector.markSuccess(key)
+			collector.forget(key)
+			Expect(testutil.CollectAndCount(collector)).To(Equal(0))
+		})
+	})
+
+	Context("sync outcomes", func() {
+		var (
+			secret *corev1.Secret
+			leaf   *testCert
+			req    reconcile.Request
+		)
+
+		BeforeEach(func() {
+			acmFake.region = "eu-west-1"
+			var intermediate *testCert
+			_, intermediate, leaf = newTestChain("example.com")
+			secret = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "metrics",
+					Name:      "web-tls",
+					Annotations: map[string]string{
+						"sync-to-acm":                 "true",
+						"cert-manager.io/common-name": "example.com",
+					},
+				},
+				Type: corev1.SecretTypeTLS,
+				Data: map[string][]byte{
+					corev1.TLSCertKey:       append(append([]byte{}, leaf.PEM...), intermediate.PEM...),
+					corev1.TLSPrivateKeyKey: leaf.keyPEM(),
+				},
+			}
+			r.ACM = acmFake
+			r.Client = fake.NewClientBuilder().WithObjects(secret).Build()
+			req = reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)}
+		})
+
+		It("exports the expiry and counts imports and skips", func() {
+			imported := testutil.ToFloat64(importsTotal.WithLabelValues(importResultImported, "eu-west-1"))
+			skipped := testutil.ToFloat64(importsTotal.WithLabelValues(importResultSkipped, "eu-west-1"))
+
+			for range 2 {
+				_, err := r.Reconcile(ctx, req)
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(testutil.ToFloat64(importsTotal.WithLabelValues(importResultImported, "eu-west-1"))).To(Equal(imported + 1))
+			Expect(testutil.ToFloat64(importsTotal.WithLabelValues(importResultSkipped, "eu-west-1"))).To(Equal(skipped + 1))
+
+			expiry := certificateExpirySeconds.WithLabelValues("metrics", "web-tls", "example.com")
+			Expect(testutil.ToFloat64(expiry)).To(Equal(float64(leaf.Cert.NotAfter.Unix())))
+
+			Expect(r.Delete(ctx, secret)).To(Succeed())
+			_, err := r.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			// Nothing left to delete once the Secret is gone
+			Expect(certificateExpirySeconds.DeleteLabelValues("metrics", "web-tls", "example.com")).To(BeFalse())
+		})
+
+		It("counts failed imports and reconciles", func() {
+			acmFake.importErrs = []error{errors.New("access denied")}
+			failed := testutil.ToFloat64(importsTotal.WithLabelValues(importResultFailed, "eu-west-1"))
+			errored := testutil.ToFloat64(reconcileErrorsTotal)
+
+			_, err := r.Reconcile(ctx, req)
+			Expect(err).To(HaveOccurred())
+			Expect(testutil.ToFloat64(importsTotal.WithLabelValues(importResultFailed, "eu-west-1"))).To(Equal(failed + 1))
+			Expect(testutil.ToFloat64(reconcileErrorsTotal)).To(Equal(errored + 1))
+		})
+	})
+})
+
+var _ = Describe("domainLabels", func() {
+	var d *domainLabels
+
+	BeforeEach(func() {
+		d = &domainLabels{}
+	})
+
+	secret := func(name string) k8stypes.NamespacedName {
+		return k8stypes.NamespacedName{Namespace: "prod", Name: name}
+	}
+
+	It("reports domains beyond the limit as __other__", func() {
+		Expect(d.label(secret("a"), "a.example.com", 2)).To(Equal("a.example.com"))
+		Expect(d.label(secret("b"), "b.example.com", 2)).To(Equal("b.example.com"))
+		Expect(d.label(secret("c"), "c.example.com", 2)).To(Equal(otherDomain))
+		// A domain already reported doesn't count again
+		Expect(d.label(secret("a2"), "a.example.com", 2)).To(Equal("a.example.com"))
+	})
+
+	It("frees a domain once no Secret reports it", func() {
+		d.label(secret("a"), "a.example.com", 1)
+		d.label(secret("a2"), "a.example.com", 1)
+		Expect(d.label(secret("b"), "b.example.com", 1)).To(Equal(otherDomain))
+
+		d.forget(secret("a"))
+		Expect(d.label(secret("c"), "c.example.com", 1)).To(Equal(otherDomain))
+		d.forget(secret("a2"))
+		Expect(d.label(secret("c"), "c.example.com", 1)).To(Equal("c.example.com"))
+	})
+
+	It("moves a Secret whose domain changed", func() {
+		d.label(secret("a"), "a.example.com", 1)
+		Expect(d.label(secret("a"), "b.example.com", 1)).To(Equal("b.example.com"))
+	})
+
+	It("doesn't cap domains without a limit", func() {
+		for i := range 10 {
+			domain := fmt.Sprintf("%d.example.com", i)
+			Expect(d.label(secret(domain), domain, 0)).To(Equal(domain))
+		}
+	})
+
+	It("caps the expiry gauge", func() {
+		notAfter := time.Now().Add(24 * time.Hour)
+		defer forgetCertificateExpiry(secret("capped-1"))
+		defer forgetCertificateExpiry(secret("capped-2"))
+		limit := len(expiryDomains.secrets) + 1
+
+		setCertificateExpiry(secret("capped-1"), "capped-1.example.com", notAfter, limit)
+		setCertificateExpiry(secret("capped-2"), "capped-2.example.com", notAfter, limit)
+		Expect(testutil.ToFloat64(certificateExpirySeconds.WithLabelValues("prod", "capped-1", "capped-1.example.com"))).To(Equal(float64(notAfter.Unix())))
+		Expect(testutil.ToFloat64(certificateExpirySeconds.WithLabelValues("prod", "capped-2", otherDomain))).To(Equal(float64(notAfter.Unix())))
+		Expect(certificateExpirySeconds.DeleteLabelValues("prod", "capped-2", "capped-2.example.com")).To(BeFalse())
+	})
+})
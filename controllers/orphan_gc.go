@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+// errNoClusterName is returned by the OrphanCollector without a ClusterName.
+var errNoClusterName = errors.New("refusing to collect orphaned certificates without a cluster name")
+
+// DefaultGCInterval is the default time between two OrphanCollector passes.
+const DefaultGCInterval = time.Hour
+
+// OrphanCollector periodically deletes ACM certificates imported from a
+// Secret that no longer exists or no longer asks to be synced, e.g. because
+// it was deleted before cleanup on delete was enabled. A certificate is only
+// considered when its clusterTagKey tag names the reconciler's ClusterName and
+// its secretTagKey tag names the Secret, and is never deleted while it is in
+// use. Without a ClusterName nothing is collected, as certificates of other
+// clusters sharing the account can't be told apart. Secrets outside the watched namespaces or the label
+// selector can't be checked and keep their certificates, as do Secrets that
+// are being deleted or are delete-protected.
+type OrphanCollector struct {
+	// Reconciler provides the client and the sync and selector settings.
+	Reconciler *SecretReconciler
+	// ACM is the client orphans are looked for and deleted through.
+	ACM awsclient.ACMAPI
+	// Interval is the time between two passes. Defaults to
+	// DefaultGCInterval.
+	Interval time.Duration
+	Log      logr.Logger
+}
+
+// Start collects orphans every Interval until ctx is cancelled. It
+// implements manager.Runnable.
+func (c *OrphanCollector) Start(ctx context.Context) error {
+	if c.Reconciler.ClusterName == "" {
+		return errNoClusterName
+	}
+	interval := c.Interval
+	if interval <= 0 {
+		interval = DefaultGCInterval
+	}
+	for {
+		if _, err := c.collect(ctx); err != nil && ctx.Err() == nil {
+			// The next pass retries whatever failed
+			c.Log.Error(err, "Failed to collect some orphaned ACM certificates")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// collect makes one pass over the imported certificates and returns the ARNs
+// of the orphans it deleted, with the errors of all failed checks and
+// deletes.
+func (c *OrphanCollector) collect(ctx context.Context) ([]string, error) {
+	var (
+		deleted []string
+		errs    []error
+	)
+	if c.Reconciler.ClusterName == "" {
+		return nil, errNoClusterName
+	}
+	err := c.Reconciler.forEachImported(ctx, c.ACM, func(summary types.CertificateSummary) (bool, error) {
+		certificateArn := aws.ToString(summary.CertificateArn)
+		collected, err := c.collectOne(ctx, certificateArn)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", certificateArn, err))
+		} else if collected {
+			deleted = append(deleted, certificateArn)
+		}
+		return false, nil
+	})
+	if err != nil {
+		return deleted, errors.Join(append(errs, err)...)
+	}
+
+	c.Log.Info("Collected orphaned ACM certificates", "deleted", len(deleted), "failed", len(errs))
+	return deleted, errors.Join(errs...)
+}
+
+// collectOne deletes the certificate if it is an orphan that isn't in use,
+// reporting whether it did.
+func (c *OrphanCollector) collectOne(ctx context.Context, certificateArn string) (bool, error) {
+	tags, err := c.ACM.ListTagsForCertificate(ctx, &acm.ListTagsForCertificateInput{CertificateArn: aws.String(certificateArn)})
+	if err != nil {
+		return false, err
+	}
+	var owner, cluster string
+	for _, tag := range tags.Tags {
+		switch aws.ToString(tag.Key) {
+		case secretTagKey:
+			owner = aws.ToString(tag.Value)
+		case clusterTagKey:
+			cluster = aws.ToString(tag.Value)
+		}
+	}
+	key, ok := parseSecretTag(owner)
+	if !ok || cluster != c.Reconciler.ClusterName {
+		// Not imported by cert-sync in this cluster
+		return false, nil
+	}
+
+	orphaned, err := c.orphaned(ctx, key)
+	if err != nil || !orphaned {
+		return false, err
+	}
+
+	log := c.Log.WithValues("secret", key, "certificateArn", certificateArn)
+	certificate, err := describeImported(ctx, c.ACM, certificateArn)
+	if err != nil || certificate == nil {
+		return false, err
+	}
+	if len(certificate.InUseBy) > 0 {
+		log.Info("Orphaned ACM certificate is still in use; leaving it in place", "inUseBy", certificate.InUseBy)
+		return false, nil
+	}
+
+	region := c.ACM.Options().Region
+	_, err = c.ACM.DeleteCertificate(ctx, &acm.DeleteCertificateInput{CertificateArn: aws.String(certificateArn)})
+	c.Reconciler.record(AuditEntry{
+		Action:         AuditActionDelete,
+		Secret:         key.String(),
+		Domain:         aws.ToString(certificate.DomainName),
+		CertificateArn: certificateArn,
+		Region:         region,
+	}, err)
+	if err != nil {
+		return false, err
+	}
+	if !c.Reconciler.DryRun {
+		if err := c.Reconciler.arnStore().Delete(ctx, ARNKey{Region: region, Secret: key}); err != nil {
+			return true, err
+		}
+	}
+
+	log.Info("Deleted orphaned ACM certificate")
+	return true, nil
+}
+
+// orphaned reports whether the Secret named key is gone or no longer asks to
+// be synced.
+func (c *OrphanCollector) orphaned(ctx context.Context, key k8stypes.NamespacedName) (bool, error) {
+	var secret corev1.Secret
+	if err := c.Reconciler.Get(ctx, key, &secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	switch {
+	case !secret.DeletionTimestamp.IsZero(),
+		secret.Annotations[deleteProtectionAnnotation] == "true",
+		!c.Reconciler.selected(&secret):
+		return false, nil
+	}
+	return !c.Reconciler.syncRequested(&secret), nil
+}
+
+// parseSecretTag parses the value of a secretTagKey tag.
+func parseSecretTag(value string) (k8stypes.NamespacedName, bool) {
+	namespace, name, ok := strings.Cut(value, "/")
+	if !ok || namespace == "" || name == "" {
+		return k8stypes.NamespacedName{}, false
+	}
+	return k8stypes.NamespacedName{Namespace: namespace, Name: name}, true
+}
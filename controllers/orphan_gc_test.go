@@ -0,0 +1,134 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("OrphanCollector", func() {
+	var acmFake *fakeACM
+
+	BeforeEach(func() {
+		acmFake = newFakeACM()
+	})
+
+	addFromCluster := func(cluster, owner string, inUseBy ...string) string {
+		cert := &fakeCertificate{Detail: types.CertificateDetail{Type: types.CertificateTypeImported, InUseBy: inUseBy}}
+		if owner != "" {
+			cert.Tags = append(cert.Tags, types.Tag{Key: aws.String(secretTagKey), Value: aws.String(owner)})
+		}
+		if cluster != "" {
+			cert.Tags = append(cert.Tags, types.Tag{Key: aws.String(clusterTagKey), Value: aws.String(cluster)})
+		}
+		return acmFake.add("example.com", cert)
+	}
+	addTagged := func(owner string, inUseBy ...string) string {
+		return addFromCluster("prod-east", owner, inUseBy...)
+	}
+
+	collect := func(objects ...client.Object) []string {
+		c := &OrphanCollector{
+			Reconciler: &SecretReconciler{Client: fake.NewClientBuilder().WithObjects(objects...).Build(), Log: logr.Discard(), ClusterName: "prod-east"},
+			ACM:        acmFake,
+			Log:        logr.Discard(),
+		}
+		deleted, err := c.collect(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		return deleted
+	}
+
+	secretWith := func(annotations map[string]string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web-tls", Annotations: annotations},
+			Type:       corev1.SecretTypeTLS,
+		}
+	}
+
+	It("deletes the certificate of a Secret that is gone", func() {
+		arn := addTagged("prod/web-tls")
+		Expect(collect()).To(ConsistOf(arn))
+		Expect(acmFake.certs).To(BeEmpty())
+	})
+
+	It("keeps the certificate of a Secret that is still synced", func() {
+		addTagged("prod/web-tls")
+		Expect(collect(secretWith(map[string]string{"sync-to-acm": "true"}))).To(BeEmpty())
+		Expect(acmFake.called("DeleteCertificate")).To(BeZero())
+		Expect(acmFake.certs).To(HaveLen(1))
+	})
+
+	It("deletes the certificate of a Secret that no longer asks to be synced", func() {
+		arn := addTagged("prod/web-tls")
+		Expect(collect(secretWith(nil))).To(ConsistOf(arn))
+	})
+
+	It("keeps the certificate of a delete-protected Secret", func() {
+		addTagged("prod/web-tls")
+		Expect(collect(secretWith(map[string]string{deleteProtectionAnnotation: "true"}))).To(BeEmpty())
+		Expect(acmFake.called("DeleteCertificate")).To(BeZero())
+	})
+
+	It("never deletes a certificate in use", func() {
+		addTagged("prod/web-tls", "arn:aws:elasticloadbalancing:us-east-1:123456789012:loadbalancer/app/web/1")
+		Expect(collect()).To(BeEmpty())
+		Expect(acmFake.called("DeleteCertificate")).To(BeZero())
+	})
+
+	It("ignores certificates not imported from a Secret", func() {
+		addTagged("")
+		Expect(collect()).To(BeEmpty())
+		Expect(acmFake.called("DeleteCertificate")).To(BeZero())
+	})
+
+	It("ignores certificates of other clusters", func() {
+		addFromCluster("prod-west", "prod/web-tls")
+		addFromCluster("", "prod/web-tls")
+		Expect(collect()).To(BeEmpty())
+		Expect(acmFake.called("DeleteCertificate")).To(BeZero())
+	})
+
+	It("refuses to run without a cluster name", func() {
+		addTagged("prod/web-tls")
+		c := &OrphanCollector{
+			Reconciler: &SecretReconciler{Client: fake.NewClientBuilder().Build(), Log: logr.Discard()},
+			ACM:        acmFake,
+			Log:        logr.Discard(),
+		}
+		_, err := c.collect(context.Background())
+		Expect(err).To(MatchError(errNoClusterName))
+		Expect(c.Start(context.Background())).To(MatchError(errNoClusterName))
+		Expect(acmFake.called("ListCertificates")).To(BeZero())
+	})
+
+	It("only deletes the orphans", func() {
+		orphan := addTagged("prod/old-tls")
+		addTagged("prod/web-tls")
+		Expect(collect(secretWith(map[string]string{"sync-to-acm": "true"}))).To(ConsistOf(orphan))
+		Expect(acmFake.certs).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,22 @@
+package controllers
+
+import (
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// pausedAnnotation, when "true", freezes the Secret's certificate in ACM: it
+// is neither imported, updated nor deleted, even when the Secret is deleted,
+// until the annotation is removed.
+const pausedAnnotation = "cert-sync.denyshubh.github.io/paused"
+
+// syncPaused reports whether secret is paused, logging it and recording a
+// SyncPaused event when it is.
+func (r *SecretReconciler) syncPaused(log logr.Logger, secret *corev1.Secret, action string) bool {
+	if secret.Annotations[pausedAnnotation] != "true" {
+		return false
+	}
+	log.Info("Secret is paused; leaving ACM certificate untouched", "skipped", action)
+	r.eventf(secret, corev1.EventTypeNormal, reasonSyncPaused, "Sync is paused; skipped %s", action)
+	return true
+}
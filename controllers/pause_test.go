@@ -0,0 +1,110 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Paused Secrets", func() {
+	var (
+		ctx      context.Context
+		acmFake  *fakeACM
+		recorder *record.FakeRecorder
+		secret   *corev1.Secret
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		acmFake = newFakeACM()
+		recorder = record.NewFakeRecorder(10)
+		_, intermediate, leaf := newTestChain("example.com")
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "prod",
+				Name:      "web-tls",
+				Annotations: map[string]string{
+					"sync-to-acm":                 "true",
+					"cert-manager.io/common-name": "example.com",
+					pausedAnnotation:              "true",
+				},
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       append(append([]byte{}, leaf.PEM...), intermediate.PEM...),
+				corev1.TLSPrivateKeyKey: leaf.keyPEM(),
+			},
+		}
+	})
+
+	reconcileSecret := func() client.Client {
+		k8s := fake.NewClientBuilder().WithObjects(secret).Build()
+		r := &SecretReconciler{Client: k8s, Log: logr.Discard(), ACM: acmFake, Recorder: recorder}
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+		Expect(err).NotTo(HaveOccurred())
+		return k8s
+	}
+
+	expectNoWrites := func() {
+		for _, op := range []string{"ImportCertificate", "DeleteCertificate", "AddTagsToCertificate", "RemoveTagsFromCertificate"} {
+			Expect(acmFake.called(op)).To(Equal(0), op)
+		}
+	}
+
+	It("doesn't import the certificate", func() {
+		reconcileSecret()
+		expectNoWrites()
+		Expect(acmFake.certs).To(BeEmpty())
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal " + reasonSyncPaused)))
+	})
+
+	It("keeps the ACM certificate when the Secret is deleted", func() {
+		acmFake.add("example.com", &fakeCertificate{
+			Detail: types.CertificateDetail{SubjectAlternativeNames: []string{"example.com"}},
+			Tags:   []types.Tag{{Key: aws.String(secretTagKey), Value: aws.String("prod/web-tls")}},
+		})
+		now := metav1.Now()
+		secret.Finalizers = []string{secretFinalizer}
+		secret.DeletionTimestamp = &now
+
+		k8s := reconcileSecret()
+		expectNoWrites()
+		Expect(acmFake.certs).To(HaveLen(1))
+		err := k8s.Get(ctx, client.ObjectKeyFromObject(secret), &corev1.Secret{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue(), "finalizer should be released")
+		Expect(recorder.Events).To(Receive(HavePrefix("Normal " + reasonSyncPaused)))
+	})
+
+	It("syncs again once unpaused", func() {
+		secret.Annotations[pausedAnnotation] = "false"
+		reconcileSecret()
+		Expect(acmFake.called("ImportCertificate")).To(Equal(1))
+	})
+})
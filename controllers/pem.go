@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// normalizePEM returns data rewritten so that pem.Decode finds every block in
+// it: CRLF and CR line endings become LF, a byte order mark or any other text
+// before the first block is dropped, and blocks run together on one line are
+// split. Text between blocks is left for pem.Decode to skip.
+func normalizePEM(data []byte) []byte {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	data = bytes.ReplaceAll(data, []byte("\r"), []byte("\n"))
+	if start := bytes.Index(data, []byte("-----BEGIN ")); start > 0 {
+		data = data[start:]
+	}
+	return bytes.ReplaceAll(data, []byte("----------BEGIN "), []byte("-----\n-----BEGIN "))
+}
+
+// certificateDERs returns the DER bytes of every CERTIFICATE block in data, in
+// order. Non-certificate blocks and surrounding text are ignored.
+func certificateDERs(data []byte) [][]byte {
+	var ders [][]byte
+	rest := normalizePEM(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return ders
+		}
+		if block.Type == "CERTIFICATE" {
+			ders = append(ders, block.Bytes)
+		}
+	}
+}
+
+// sameCertificateContent reports whether the leaf and chain stored in ACM are
+// the same certificates as leafPEM and chainPEM, ignoring PEM formatting.
+func sameCertificateContent(acmCertPEM, acmChainPEM string, leafPEM, chainPEM []byte) bool {
+	return equalDERs(certificateDERs([]byte(acmCertPEM)), certificateDERs(leafPEM)) &&
+		equalDERs(certificateDERs([]byte(acmChainPEM)), certificateDERs(chainPEM))
+}
+
+func equalDERs(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseLeafCertificate parses the leaf of the certificates in leafPEM, found
+// as orderBundle finds it, so a bundle in reverse or shuffled order still
+// yields its leaf.
+func parseLeafCertificate(leafPEM []byte) (*x509.Certificate, error) {
+	var blocks []*pem.Block
+	for _, der := range certificateDERs(leafPEM) {
+		blocks = append(blocks, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+	}
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("no certificates found in PEM data")
+	}
+	cert, err := x509.ParseCertificate(orderBundle(blocks)[0].Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse leaf certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// checkTrailingPEMData returns an error if data has non-whitespace bytes after
+// its last PEM block, which usually means the PEM was truncated or garbled.
+func checkTrailingPEMData(data []byte) error {
+	rest := data
+	for {
+		block, next := pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		rest = next
+	}
+
+	if trailing := bytes.TrimSpace(rest); len(trailing) > 0 {
+		return fmt.Errorf("found %d unexpected bytes after the last PEM block", len(trailing))
+	}
+	return nil
+}
+
+// expiringCertificates returns the certificates in chainPEM that expire
+// before deadline. Blocks that don't parse are ignored.
+func expiringCertificates(chainPEM []byte, deadline time.Time) []*x509.Certificate {
+	var expiring []*x509.Certificate
+	for _, der := range certificateDERs(chainPEM) {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			continue
+		}
+		if cert.NotAfter.Before(deadline) {
+			expiring = append(expiring, cert)
+		}
+	}
+	return expiring
+}
+
+// canonicalChain returns chainPEM in a canonical form: ordered from the
+// certificate that issued leaf towards the root, without duplicates, copies
+// of leaf or self-signed roots, and re-encoded without PEM headers. The same
+// chain therefore always yields the same bytes, whatever order or formatting
+// the Secret uses. Certificates that aren't part of the path from leaf are
+// kept after it in their original order.
+func canonicalChain(leaf *x509.Certificate, chainPEM []byte) ([]byte, error) {
+	var remaining []*x509.Certificate
+	seen := map[string]bool{string(leaf.Raw): true}
+	for _, der := range certificateDERs(chainPEM) {
+		if seen[string(der)] {
+			continue
+		}
+		seen[string(der)] = true
+
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse chain certificate: %w", err)
+		}
+		if isSelfSigned(cert) {
+			continue
+		}
+		remaining = append(remaining, cert)
+	}
+
+	var ordered []*x509.Certificate
+	for current := leaf; ; {
+		i := slices.IndexFunc(remaining, func(candidate *x509.Certificate) bool {
+			return current.CheckSignatureFrom(candidate) == nil
+		})
+		if i < 0 {
+			break
+		}
+		current = remaining[i]
+		ordered = append(ordered, current)
+		remaining = slices.Delete(remaining, i, i+1)
+	}
+	ordered = append(ordered, remaining...)
+
+	var canonical []byte
+	for _, cert := range ordered {
+		canonical = append(canonical, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+	return canonical, nil
+}
+
+// orderBundle returns the CERTIFICATE blocks of a bundle leaf first, then the
+// path from the leaf's issuer towards the root, then any other certificates
+// in their original order, without duplicates. The leaf is the first block
+// unless that block issued another certificate in the bundle, in which case
+// it is the only certificate that issued none of the others. Bundles that
+// don't parse or have no such single certificate are returned as they are.
+func orderBundle(blocks []*pem.Block) []*pem.Block {
+	if len(blocks) < 2 {
+		return blocks
+	}
+
+	var unique []*pem.Block
+	var certs []*x509.Certificate
+	seen := map[string]bool{}
+	for _, block := range blocks {
+		if seen[string(block.Bytes)] {
+			continue
+		}
+		seen[string(block.Bytes)] = true
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return blocks
+		}
+		unique = append(unique, block)
+		certs = append(certs, cert)
+	}
+
+	issuer := func(i int) bool {
+		return slices.ContainsFunc(certs, func(cert *x509.Certificate) bool { return cert != certs[i] && issuedBy(cert, certs[i]) })
+	}
+	leaf := 0
+	if issuer(0) {
+		leaf = -1
+		for i := range certs {
+			if issuer(i) {
+				continue
+			}
+			if leaf >= 0 {
+				return blocks
+			}
+			leaf = i
+		}
+		if leaf < 0 {
+			return blocks
+		}
+	}
+
+	ordered := []*pem.Block{unique[leaf]}
+	remaining := slices.Delete(slices.Clone(certs), leaf, leaf+1)
+	remainingBlocks := slices.Delete(slices.Clone(unique), leaf, leaf+1)
+	for current := certs[leaf]; ; {
+		i := slices.IndexFunc(remaining, func(candidate *x509.Certificate) bool { return issuedBy(current, candidate) })
+		if i < 0 {
+			break
+		}
+		current = remaining[i]
+		ordered = append(ordered, remainingBlocks[i])
+		remaining = slices.Delete(remaining, i, i+1)
+		remainingBlocks = slices.Delete(remainingBlocks, i, i+1)
+	}
+	return append(ordered, remainingBlocks...)
+}
+
+// issuedBy reports whether issuer signed cert.
+func issuedBy(cert, issuer *x509.Certificate) bool {
+	return bytes.Equal(cert.RawIssuer, issuer.RawSubject) && cert.CheckSignatureFrom(issuer) == nil
+}
+
+// isSelfSigned reports whether cert is a self-signed root.
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawSubject, cert.RawIssuer) && cert.CheckSignatureFrom(cert) == nil
+}
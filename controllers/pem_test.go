@@ -0,0 +1,107 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("sameCertificateContent", func() {
+	var leaf, intermediate, renewed *testCert
+
+	BeforeEach(func() {
+		_, intermediate, leaf = newTestChain("example.com")
+		renewed = newTestCert("example.com", intermediate, testCertOptions{DNSNames: []string{"example.com"}})
+	})
+
+	It("matches identical content near expiry regardless of line endings", func() {
+		acmLeaf := string(bytes.ReplaceAll(leaf.PEM, []byte("\n"), []byte("\r\n")))
+		Expect(sameCertificateContent(acmLeaf, string(intermediate.PEM), leaf.PEM, intermediate.PEM)).To(BeTrue())
+	})
+
+	It("does not match a reissued leaf", func() {
+		Expect(sameCertificateContent(string(leaf.PEM), string(intermediate.PEM), renewed.PEM, intermediate.PEM)).To(BeFalse())
+	})
+
+	It("does not match a different chain", func() {
+		Expect(sameCertificateContent(string(leaf.PEM), "", leaf.PEM, intermediate.PEM)).To(BeFalse())
+	})
+})
+
+var _ = Describe("checkTrailingPEMData", func() {
+	var leaf *testCert
+
+	BeforeEach(func() {
+		leaf = newTestCert("example.com", nil, testCertOptions{})
+	})
+
+	It("accepts PEM followed only by whitespace", func() {
+		Expect(checkTrailingPEMData(append(append([]byte{}, leaf.PEM...), "\n\r\n  \t"...))).To(Succeed())
+	})
+
+	It("detects garbage after the last block", func() {
+		Expect(checkTrailingPEMData(append(append([]byte{}, leaf.PEM...), "garbage"...))).NotTo(Succeed())
+	})
+
+	It("detects a truncated block", func() {
+		truncated := leaf.PEM[:len(leaf.PEM)-30]
+		Expect(checkTrailingPEMData(truncated)).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("canonicalChain", func() {
+	var root, intermediate, leaf, crossSigned *testCert
+	var canonical []byte
+
+	BeforeEach(func() {
+		root, intermediate, leaf = newTestChain("example.com")
+		crossSigned = newTestCert("Test Issuing CA", intermediate, testCertOptions{IsCA: true})
+		leaf = newTestCert("example.com", crossSigned, testCertOptions{DNSNames: []string{"example.com"}})
+		canonical = append(append([]byte{}, crossSigned.PEM...), intermediate.PEM...)
+	})
+
+	join := func(parts ...[]byte) []byte {
+		return bytes.Join(parts, nil)
+	}
+
+	DescribeTable("produces the same bytes for",
+		func(chain func() []byte) {
+			Expect(canonicalChain(leaf.Cert, chain())).To(Equal(canonical))
+		},
+		Entry("an already canonical chain", func() []byte { return join(crossSigned.PEM, intermediate.PEM) }),
+		Entry("a chain in root-to-leaf order", func() []byte { return join(intermediate.PEM, crossSigned.PEM) }),
+		Entry("a chain including the root", func() []byte { return join(crossSigned.PEM, intermediate.PEM, root.PEM) }),
+		Entry("a chain with duplicates and the leaf", func() []byte {
+			return join(leaf.PEM, intermediate.PEM, crossSigned.PEM, intermediate.PEM)
+		}),
+		Entry("a chain with CRLF line endings", func() []byte {
+			return bytes.ReplaceAll(join(intermediate.PEM, crossSigned.PEM), []byte("\n"), []byte("\r\n"))
+		}),
+	)
+
+	It("keeps unrelated certificates after the path", func() {
+		other := newTestCert("Unrelated CA", root, testCertOptions{IsCA: true})
+		Expect(canonicalChain(leaf.Cert, join(other.PEM, intermediate.PEM, crossSigned.PEM))).To(Equal(join(canonical, other.PEM)))
+	})
+
+	It("returns an empty chain for a root-only chain", func() {
+		Expect(canonicalChain(leaf.Cert, root.PEM)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// expiryPriorityHorizon is the remaining lifetime at which a certificate gets
+// the full enqueue delay. It matches the usual 90 day certificate lifetime.
+const expiryPriorityHorizon = 90 * 24 * time.Hour
+
+// expiryPriorityHandler enqueues newly seen Secrets with a delay that grows
+// with the remaining lifetime of their certificate. When the controller
+// starts, or many Secrets appear at once, the workqueue therefore hands out
+// the nearest-to-expiry Secrets first. Other events are enqueued immediately.
+type expiryPriorityHandler struct {
+	handler.EnqueueRequestForObject
+
+	// Window is the delay given to the longest-lived certificates.
+	Window time.Duration
+	now    func() time.Time
+}
+
+func (h *expiryPriorityHandler) Create(_ context.Context, evt event.CreateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	if evt.Object == nil {
+		return
+	}
+
+	now := time.Now
+	if h.now != nil {
+		now = h.now
+	}
+	secret, _ := evt.Object.(*corev1.Secret)
+	q.AddAfter(reconcile.Request{NamespacedName: client.ObjectKeyFromObject(evt.Object)}, expiryDelay(secret, now(), h.Window))
+}
+
+// expiryDelay maps the remaining lifetime of secret's certificate onto
+// [0, window]. Secrets without a parseable certificate go last.
+func expiryDelay(secret *corev1.Secret, now time.Time, window time.Duration) time.Duration {
+	if secret == nil {
+		return window
+	}
+	leaf, err := parseLeafCertificate(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		return window
+	}
+
+	remaining := leaf.NotAfter.Sub(now)
+	switch {
+	case remaining <= 0:
+		return 0
+	case remaining >= expiryPriorityHorizon:
+		return window
+	}
+	return time.Duration(float64(window) * float64(remaining) / float64(expiryPriorityHorizon))
+}
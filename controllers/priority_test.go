@@ -0,0 +1,78 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("expiry priority", func() {
+	var now time.Time
+
+	BeforeEach(func() {
+		now = time.Now()
+	})
+
+	secretExpiringIn := func(name string, d time.Duration) *corev1.Secret {
+		cert := newTestCert(name+".example.com", nil, testCertOptions{NotBefore: now.Add(-time.Hour), NotAfter: now.Add(d)})
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: name},
+			Type:       corev1.SecretTypeTLS,
+			Data:       map[string][]byte{corev1.TLSCertKey: cert.PEM},
+		}
+	}
+
+	It("scales the delay with the remaining lifetime", func() {
+		window := 10 * time.Second
+		Expect(expiryDelay(secretExpiringIn("expired", -time.Hour), now, window)).To(BeZero())
+		Expect(expiryDelay(secretExpiringIn("soon", 9*24*time.Hour), now, window)).To(BeNumerically("~", time.Second, 10*time.Millisecond))
+		Expect(expiryDelay(secretExpiringIn("later", 365*24*time.Hour), now, window)).To(Equal(window))
+		Expect(expiryDelay(&corev1.Secret{}, now, window)).To(Equal(window))
+	})
+
+	It("hands out the nearest-to-expiry Secrets first", func() {
+		q := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+		defer q.ShutDown()
+		h := &expiryPriorityHandler{Window: 300 * time.Millisecond, now: func() time.Time { return now }}
+
+		for _, secret := range []*corev1.Secret{
+			secretExpiringIn("long-lived", 80*24*time.Hour),
+			{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "opaque"}},
+			secretExpiringIn("at-risk", 2*24*time.Hour),
+			secretExpiringIn("mid", 40*24*time.Hour),
+		} {
+			h.Create(context.Background(), event.CreateEvent{Object: secret}, q)
+		}
+
+		var order []string
+		for range 4 {
+			req, _ := q.Get()
+			order = append(order, req.Name)
+			q.Done(req)
+		}
+		Expect(order).To(Equal([]string{"at-risk", "mid", "long-lived", "opaque"}))
+	})
+})
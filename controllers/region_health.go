@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// regionBackoffBase is the requeue delay after the first connectivity
+// failure in a region. It doubles with every further consecutive failure, up
+// to RegionBackoffMax.
+const regionBackoffBase = 15 * time.Second
+
+// isRegionUnreachable reports whether err means the ACM endpoint couldn't be
+// reached at all (DNS failure, failure to connect, or no answer within
+// ACMTimeout), as opposed to ACM rejecting the call. A connection dropped
+// mid-response, or the reconcile's own context expiring, says nothing about
+// the region.
+func isRegionUnreachable(err error) bool {
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	return errors.As(err, &dnsErr) || (errors.As(err, &opErr) && opErr.Op == "dial") || errors.Is(err, errACMTimeout)
+}
+
+// regionBackoff counts consecutive connectivity failures per region. Every
+// Secret synced to a region shares its count, since an outage affects them
+// all alike.
+type regionBackoff struct {
+	mu       sync.Mutex
+	failures map[string]int
+}
+
+// failed records a connectivity failure in region and returns how long to
+// wait before trying it again.
+func (b *regionBackoff) failed(region string, maxDelay time.Duration) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures == nil {
+		b.failures = map[string]int{}
+	}
+
+	delay := regionBackoffBase
+	for range b.failures[region] {
+		if delay >= maxDelay {
+			break
+		}
+		delay *= 2
+	}
+	b.failures[region]++
+	return min(delay, maxDelay)
+}
+
+// reset forgets the failures recorded for region.
+func (b *regionBackoff) reset(region string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.failures, region)
+}
+
+// regionUnreachable records that err kept the sync from reaching region. When
+// it is a connectivity failure and RegionBackoffMax is set, it returns the
+// delay to requeue the Secret after instead of failing the reconcile.
+func (r *SecretReconciler) regionUnreachable(log logr.Logger, region string, err error) (time.Duration, bool) {
+	if !isRegionUnreachable(err) {
+		return 0, false
+	}
+	regionReachable.WithLabelValues(region).Set(0)
+	if r.RegionBackoffMax <= 0 {
+		return 0, false
+	}
+
+	delay := r.regionBackoff.failed(region, r.RegionBackoffMax)
+	log.Error(err, "ACM region is unreachable; backing off", "retryAfter", delay)
+	return delay, true
+}
+
+// regionReached records a sync that reached region.
+func (r *SecretReconciler) regionReached(region string) {
+	regionReachable.WithLabelValues(region).Set(1)
+	r.regionBackoff.reset(region)
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/smithy-go"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+// unreachableACM fails the certificate lookups the way the SDK does when the
+// endpoint can't be dialled, until reachable is set.
+type unreachableACM struct {
+	*fakeACM
+	reachable bool
+}
+
+func (u *unreachableACM) dialError(operation string) error {
+	return &smithy.OperationError{
+		ServiceID:     "ACM",
+		OperationName: operation,
+		Err:           &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")},
+	}
+}
+
+func (u *unreachableACM) ListCertificates(ctx context.Context, params *acm.ListCertificatesInput, optFns ...func(*acm.Options)) (*acm.ListCertificatesOutput, error) {
+	if !u.reachable {
+		return nil, u.dialError("ListCertificates")
+	}
+	return u.fakeACM.ListCertificates(ctx, params, optFns...)
+}
+
+func (u *unreachableACM) DescribeCertificate(ctx context.Context, params *acm.DescribeCertificateInput, optFns ...func(*acm.Options)) (*acm.DescribeCertificateOutput, error) {
+	if !u.reachable {
+		return nil, u.dialError("DescribeCertificate")
+	}
+	return u.fakeACM.DescribeCertificate(ctx, params, optFns...)
+}
+
+var _ = Describe("region connectivity back-off", func() {
+	var (
+		ctx     context.Context
+		acmFake *unreachableACM
+		secret  *corev1.Secret
+		r       *SecretReconciler
+		req     reconcile.Request
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		acmFake = &unreachableACM{fakeACM: newFakeACM()}
+		acmFake.region = "eu-central-1"
+
+		_, intermediate, leaf := newTestChain("example.com")
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "prod",
+				Name:      "web-tls",
+				Annotations: map[string]string{
+					"sync-to-acm":                 "true",
+					"cert-manager.io/common-name": "example.com",
+				},
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       append(append([]byte{}, leaf.PEM...), intermediate.PEM...),
+				corev1.TLSPrivateKeyKey: leaf.keyPEM(),
+			},
+		}
+		r = &SecretReconciler{
+			Client:           fake.NewClientBuilder().WithObjects(secret).Build(),
+			Log:              logr.Discard(),
+			RegionBackoffMax: time.Minute,
+			NewACMClient: func(context.Context, awsclient.ClientKey) (awsclient.ACMAPI, error) {
+				return acmFake, nil
+			},
+		}
+		req = reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)}
+	})
+
+	It("tells connectivity failures from API errors", func() {
+		Expect(isRegionUnreachable(&smithy.OperationError{Err: &net.DNSError{Err: "no such host", Name: "acm.example"}})).To(BeTrue())
+		Expect(isRegionUnreachable(&smithy.GenericAPIError{Code: "ValidationException"})).To(BeFalse())
+		Expect(isRegionUnreachable(&smithy.OperationError{Err: &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}})).To(BeFalse())
+		Expect(isRegionUnreachable(&smithy.OperationError{Err: context.DeadlineExceeded})).To(BeFalse())
+		Expect(isRegionUnreachable(&smithy.OperationError{Err: context.Canceled})).To(BeFalse())
+	})
+
+	It("backs off exponentially while the region is unreachable", func() {
+		var delays []time.Duration
+		for range 4 {
+			result, err := r.Reconcile(ctx, req)
+			Expect(err).NotTo(HaveOccurred())
+			delays = append(delays, result.RequeueAfter)
+		}
+		Expect(delays).To(Equal([]time.Duration{15 * time.Second, 30 * time.Second, time.Minute, time.Minute}))
+		Expect(testutil.ToFloat64(regionReachable.WithLabelValues("eu-central-1"))).To(Equal(0.0))
+	})
+
+	It("resets once the region is reachable again", func() {
+		_, err := r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+
+		acmFake.reachable = true
+		_, err = r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acmFake.called("ImportCertificate")).To(Equal(1))
+		Expect(testutil.ToFloat64(regionReachable.WithLabelValues("eu-central-1"))).To(Equal(1.0))
+
+		acmFake.reachable = false
+		result, err := r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(15 * time.Second))
+	})
+
+	It("returns the error when disabled", func() {
+		r.RegionBackoffMax = 0
+		_, err := r.Reconcile(ctx, req)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+// regionsAnnotation lists the comma-separated AWS regions a Secret is synced
+// to. On a Namespace it sets the default for every Secret in it; an
+// annotation on the Secret itself takes precedence.
+const regionsAnnotation = "cert-sync.denyshubh.github.io/regions"
+
+// With CredentialAnnotations, profileAnnotation and roleARNAnnotation select
+// the shared configuration profile and the IAM role a Secret is synced with.
+const (
+	profileAnnotation = "cert-sync.denyshubh.github.io/aws-profile"
+	roleARNAnnotation = "cert-sync.denyshubh.github.io/role-arn"
+)
+
+// targetRegions returns the regions secret is synced to. An empty region
+// stands for the region of the default AWS configuration, which is used when
+// neither the Secret nor, with NamespaceRegions, its Namespace names any.
+func (r *SecretReconciler) targetRegions(ctx context.Context, secret *corev1.Secret) ([]string, error) {
+	if regions := parseRegions(secret.Annotations[regionsAnnotation]); len(regions) > 0 {
+		return regions, nil
+	}
+
+	if r.NamespaceRegions {
+		var namespace corev1.Namespace
+		err := r.Get(ctx, types.NamespacedName{Name: secret.Namespace}, &namespace)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		if regions := parseRegions(namespace.Annotations[regionsAnnotation]); len(regions) > 0 {
+			return regions, nil
+		}
+	}
+
+	return []string{""}, nil
+}
+
+// parseRegions splits a regions annotation, dropping blanks and duplicates.
+func parseRegions(value string) []string {
+	var regions []string
+	seen := map[string]bool{}
+	for _, region := range strings.Split(value, ",") {
+		region = strings.TrimSpace(region)
+		if region == "" || seen[region] {
+			continue
+		}
+		seen[region] = true
+		regions = append(regions, region)
+	}
+	return regions
+}
+
+// acmClientsFor returns an ACM client for each region secret is synced to,
+// using the credentials the Secret selects with CredentialAnnotations.
+func (r *SecretReconciler) acmClientsFor(ctx context.Context, secret *corev1.Secret) ([]awsclient.ACMAPI, error) {
+	regions, err := r.targetRegions(ctx, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	var base awsclient.ClientKey
+	if r.CredentialAnnotations {
+		base.Profile = secret.Annotations[profileAnnotation]
+		base.RoleARN = secret.Annotations[roleARNAnnotation]
+		if err := r.credentialsAllowed(base); err != nil {
+			r.eventf(secret, corev1.EventTypeWarning, reasonCredentialsRejected, "Not syncing: %s", err)
+			return nil, reconcile.TerminalError(err)
+		}
+	}
+
+	clients := make([]awsclient.ACMAPI, 0, len(regions))
+	for _, region := range regions {
+		key := base
+		key.Region = region
+		acmClient, err := r.acmClient(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if r.ACMTimeout > 0 {
+			acmClient = NewTimeoutACM(acmClient, r.ACMTimeout)
+		}
+		if r.DryRun {
+			acmClient = NewDryRunACM(acmClient, r.Log.WithValues("secret", client.ObjectKeyFromObject(secret)))
+		}
+		clients = append(clients, acmClient)
+	}
+	return clients, nil
+}
+
+// credentialsAllowed checks the profile and role a Secret selected against
+// AllowedProfiles and AllowedRoleARNs, so a Secret author can't have the
+// controller assume any role its own identity may.
+func (r *SecretReconciler) credentialsAllowed(key awsclient.ClientKey) error {
+	if key.Profile != "" && !matchesAny(r.AllowedProfiles, key.Profile) {
+		return fmt.Errorf("AWS profile %q is not allowed", key.Profile)
+	}
+	if key.RoleARN != "" && !matchesAny(r.AllowedRoleARNs, key.RoleARN) {
+		return fmt.Errorf("IAM role %q is not allowed", key.RoleARN)
+	}
+	return nil
+}
+
+// matchesAny reports whether value matches one of the path.Match patterns.
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// acmClient returns the ACM client for the credential context key. ACM
+// serves the default context when set; any other client comes from a pool
+// built with NewACMClient, so reconciles don't reload the AWS configuration
+// and credentials every time.
+func (r *SecretReconciler) acmClient(ctx context.Context, key awsclient.ClientKey) (awsclient.ACMAPI, error) {
+	if key == (awsclient.ClientKey{}) && r.ACM != nil {
+		return r.ACM, nil
+	}
+
+	newClient := r.NewACMClient
+	if newClient == nil {
+		newClient = awsclient.NewACMClientFor
+	}
+	return r.acmClients.get(ctx, key, r.ACMClientPoolSize, newClient)
+}
+
+// secretsInNamespace enqueues the synced Secrets of a Namespace whose
+// annotations changed, so they pick up a new default region list.
+func (r *SecretReconciler) secretsInNamespace() handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, namespace client.Object) []reconcile.Request {
+		var secrets corev1.SecretList
+		if err := r.List(ctx, &secrets, client.InNamespace(namespace.GetName())); err != nil {
+			r.Log.Error(err, "Failed to list Secrets for Namespace", "namespace", namespace.GetName())
+			return nil
+		}
+
+		var requests []reconcile.Request
+		for _, secret := range secrets.Items {
+			if r.syncRequested(&secret) && secret.Annotations[regionsAnnotation] == "" {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&secret)})
+			}
+		}
+		return requests
+	})
+}
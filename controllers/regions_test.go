@@ -0,0 +1,239 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+var _ = Describe("target regions", func() {
+	var (
+		ctx       context.Context
+		namespace *corev1.Namespace
+		secret    *corev1.Secret
+		regional  map[string]*fakeACM
+		r         *SecretReconciler
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		namespace = &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:        "prod",
+			Annotations: map[string]string{regionsAnnotation: "us-east-1, eu-west-1"},
+		}}
+
+		_, intermediate, leaf := newTestChain("example.com")
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "prod",
+				Name:      "web-tls",
+				Annotations: map[string]string{
+					"sync-to-acm":                 "true",
+					"cert-manager.io/common-name": "example.com",
+				},
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       append(append([]byte{}, leaf.PEM...), intermediate.PEM...),
+				corev1.TLSPrivateKeyKey: leaf.keyPEM(),
+			},
+		}
+
+		regional = map[string]*fakeACM{}
+		for _, region := range []string{"us-east-1", "eu-west-1", "ap-southeast-2"} {
+			regional[region] = newFakeACM()
+			regional[region].region = region
+		}
+		r = &SecretReconciler{
+			Log:              logr.Discard(),
+			NamespaceRegions: true,
+			NewACMClient: func(_ context.Context, key awsclient.ClientKey) (awsclient.ACMAPI, error) {
+				region := key.Region
+				if region == "" {
+					region = "us-east-1"
+				}
+				return regional[region], nil
+			},
+		}
+	})
+
+	build := func(objects ...client.Object) {
+		r.Client = fake.NewClientBuilder().WithObjects(objects...).Build()
+	}
+
+	It("inherits the regions of the Namespace", func() {
+		build(namespace, secret)
+		Expect(r.targetRegions(ctx, secret)).To(Equal([]string{"us-east-1", "eu-west-1"}))
+	})
+
+	It("prefers the regions on the Secret", func() {
+		secret.Annotations[regionsAnnotation] = "ap-southeast-2"
+		build(namespace, secret)
+		Expect(r.targetRegions(ctx, secret)).To(Equal([]string{"ap-southeast-2"}))
+	})
+
+	It("ignores the Namespace unless enabled", func() {
+		r.NamespaceRegions = false
+		build(namespace, secret)
+		Expect(r.targetRegions(ctx, secret)).To(Equal([]string{""}))
+	})
+
+	It("falls back to the default region", func() {
+		build(secret)
+		Expect(r.targetRegions(ctx, secret)).To(Equal([]string{""}))
+	})
+
+	It("imports into every inherited region", func() {
+		build(namespace, secret)
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(regional["us-east-1"].called("ImportCertificate")).To(Equal(1))
+		Expect(regional["eu-west-1"].called("ImportCertificate")).To(Equal(1))
+		Expect(regional["ap-southeast-2"].called("ImportCertificate")).To(Equal(0))
+	})
+
+	It("imports only into the regions on the Secret", func() {
+		secret.Annotations[regionsAnnotation] = "ap-southeast-2"
+		build(namespace, secret)
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(regional["ap-southeast-2"].called("ImportCertificate")).To(Equal(1))
+		Expect(regional["us-east-1"].called("ImportCertificate")).To(Equal(0))
+	})
+
+	It("re-applies missing tags in every region on update", func() {
+		secret.Annotations[regionsAnnotation] = "us-east-1,eu-west-1"
+		r.VerifyTags = true
+		build(secret)
+
+		soon := aws.Time(time.Now().Add(24 * time.Hour))
+		for _, region := range []string{"us-east-1", "eu-west-1"} {
+			regional[region].add("example.com", &fakeCertificate{
+				Detail: types.CertificateDetail{Type: types.CertificateTypeImported, SubjectAlternativeNames: []string{"example.com"}, NotAfter: soon},
+			})
+		}
+		// The eu-west-1 copy was imported without the identity tag
+		regional["us-east-1"].certs[0].Tags = []types.Tag{{Key: aws.String(secretTagKey), Value: aws.String("prod/web-tls")}}
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+		Expect(err).NotTo(HaveOccurred())
+		for _, region := range []string{"us-east-1", "eu-west-1"} {
+			Expect(regional[region].called("ImportCertificate")).To(Equal(1))
+			Expect(regional[region].tag(aws.ToString(regional[region].certs[0].Detail.CertificateArn), secretTagKey)).To(Equal("prod/web-tls"), region)
+		}
+		Expect(regional["us-east-1"].called("AddTagsToCertificate")).To(Equal(0))
+		Expect(regional["eu-west-1"].called("AddTagsToCertificate")).To(Equal(1))
+	})
+
+	It("builds each region's client only once", func() {
+		built := map[string]int{}
+		newClient := r.NewACMClient
+		r.NewACMClient = func(ctx context.Context, key awsclient.ClientKey) (awsclient.ACMAPI, error) {
+			region := key.Region
+			built[region]++
+			return newClient(ctx, key)
+		}
+		build(namespace, secret)
+
+		for range 3 {
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(built).To(Equal(map[string]int{"us-east-1": 1, "eu-west-1": 1}))
+	})
+
+	It("uses the shared client for the default region", func() {
+		r.NamespaceRegions = false
+		r.ACM = regional["ap-southeast-2"]
+		r.NewACMClient = nil
+		build(secret)
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(regional["ap-southeast-2"].called("ImportCertificate")).To(Equal(1))
+	})
+
+	It("tags each region with its last sync", func() {
+		r.LastSyncedTag = true
+		build(namespace, secret)
+		before := time.Now().Add(-time.Second)
+
+		for range 2 {
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+			Expect(err).NotTo(HaveOccurred())
+		}
+		for _, region := range []string{"us-east-1", "eu-west-1"} {
+			acmFake := regional[region]
+			Expect(acmFake.certs).To(HaveLen(1), region)
+			lastSynced, err := time.Parse(time.RFC3339, acmFake.tag(aws.ToString(acmFake.certs[0].Detail.CertificateArn), lastSyncedTagKey))
+			Expect(err).NotTo(HaveOccurred(), region)
+			Expect(lastSynced).To(BeTemporally(">=", before.Truncate(time.Second)))
+			Expect(acmFake.called("AddTagsToCertificate")).To(Equal(2), region)
+		}
+		Expect(regional["ap-southeast-2"].called("AddTagsToCertificate")).To(Equal(0))
+	})
+
+	It("records the ARN of every region on the Secret", func() {
+		build(namespace, secret)
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+		Expect(err).NotTo(HaveOccurred())
+
+		var stored corev1.Secret
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(secret), &stored)).To(Succeed())
+		for _, region := range []string{"us-east-1", "eu-west-1"} {
+			Expect(recordedArn(&stored, region)).To(Equal(aws.ToString(regional[region].certs[0].Detail.CertificateArn)), region)
+		}
+	})
+
+	It("syncs the other regions when one fails", func() {
+		regional["us-east-1"].importErrs = []error{errors.New("boom")}
+		build(namespace, secret)
+
+		_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+		Expect(err).To(MatchError(ContainSubstring(`region "us-east-1"`)))
+		Expect(regional["eu-west-1"].certs).To(HaveLen(1))
+
+		var stored corev1.Secret
+		Expect(r.Get(ctx, client.ObjectKeyFromObject(secret), &stored)).To(Succeed())
+		Expect(recordedArn(&stored, "eu-west-1")).NotTo(BeEmpty())
+		Expect(recordedArn(&stored, "us-east-1")).To(BeEmpty())
+
+		// The retry only imports into the failed region
+		_, err = r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(regional["us-east-1"].called("ImportCertificate")).To(Equal(2))
+		Expect(regional["eu-west-1"].called("ImportCertificate")).To(Equal(1))
+	})
+})
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// DefaultRenewBefore is how long before expiry a certificate in ACM is
+// replaced when RenewBefore is unset.
+const DefaultRenewBefore = 72 * time.Hour
+
+// renewBeforeAnnotation overrides RenewBefore for a single Secret, e.g.
+// "240h".
+const renewBeforeAnnotation = "cert-sync.denyshubh.github.io/renew-before"
+
+// renewBefore returns how long before expiry the certificate of secret is
+// replaced in ACM. An annotation that doesn't parse is logged and ignored.
+func (r *SecretReconciler) renewBefore(log logr.Logger, secret *corev1.Secret) time.Duration {
+	renewBefore := r.RenewBefore
+	if renewBefore <= 0 {
+		renewBefore = DefaultRenewBefore
+	}
+
+	value, ok := secret.Annotations[renewBeforeAnnotation]
+	if !ok {
+		return renewBefore
+	}
+	override, err := time.ParseDuration(value)
+	if err != nil || override <= 0 {
+		log.Info("Ignoring invalid renew-before annotation", "annotation", renewBeforeAnnotation, "value", value, "renewBefore", renewBefore)
+		return renewBefore
+	}
+	return override
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("renewBefore", func() {
+	secretWith := func(annotations map[string]string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}}
+	}
+
+	DescribeTable("picks the renewal threshold",
+		func(configured time.Duration, annotations map[string]string, expected time.Duration) {
+			r := &SecretReconciler{RenewBefore: configured}
+			Expect(r.renewBefore(logr.Discard(), secretWith(annotations))).To(Equal(expected))
+		},
+		Entry("defaults to 72h", time.Duration(0), nil, DefaultRenewBefore),
+		Entry("uses the configured threshold", 24*time.Hour, nil, 24*time.Hour),
+		Entry("prefers the annotation", 24*time.Hour, map[string]string{renewBeforeAnnotation: "240h"}, 240*time.Hour),
+		Entry("ignores an invalid annotation", 24*time.Hour, map[string]string{renewBeforeAnnotation: "ten days"}, 24*time.Hour),
+		Entry("ignores a negative annotation", time.Duration(0), map[string]string{renewBeforeAnnotation: "-1h"}, DefaultRenewBefore),
+	)
+
+	Context("when reconciling", func() {
+		var (
+			ctx     context.Context
+			secret  *corev1.Secret
+			acmFake *fakeACM
+			r       *SecretReconciler
+		)
+
+		BeforeEach(func() {
+			ctx = context.Background()
+			_, intermediate, leaf := newTestChain("example.com")
+			secret = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace: "prod",
+					Name:      "web-tls",
+					Annotations: map[string]string{
+						"sync-to-acm":                 "true",
+						"cert-manager.io/common-name": "example.com",
+					},
+				},
+				Type: corev1.SecretTypeTLS,
+				Data: map[string][]byte{
+					corev1.TLSCertKey:       append(append([]byte{}, leaf.PEM...), intermediate.PEM...),
+					corev1.TLSPrivateKeyKey: leaf.keyPEM(),
+				},
+			}
+
+			// The copy in ACM expires in 100 hours
+			acmFake = newFakeACM()
+			acmFake.add("example.com", &fakeCertificate{
+				Detail: types.CertificateDetail{
+					Type:                    types.CertificateTypeImported,
+					SubjectAlternativeNames: []string{"example.com"},
+					NotAfter:                aws.Time(time.Now().Add(100 * time.Hour)),
+				},
+			})
+			r = &SecretReconciler{Log: logr.Discard(), ACM: acmFake}
+		})
+
+		reconcileSecret := func() {
+			r.Client = fake.NewClientBuilder().WithObjects(secret).Build()
+			_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		It("keeps a certificate outside the default threshold", func() {
+			reconcileSecret()
+			Expect(acmFake.called("ImportCertificate")).To(Equal(0))
+		})
+
+		It("renews early when configured", func() {
+			r.RenewBefore = 120 * time.Hour
+			reconcileSecret()
+			Expect(acmFake.called("ImportCertificate")).To(Equal(1))
+		})
+
+		It("renews early when the Secret asks for it", func() {
+			secret.Annotations[renewBeforeAnnotation] = "240h"
+			reconcileSecret()
+			Expect(acmFake.called("ImportCertificate")).To(Equal(1))
+		})
+	})
+})
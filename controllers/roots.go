@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// LoadRootBundle reads the PEM-encoded root CAs in path into a pool for
+// SecretReconciler.RootCAs.
+func LoadRootBundle(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// verifyChain checks that leaf chains to one of RootCAs through the
+// intermediates in chainPEM. It always succeeds when RootCAs is unset.
+func (r *SecretReconciler) verifyChain(leaf *x509.Certificate, chainPEM []byte) error {
+	if r.RootCAs == nil {
+		return nil
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, der := range certificateDERs(chainPEM) {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return fmt.Errorf("failed to parse chain certificate: %w", err)
+		}
+		intermediates.AddCert(cert)
+	}
+
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         r.RootCAs,
+		Intermediates: intermediates,
+		// The usage is up to whatever consumes the certificate from ACM
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("pinned root bundle", func() {
+	var (
+		root, intermediate, leaf *testCert
+		bundlePath               string
+		r                        *SecretReconciler
+	)
+
+	BeforeEach(func() {
+		root, intermediate, leaf = newTestChain("internal.example.com")
+		bundlePath = filepath.Join(GinkgoT().TempDir(), "roots.pem")
+		Expect(os.WriteFile(bundlePath, root.PEM, 0o600)).To(Succeed())
+
+		pool, err := LoadRootBundle(bundlePath)
+		Expect(err).NotTo(HaveOccurred())
+		r = &SecretReconciler{RootCAs: pool}
+	})
+
+	It("accepts a chain that ends at a pinned root", func() {
+		Expect(r.verifyChain(leaf.Cert, intermediate.PEM)).To(Succeed())
+	})
+
+	It("accepts a chain that includes the pinned root itself", func() {
+		Expect(r.verifyChain(leaf.Cert, append(append([]byte{}, intermediate.PEM...), root.PEM...))).To(Succeed())
+	})
+
+	It("rejects a chain issued by another root", func() {
+		_, otherIntermediate, otherLeaf := newTestChain("internal.example.com")
+		Expect(r.verifyChain(otherLeaf.Cert, otherIntermediate.PEM)).NotTo(Succeed())
+	})
+
+	It("rejects a leaf whose intermediate is missing", func() {
+		Expect(r.verifyChain(leaf.Cert, nil)).NotTo(Succeed())
+	})
+
+	It("skips verification without a bundle", func() {
+		r.RootCAs = nil
+		_, _, otherLeaf := newTestChain("internal.example.com")
+		Expect(r.verifyChain(otherLeaf.Cert, nil)).To(Succeed())
+	})
+
+	It("refuses a bundle without certificates", func() {
+		Expect(os.WriteFile(bundlePath, []byte("not a bundle"), 0o600)).To(Succeed())
+		_, err := LoadRootBundle(bundlePath)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RunOnceReport is the outcome of RunOnce.
+type RunOnceReport struct {
+	// Synced lists the Secrets that reconciled without error.
+	Synced []types.NamespacedName
+	// Failed holds the error each failing Secret reconciled with.
+	Failed map[types.NamespacedName]error
+}
+
+// ExitCode returns the process exit code for the report: 0 when every Secret
+// synced, 1 otherwise.
+func (rep RunOnceReport) ExitCode() int {
+	if len(rep.Failed) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// RunOnce reconciles every Secret annotated for sync once, outside the
+// controller loop, for running cert-sync as a Job. It only returns an error
+// when the Secrets can't be listed; per-Secret failures are in the report.
+func (r *SecretReconciler) RunOnce(ctx context.Context) (RunOnceReport, error) {
+	report := RunOnceReport{Failed: map[types.NamespacedName]error{}}
+
+	var secrets corev1.SecretList
+	if err := r.List(ctx, &secrets); err != nil {
+		return report, err
+	}
+
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if !r.syncRequested(secret) || !r.selected(secret) || secret.Type != corev1.SecretTypeTLS {
+			continue
+		}
+
+		key := client.ObjectKeyFromObject(secret)
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+			report.Failed[key] = err
+			continue
+		}
+		report.Synced = append(report.Synced, key)
+	}
+
+	r.Log.Info("Run complete", "synced", len(report.Synced), "failed", len(report.Failed))
+	return report, nil
+}
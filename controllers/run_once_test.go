@@ -0,0 +1,116 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+var _ = Describe("RunOnce", func() {
+	var (
+		ctx     context.Context
+		acmFake *fakeACM
+		r       *SecretReconciler
+	)
+
+	tlsSecret := func(name, domain string, annotations map[string]string) *corev1.Secret {
+		_, intermediate, leaf := newTestChain(domain)
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "prod",
+				Name:      name,
+				Annotations: map[string]string{
+					"sync-to-acm":                 "true",
+					"cert-manager.io/common-name": domain,
+				},
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       append(append([]byte{}, leaf.PEM...), intermediate.PEM...),
+				corev1.TLSPrivateKeyKey: leaf.keyPEM(),
+			},
+		}
+		for k, v := range annotations {
+			secret.Annotations[k] = v
+		}
+		return secret
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		acmFake = newFakeACM()
+		r = &SecretReconciler{
+			Log: logr.Discard(),
+			NewACMClient: func(_ context.Context, key awsclient.ClientKey) (awsclient.ACMAPI, error) {
+				region := key.Region
+				if region == "nowhere-1" {
+					return nil, errors.New("unknown region")
+				}
+				return acmFake, nil
+			},
+		}
+	})
+
+	It("syncs every annotated Secret and exits zero", func() {
+		ignored := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "opaque"}}
+		r.Client = fake.NewClientBuilder().WithObjects(
+			tlsSecret("web-tls", "example.com", nil),
+			tlsSecret("api-tls", "api.example.com", nil),
+			ignored,
+		).Build()
+
+		report, err := r.RunOnce(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Synced).To(HaveLen(2))
+		Expect(report.Failed).To(BeEmpty())
+		Expect(report.ExitCode()).To(Equal(0))
+		Expect(acmFake.called("ImportCertificate")).To(Equal(2))
+	})
+
+	It("reports failed Secrets and exits nonzero", func() {
+		failing := tlsSecret("api-tls", "api.example.com", map[string]string{regionsAnnotation: "nowhere-1"})
+		r.Client = fake.NewClientBuilder().WithObjects(tlsSecret("web-tls", "example.com", nil), failing).Build()
+
+		report, err := r.RunOnce(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Synced).To(ConsistOf(client.ObjectKey{Namespace: "prod", Name: "web-tls"}))
+		Expect(report.Failed).To(HaveKey(client.ObjectKeyFromObject(failing)))
+		Expect(report.ExitCode()).To(Equal(1))
+	})
+
+	It("reports Secrets whose import failed while backing off", func() {
+		r.Client = fake.NewClientBuilder().WithObjects(tlsSecret("web-tls", "example.com", nil)).Build()
+		acmFake.importErrs = []error{errors.New("quota exceeded")}
+
+		report, err := r.RunOnce(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Synced).To(BeEmpty())
+		Expect(report.Failed).To(HaveKey(client.ObjectKey{Namespace: "prod", Name: "web-tls"}))
+		Expect(report.ExitCode()).To(Equal(1))
+	})
+})
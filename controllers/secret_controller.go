@@ -1,9 +1,13 @@
 package controllers
 
 import (
+	"bytes"
 	"context"
+	"crypto/x509"
 	"encoding/pem"
 	"fmt"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -12,49 +16,461 @@ import (
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
 )
 
+// EmptyChainPolicy controls how a Secret whose tls.crt holds only the leaf
+// certificate (no intermediates) is handled.
+type EmptyChainPolicy string
+
+const (
+	// EmptyChainWarn logs a warning and imports the leaf without a chain.
+	EmptyChainWarn EmptyChainPolicy = "warn"
+	// EmptyChainFail refuses to import a leaf without a chain.
+	EmptyChainFail EmptyChainPolicy = "fail"
+	// EmptyChainProceed silently imports the leaf without a chain.
+	EmptyChainProceed EmptyChainPolicy = "proceed"
+)
+
+// ParseEmptyChainPolicy validates s as an EmptyChainPolicy.
+func ParseEmptyChainPolicy(s string) (EmptyChainPolicy, error) {
+	switch p := EmptyChainPolicy(s); p {
+	case EmptyChainWarn, EmptyChainFail, EmptyChainProceed:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid empty chain policy %q: must be one of warn, fail, proceed", s)
+}
+
+// expiredRecheckInterval is how often a Secret holding an expired certificate
+// is looked at again while waiting for cert-manager to renew it.
+const expiredRecheckInterval = time.Hour
+
 // SecretReconciler reconciles a Secret Object
 type SecretReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 	Log    logr.Logger
+
+	// Audit receives an entry for every mutating ACM call. Optional.
+	Audit *AuditLogger
+
+	// Summary tallies mutating ACM calls for a report on shutdown. Optional.
+	Summary *SessionSummary
+
+	// Recorder records events on synced Secrets. SetupWithManager sets it
+	// when unset.
+	Recorder record.EventRecorder
+
+	// SyncAnnotation is the annotation key that opts a Secret into syncing.
+	// Defaults to DefaultSyncAnnotation.
+	SyncAnnotation string
+
+	// AllowPinnedARNs lets arnAnnotation target imported certificates that
+	// don't carry the Secret's identity tag.
+	AllowPinnedARNs bool
+
+	// ClusterName, when set, is tagged on imported certificates so the
+	// certificates of clusters sharing an AWS account can be told apart. The
+	// OrphanCollector requires it.
+	ClusterName string
+
+	// SecretSelector restricts syncing to Secrets whose labels match it.
+	// Nil selects every Secret.
+	SecretSelector labels.Selector
+
+	// DomainAnnotations lists the annotation keys the domain is read from, in
+	// order of precedence. Defaults to DefaultDomainAnnotations. Without any
+	// of them, the domain comes from the certificate itself.
+	DomainAnnotations []string
+
+	// CleanupOnDelete deletes the imported ACM certificate when its Secret is
+	// deleted, using a finalizer.
+	CleanupOnDelete bool
+
+	// OnInUse controls whether a deleted Secret waits for its ACM
+	// certificate to be detached so it can be cleaned up. Defaults to
+	// InUseOrphan.
+	OnInUse InUsePolicy
+
+	// ImportStaged tags newly imported certificates as staged so they aren't
+	// wired to infrastructure until promoted (see promoteAnnotation).
+	ImportStaged bool
+
+	// ReuseTaggedCertificates searches ACM for a certificate carrying the
+	// Secret's identity tag before importing a new one, so a region that
+	// already holds the Secret's certificate keeps its ARN.
+	ReuseTaggedCertificates bool
+
+	// LastSyncedTag tags the certificate in each region with the time it was
+	// last synced (see lastSyncedTagKey), so a lagging region stands out.
+	LastSyncedTag bool
+
+	// VerifyTags re-applies tags missing from a certificate after it is
+	// updated, keeping its tags the same in every region.
+	VerifyTags bool
+
+	// AnnotationTags tags certificates from the Secret's annotations
+	// prefixed with tagAnnotationPrefix, and keeps those tags in sync on
+	// every reconcile.
+	AnnotationTags bool
+
+	// PruneStaleTags removes tags no longer produced for the Secret when its
+	// certificate is re-imported.
+	PruneStaleTags bool
+
+	// ScanThrottleRetries is how many times a throttled ListCertificates or
+	// DescribeCertificate call is retried during a domain scan before the
+	// reconcile fails.
+	ScanThrottleRetries int
+
+	// ImportThrottleRetries is how many times a throttled ImportCertificate
+	// call is retried before the reconcile fails.
+	ImportThrottleRetries int
+
+	// ScanThrottleMaxDelay caps the back-off between retries of throttled
+	// calls. Defaults to 5s.
+	ScanThrottleMaxDelay time.Duration
+
+	// ConsolidateDuplicates deletes duplicate ACM certificates holding the
+	// Secret's content, keeping one. See consolidateDuplicates.
+	ConsolidateDuplicates bool
+
+	// ContentHashTags tags imported certificates with a hash of their leaf
+	// and chain (see contentHashTagKey) and uses it to tell whether ACM is up
+	// to date without fetching the certificate.
+	ContentHashTags bool
+
+	// AdoptIdentical tags an untagged ACM certificate that already matches
+	// the Secret on the first reconcile after start, instead of re-importing
+	// it.
+	AdoptIdentical bool
+
+	// MaxTags caps the number of tags applied to an ACM certificate. Built-in
+	// tags are always kept; custom tags over the limit are dropped. Defaults
+	// to the ACM limit of 50.
+	MaxTags int
+
+	// ImportLimiter bounds concurrent ImportCertificate calls. Optional.
+	ImportLimiter *ImportLimiter
+
+	// SyncLimiter caps how many Secrets sync at once. Optional.
+	SyncLimiter *SyncLimiter
+
+	// MaxConcurrentReconciles is the number of reconcile workers. Defaults
+	// to one. Secrets claiming the same domain still sync one at a time.
+	MaxConcurrentReconciles int
+
+	// DomainValidator, when set, must accept the domain before it is synced.
+	DomainValidator *DomainValidator
+
+	// MinNotBefore, when set, skips Secrets whose leaf certificate was issued
+	// before it.
+	MinNotBefore time.Time
+
+	// MirrorACMErrors records ACM validation errors on the Secret (see
+	// acmErrorAnnotation).
+	MirrorACMErrors bool
+
+	// FieldManager is the field manager used for writes to Secrets. Defaults
+	// to DefaultFieldManager.
+	FieldManager string
+
+	// MinRemainingValidity, when positive, skips Secrets whose leaf
+	// certificate expires within it, leaving cert-manager to reissue it first.
+	MinRemainingValidity time.Duration
+
+	// KeyPassphraseField is the Secret field holding the passphrase of an
+	// encrypted PKCS#8 tls.key. Defaults to DefaultKeyPassphraseField.
+	KeyPassphraseField string
+
+	// DryRun logs and records events for the ACM imports, updates, deletions
+	// and tag changes the controller would make instead of making them.
+	// Reads still reach ACM so the logged operations are accurate. Secrets
+	// aren't written to either, except to release the finalizer of one that
+	// is being deleted.
+	DryRun bool
+
+	// ACMTimeout bounds each ACM call; a call that takes longer fails with
+	// context.DeadlineExceeded and the Secret is retried. Zero leaves calls
+	// bounded only by the reconcile's context.
+	ACMTimeout time.Duration
+
+	// AllowExpired imports certificates that have already expired instead
+	// of skipping them. Meant for testing.
+	AllowExpired bool
+
+	// RenewBefore is how long before its expiry the certificate in ACM is
+	// replaced. Defaults to DefaultRenewBefore; Secrets can override it with
+	// renewBeforeAnnotation.
+	RenewBefore time.Duration
+
+	// LenientAnnotations accepts common spellings of true ("True", "1",
+	// "yes") on sync-to-acm and warns once about Secrets whose annotations
+	// look misspelled.
+	LenientAnnotations bool
+
+	// TerminalACMErrors stops retrying a Secret whose certificate ACM rejected
+	// as invalid until the certificate or key in the Secret change, instead
+	// of requeueing it every few minutes.
+	TerminalACMErrors bool
+
+	// PreferInUse picks, among several ACM certificates matching the domain,
+	// one attached to AWS resources (InUseBy) over detached duplicates, so
+	// the copy serving traffic is the one kept up to date.
+	PreferInUse bool
+
+	// SourceVersionTags tags certificates with the UID and resourceVersion of
+	// their Secret (see secretVersionTagKey), so an unchanged Secret is
+	// recognised without comparing content.
+	SourceVersionTags bool
+
+	// WaitForCacheSync requeues reconciles that run before the Secret cache
+	// has synced instead of reading a cache that may not hold the Secret yet.
+	WaitForCacheSync bool
+
+	// AnnotateNotAfter records the ACM certificate's expiry on the Secret (see
+	// notAfterAnnotation) so it can be read without ACM access.
+	AnnotateNotAfter bool
+
+	// NormalizeKeyToPKCS8 re-encodes PKCS#1 and SEC1 private keys as PKCS#8
+	// before import.
+	NormalizeKeyToPKCS8 bool
+
+	// SplitCombinedPEM accepts Secrets whose tls.crt or tls.key holds both
+	// the certificates and the private key, importing each part separately.
+	SplitCombinedPEM bool
+
+	// StrictPEM rejects Secrets with non-whitespace data after the last PEM
+	// block instead of silently ignoring it.
+	StrictPEM bool
+
+	// MetricsDomainLimit caps how many distinct domains label per-domain
+	// metrics; Secrets with further domains are reported under "__other__".
+	// Zero or less doesn't cap them.
+	MetricsDomainLimit int
+
+	// ChainExpiryWarning, when positive, warns about intermediates expiring
+	// within this window.
+	ChainExpiryWarning time.Duration
+
+	// RootCAs, when set, pins the roots a Secret's chain must verify against
+	// before it is imported, for private CAs.
+	RootCAs *x509.CertPool
+
+	// CanonicalizeChain reorders and cleans up the chain before it is
+	// imported or compared (see canonicalChain), so the same certificates
+	// always produce the same ACM chain.
+	CanonicalizeChain bool
+
+	// RepairChainOnError retries an import ACM rejects because of its chain
+	// once with the chain reordered, leaving chains that import fine alone.
+	RepairChainOnError bool
+
+	// KeyUsagePolicy decides what to do with a leaf certificate whose key
+	// usage doesn't allow TLS server auth. Defaults to KeyUsageIgnore.
+	KeyUsagePolicy KeyUsagePolicy
+
+	// SupersetMatch decides whether an ACM certificate covering more domains
+	// than the Secret may be updated from it. Defaults to SupersetMatchAccept.
+	SupersetMatch SupersetMatchPolicy
+
+	// OnEmptyChain decides what to do with a leaf-only certificate. Defaults to
+	// EmptyChainWarn.
+	OnEmptyChain EmptyChainPolicy
+
+	// ExpiryPriorityWindow, when positive, spreads the initial enqueue of
+	// Secrets over this window ordered by certificate expiry, so the
+	// nearest-to-expiry Secrets sync first during a backlog.
+	ExpiryPriorityWindow time.Duration
+
+	// NamespaceRegions lets a Namespace set the default regions of its
+	// Secrets with regionsAnnotation.
+	NamespaceRegions bool
+
+	// RegionBackoffMax, when positive, requeues a Secret with an exponential
+	// back-off up to this delay when an ACM region can't be reached, instead
+	// of failing the reconcile.
+	RegionBackoffMax time.Duration
+
+	// ErrorBackoffMax caps the per-Secret exponential back-off after failed
+	// reconciles, reset by the next successful reconcile. Defaults to
+	// DefaultErrorBackoffMax.
+	ErrorBackoffMax time.Duration
+
+	// LoopThreshold, when positive, warns about a suspected reconcile loop
+	// when a Secret reconciles more than this many times within LoopWindow.
+	LoopThreshold int
+	LoopWindow    time.Duration
+
+	// ACM is the ACM client for the default region. Optional; built with
+	// NewACMClient on first use when nil.
+	ACM awsclient.ACMAPI
+
+	// NewACMClient builds the ACM client for a credential context. Defaults
+	// to awsclient.NewACMClientFor.
+	NewACMClient func(ctx context.Context, key awsclient.ClientKey) (awsclient.ACMAPI, error)
+
+	// CredentialAnnotations lets a Secret pick the AWS profile and IAM role
+	// it is synced with (see profileAnnotation and roleARNAnnotation).
+	CredentialAnnotations bool
+
+	// AllowedProfiles and AllowedRoleARNs list the path.Match patterns of
+	// the profiles and roles a Secret may select with CredentialAnnotations.
+	// A Secret naming any other isn't synced.
+	AllowedProfiles []string
+	AllowedRoleARNs []string
+
+	// ACMClientPoolSize caps how many ACM clients, one per credential
+	// context, are kept for reuse. Zero keeps them all.
+	ACMClientPoolSize int
+
+	// Triggers, when set, is an additional watch source used to force a
+	// reconcile of a named Secret (see AdminServer and ACMEventConsumer).
+	Triggers <-chan event.GenericEvent
+
+	// State counts reconciles for the StateReporter. Optional.
+	State *StateReporter
+
+	// Index records which Secret each synced ACM certificate belongs to.
+	// Optional.
+	Index *CertificateIndex
+
+	// ARNStore remembers the certificate each Secret was synced to per
+	// region. Defaults to an in-memory store.
+	ARNStore ARNStore
+
+	// sleepFn replaces the back-off sleep in tests.
+	sleepFn func(ctx context.Context, d time.Duration) error
+
+	// immutableLogged holds the immutable Secrets writeBack already logged.
+	immutableLogged sync.Map
+
+	// typosLogged holds the Secrets checkAnnotationTypos already warned about.
+	typosLogged sync.Map
+
+	// adoptChecked holds the Secrets adoptIdentical has already looked at.
+	adoptChecked sync.Map
+
+	// consolidateChecked holds the Secrets and regions consolidateDuplicates
+	// has already looked at.
+	consolidateChecked sync.Map
+
+	// regionBackoff counts connectivity failures per region.
+	regionBackoff regionBackoff
+
+	// loops spots Secrets that reconcile too often.
+	loops loopDetector
+
+	// acmClients pools the ACM client of each credential context; see
+	// acmClient.
+	acmClients acmClientPool
+
+	// terminalFailures holds the Secrets ACM rejected with TerminalACMErrors.
+	terminalFailures terminalFailures
+
+	// errorBackoff counts consecutive failed reconciles per Secret.
+	errorBackoff errorBackoff
+
+	// domainLocks serializes syncs of Secrets claiming the same domain.
+	domainLocks domainLocks
+
+	// cacheSynced reports whether the Secret cache has synced; see
+	// WaitForCacheSync.
+	cacheSynced func() bool
+
+	// defaultARNStore is used when ARNStore is unset.
+	defaultARNStore MemoryARNStore
 }
 
 // Reconcile is part of the main kubernetes reconciliation loop
 
-func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
 	log := r.Log.WithValues("secret", req.NamespacedName)
+	var secret corev1.Secret
+	defer func() {
+		if err != nil {
+			reconcileErrorsTotal.Inc()
+		}
+		result, err = r.backOff(log, req.NamespacedName, &secret, result, err)
+	}()
 	log.Info("Reconciling Secret")
-
-	// Initialize AWS ACM Client
-	acmClient, err := awsclient.NewACMClient(ctx)
-	if err != nil {
-		log.Error(err, "Failed to initialize AWS ACM Client")
-		return ctrl.Result{}, err
+	r.detectLoop(log, req.NamespacedName)
+	if r.awaitingCacheSync(log) {
+		return ctrl.Result{RequeueAfter: cacheSyncRecheckInterval}, nil
 	}
 
 	// Fetch the Secret Instance
-	var secret corev1.Secret
 	if err := r.Get(ctx, req.NamespacedName, &secret); err != nil {
 		if errors.IsNotFound(err) {
 			// Secret not found
+			secondsSinceLastSuccess.forget(req.NamespacedName)
+			forgetCertificateExpiry(req.NamespacedName)
+			r.loops.forget(req.NamespacedName)
+			r.terminalFailures.forget(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
+		if errors.IsConflict(err) || errors.IsResourceExpired(err) {
+			// The Secret changed underneath the read; try again with a fresh copy
+			log.Info("Secret changed while reading it; requeueing", "error", err.Error())
+			return ctrl.Result{Requeue: true}, nil
+		}
 		// Error reading the object
 		return ctrl.Result{}, err
 	}
 
+	// Clean up ACM before letting a synced Secret go
+	if !secret.DeletionTimestamp.IsZero() {
+		secondsSinceLastSuccess.forget(req.NamespacedName)
+		forgetCertificateExpiry(req.NamespacedName)
+		if controllerutil.ContainsFinalizer(&secret, secretFinalizer) {
+			// Don't delete a certificate a sync for the same domain is updating
+			if domain, _ := r.secretDomain(&secret); domain != "" {
+				defer r.domainLocks.lock(domain)()
+			}
+			acmClients, err := r.acmClientsFor(ctx, &secret)
+			if err != nil {
+				log.Error(err, "Failed to initialize AWS ACM Client")
+				return ctrl.Result{}, err
+			}
+			waiting, err := r.finalizeSecret(ctx, acmClients, &secret)
+			if err != nil {
+				log.Error(err, "Failed to clean up certificate in ACM")
+				return ctrl.Result{}, err
+			}
+			if waiting {
+				return ctrl.Result{RequeueAfter: inUseRecheckInterval}, nil
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
 	// Check if the secret has a sync annotation
-	if secret.Annotations["sync-to-acm"] != "true" {
+	r.checkAnnotationTypos(log, &secret)
+	if !r.syncRequested(&secret) {
 		// log.Info("Secret does not have sync-to-acm annotations; skipping")
 		return ctrl.Result{}, nil
 	}
+	if !r.selected(&secret) {
+		return ctrl.Result{}, nil
+	}
+	if r.syncPaused(log, &secret, "sync") {
+		return ctrl.Result{}, nil
+	}
 
 	// Check if Secret is of type TLS
 	if secret.Type != corev1.SecretTypeTLS {
@@ -62,81 +478,449 @@ func (r *SecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return ctrl.Result{}, nil
 	}
 
-	// Get the domain name from the annotation
-	domainName, exists := secret.Annotations["cert-manager.io/common-name"]
-	if !exists || domainName == "" {
-		// log.Info("Secret does not have cert-manager.io/common-name annotation; skipping")
+	// Get the domain name from the annotation, or else the certificate
+	domainName, domainSource := r.secretDomain(&secret)
+	if domainName == "" {
+		log.Info("Secret has no domain annotation and its certificate names no domain; skipping", "domainAnnotations", r.domainAnnotations())
 		return ctrl.Result{}, nil
 	}
+	log = log.WithValues("domain", domainName, "domainSource", domainSource)
+	if domainSource == domainSourceCommonName || domainSource == domainSourceDNSName {
+		log.Info("Secret has no domain annotation; using the domain named by its certificate")
+	}
 
-	// Find existing certificate in ACM
-	existingCertificate, err := r.findSecretByDomain(ctx, acmClient, domainName)
-	if err != nil {
-		log.Error(err, "Error finding certificate in ACM")
-		return ctrl.Result{RequeueAfter: 5 * time.Minute}, err
+	if r.DomainValidator != nil {
+		if err := r.DomainValidator.Validate(ctx, domainName); err != nil {
+			log.Error(err, "Domain failed validation; skipping")
+			return ctrl.Result{}, err
+		}
+	}
+
+	if !r.SyncLimiter.TryAcquire(req.NamespacedName) {
+		log.Info("Too many Secrets syncing; deferring")
+		return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
+	}
+	defer r.SyncLimiter.Release(req.NamespacedName)
+	defer r.domainLocks.lock(domainName)()
+
+	if err := r.ensureFinalizer(ctx, &secret); err != nil {
+		return ctrl.Result{}, err
 	}
 
 	// Extract the certificate and key
 	originalCrt := secret.Data[corev1.TLSCertKey]
 	key := secret.Data[corev1.TLSPrivateKeyKey]
+	if r.SplitCombinedPEM {
+		originalCrt, key = separateCombinedPEM(originalCrt, key)
+	}
+	if r.StrictPEM {
+		for field, data := range map[string][]byte{corev1.TLSCertKey: originalCrt, corev1.TLSPrivateKeyKey: key} {
+			if err := checkTrailingPEMData(data); err != nil {
+				log.Error(err, "Secret contains malformed PEM data; skipping", "field", field)
+				return ctrl.Result{}, nil
+			}
+		}
+	}
+	if isEncryptedKeyPEM(key) {
+		decrypted, err := r.decryptSecretKey(&secret, key)
+		if err != nil {
+			log.Error(err, "Secret contains a private key that can't be decrypted; skipping")
+			r.eventf(&secret, corev1.EventTypeWarning, reasonKeyDecryptFailed, "Not imported to ACM: %v", err)
+			return ctrl.Result{}, nil
+		}
+		key = decrypted
+	}
+	key, keyFormat, err := normalizePrivateKeyPEM(key, r.NormalizeKeyToPKCS8)
+	if err != nil {
+		log.Error(err, "Secret contains an invalid private key; skipping")
+		return ctrl.Result{}, nil
+	}
+	keyType, err := keyAlgorithm(key)
+	if err != nil {
+		log.Error(err, "Secret contains a private key ACM doesn't accept; skipping")
+		r.eventf(&secret, corev1.EventTypeWarning, reasonUnsupportedKey, "Not imported to ACM: %v", err)
+		return ctrl.Result{}, nil
+	}
+	log = log.WithValues("keyFormat", keyFormat, "keyType", keyType)
+
 	leafCert, chainCert, err := splitCertificateChain(originalCrt)
 	if err != nil {
-		return ctrl.Result{RequeueAfter: 5 * time.Minute}, err
+		return ctrl.Result{}, err
+	}
+	leaf, err := parseLeafCertificate(leafCert)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !leafCoversDomain(leaf, domainName) {
+		// A stale annotation left behind by a reissue would otherwise lead
+		// to the ACM certificate of another domain
+		log.Info("Certificate doesn't cover the annotated domain; skipping", "commonName", leaf.Subject.CommonName, "dnsNames", leaf.DNSNames)
+		r.eventf(&secret, corev1.EventTypeWarning, reasonDomainMismatch, "Not imported to ACM: certificate doesn't cover %s from %s", domainName, domainSource)
+		return ctrl.Result{}, nil
+	}
+	if err := checkKeyMatchesCertificate(key, leaf); err != nil {
+		log.Error(err, "Secret's private key doesn't belong to its certificate; skipping")
+		r.eventf(&secret, corev1.EventTypeWarning, reasonKeyMismatch, "Not imported to ACM: %v", err)
+		return ctrl.Result{}, nil
+	}
+	if r.DomainValidator != nil {
+		if err := r.DomainValidator.ValidateSANs(leaf); err != nil {
+			log.Error(err, "Certificate covers names outside the expected zones; skipping")
+			return ctrl.Result{}, nil
+		}
+	}
+	if r.CanonicalizeChain {
+		if chainCert, err = canonicalChain(leaf, chainCert); err != nil {
+			log.Error(err, "Secret contains an invalid certificate chain; skipping")
+			return ctrl.Result{}, nil
+		}
+	}
+	if r.predatesCutoff(leaf) {
+		log.Info("Certificate was issued before the --min-notbefore cutoff; skipping", "notBefore", leaf.NotBefore, "cutoff", r.MinNotBefore)
+		return ctrl.Result{}, nil
+	}
+	if r.hasExpired(leaf, time.Now()) {
+		log.Info("Certificate has already expired; skipping until it is renewed", "notAfter", leaf.NotAfter)
+		r.eventf(&secret, corev1.EventTypeWarning, reasonExpired, "Not imported to ACM: certificate expired at %s", leaf.NotAfter.UTC().Format(time.RFC3339))
+		return ctrl.Result{RequeueAfter: expiredRecheckInterval}, nil
+	}
+	if r.expiresTooSoon(leaf, time.Now()) {
+		// cert-manager reissues it shortly, which updates the Secret
+		log.Info("Certificate has less than the minimum remaining validity; skipping", "notAfter", leaf.NotAfter, "minRemainingValidity", r.MinRemainingValidity)
+		return ctrl.Result{}, nil
 	}
+	if err := r.checkEmptyChain(log, chainCert); err != nil {
+		log.Error(err, "Refusing to sync certificate without a chain")
+		return ctrl.Result{}, nil
+	}
+	if err := r.verifyChain(leaf, chainCert); err != nil {
+		log.Error(err, "Certificate doesn't chain to a pinned root CA; skipping")
+		return ctrl.Result{}, nil
+	}
+	if r.KeyUsagePolicy == KeyUsageWarn || r.KeyUsagePolicy == KeyUsageSkip {
+		if err := checkTLSKeyUsage(leaf); err != nil {
+			if r.KeyUsagePolicy == KeyUsageSkip {
+				log.Error(err, "Certificate can't be used for TLS; skipping")
+				return ctrl.Result{}, nil
+			}
+			log.Info("Certificate may not be usable for TLS", "reason", err.Error())
+		}
+	}
+	r.warnExpiringChain(log, &secret, chainCert)
 
-	if existingCertificate != nil {
-		log.Info("Found certificate in ACM", "CertificateArn: ", aws.ToString(existingCertificate.CertificateArn), "NotAfter: ", aws.ToTime(existingCertificate.NotAfter))
-		if existingCertificate.NotAfter != nil && existingCertificate.NotAfter.Before(time.Now().Add(72*time.Hour)) {
-			log.Info("Certificate exists in ACM and is going to expire; updating certificate")
+	material := &certificateMaterial{leaf: leaf, leafPEM: leafCert, chainPEM: chainCert, keyPEM: key}
+
+	if r.skipTerminalFailure(log, req.NamespacedName, &secret) {
+		return ctrl.Result{}, nil
+	}
+
+	acmClients, err := r.acmClientsFor(ctx, &secret)
+	if err != nil {
+		log.Error(err, "Failed to initialize AWS ACM Client")
+		return ctrl.Result{}, err
+	}
 
-			// Process to sync (import) the certificate
-			if err := r.updateToAcm(ctx, acmClient, &secret, existingCertificate.CertificateArn, leafCert, chainCert, key); err != nil {
-				log.Error(err, "Failed to sync certificate to ACM")
-				return ctrl.Result{RequeueAfter: 5 * time.Minute}, err
+	// The earliest expiry of the certificates held in ACM once this reconcile
+	// is done, and when to look at the Secret again
+	// A failing region doesn't hold up the others; its error is reported
+	// once every region has been tried
+	var acmNotAfter *time.Time
+	var regions, syncedRegions, syncedArns []string
+	var unrecorded []syncedCertificate
+	var errs []error
+	unreachable := false
+	requeueAfter := 24 * time.Hour
+	for _, acmClient := range acmClients {
+		region := acmClient.Options().Region
+		regions = append(regions, region)
+		regionLog := log.WithValues("region", region)
+		result, err := r.syncRegion(ctx, regionLog, acmClient, &secret, domainName, material)
+		if err != nil {
+			if delay, ok := r.regionUnreachable(regionLog, region, err); ok {
+				unreachable = true
+				requeueAfter = min(requeueAfter, delay)
+				continue
+			}
+			errs = append(errs, fmt.Errorf("region %q: %w", region, err))
+			continue
+		}
+		r.regionReached(region)
+		syncedArns = append(syncedArns, result.certificateArn)
+		// A dry run of a new import has no certificate to store or tag
+		if result.certificateArn != "" {
+			if !r.DryRun {
+				if err := r.arnStore().Set(ctx, ARNKey{Region: region, Secret: req.NamespacedName}, result.certificateArn); err != nil {
+					regionLog.Error(err, "Failed to store ACM certificate ARN")
+				}
 			}
+			if err := r.tagLastSynced(ctx, acmClient, result.certificateArn); err != nil {
+				regionLog.Error(err, "Failed to tag certificate with its last sync")
+				errs = append(errs, fmt.Errorf("region %q: %w", region, err))
+				continue
+			}
+		}
+		syncedRegions = append(syncedRegions, region)
+		if result.certificateArn != "" && !result.sourceRecorded {
+			unrecorded = append(unrecorded, syncedCertificate{acmClient: acmClient, certificateArn: result.certificateArn})
+		}
+		if result.notAfter != nil && (acmNotAfter == nil || result.notAfter.Before(*acmNotAfter)) {
+			acmNotAfter = result.notAfter
+		}
+		if result.requeueAfter > 0 && result.requeueAfter < requeueAfter {
+			requeueAfter = result.requeueAfter
+		}
+	}
 
-		} else {
-			log.Info("Certificate exists in ACM and is valid; skipping import")
+	if err := r.recordArns(ctx, &secret, regions, syncedArns); err != nil {
+		log.Error(err, "Failed to record ACM certificate ARNs on Secret")
+		return ctrl.Result{}, err
+	}
+	if err := r.recordForceReimport(ctx, &secret, syncedRegions); err != nil {
+		log.Error(err, "Failed to record processed force-reimport token on Secret")
+		return ctrl.Result{}, err
+	}
+	if len(errs) > 0 {
+		if r.TerminalACMErrors && !unreachable && allTerminal(errs) {
+			// Retrying won't help until the Secret changes, which triggers a
+			// reconcile of its own
+			r.terminalFailures.failed(req.NamespacedName, secretContent(&secret))
+			terminalErrorsTotal.Inc()
+			log.Info("Certificate was rejected by ACM; not retrying until the Secret changes")
+			return ctrl.Result{}, reconcile.TerminalError(utilerrors.NewAggregate(errs))
 		}
-	} else {
-		log.Info("Certificate does not exist in ACM; importing certificate")
+		return ctrl.Result{}, utilerrors.NewAggregate(errs)
+	}
+	if unreachable {
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
 
-		// Sync to ACM
-		if err := r.importToAcm(ctx, acmClient, &secret, leafCert, chainCert, key); err != nil {
+	if err := r.annotateNotAfter(ctx, &secret, acmNotAfter); err != nil {
+		log.Error(err, "Failed to annotate Secret with ACM expiry")
+		return ctrl.Result{}, err
+	}
+	if err := r.clearACMError(ctx, &secret); err != nil {
+		log.Error(err, "Failed to clear ACM error from Secret")
+		return ctrl.Result{}, err
+	}
+	if err := r.recordSourceVersion(ctx, &secret, unrecorded); err != nil {
+		log.Error(err, "Failed to tag certificates with the Secret's version")
+		return ctrl.Result{}, err
+	}
+
+	secondsSinceLastSuccess.markSuccess(req.NamespacedName)
+	if acmNotAfter != nil {
+		setCertificateExpiry(req.NamespacedName, domainName, *acmNotAfter, r.MetricsDomainLimit)
+	}
+	r.terminalFailures.forget(req.NamespacedName)
+	log.Info("Successfully synced certificate to ACM")
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// certificateMaterial is what gets imported into ACM for a Secret.
+type certificateMaterial struct {
+	leaf     *x509.Certificate
+	leafPEM  []byte
+	chainPEM []byte
+	keyPEM   []byte
+}
+
+// regionSync is the outcome of syncing a Secret to one region.
+type regionSync struct {
+	// certificateArn is the certificate the Secret is synced to.
+	certificateArn string
+	// notAfter is the expiry of the certificate held in ACM.
+	notAfter *time.Time
+	// requeueAfter, when set, asks for an earlier reconcile than usual.
+	requeueAfter time.Duration
+	// sourceRecorded is set when the certificate already records the
+	// current version of the Secret.
+	sourceRecorded bool
+}
+
+// syncRegion imports or updates the certificate for secret in the region of
+// acmClient.
+func (r *SecretReconciler) syncRegion(ctx context.Context, log logr.Logger, acmClient awsclient.ACMAPI, secret *corev1.Secret, domainName string, material *certificateMaterial) (regionSync, error) {
+	key := client.ObjectKeyFromObject(secret)
+	leafCert, chainCert := material.leafPEM, material.chainPEM
+
+	// Find existing certificate in ACM
+	existingCertificate, err := r.findCertificate(ctx, acmClient, secret, domainName)
+	if err != nil {
+		log.Error(err, "Error finding certificate in ACM")
+		return regionSync{}, err
+	}
+
+	if existingCertificate != nil && wildcardOnly(existingCertificate, domainName, material.leaf) {
+		// Re-importing the Secret's narrower leaf over the wildcard would break
+		// every other host behind it
+		log.Info("Domain is covered by a wildcard certificate in ACM that the Secret doesn't carry; leaving it in place", "certificateArn", aws.ToString(existingCertificate.CertificateArn))
+		importsTotal.WithLabelValues(importResultSkipped, acmClient.Options().Region).Inc()
+		r.eventf(secret, corev1.EventTypeNormal, reasonSkippedValid, "Domain %s is covered by wildcard certificate %s in %s; not importing", domainName, aws.ToString(existingCertificate.CertificateArn), acmClient.Options().Region)
+		return regionSync{}, nil
+	}
+
+	if existingCertificate != nil {
+		existingCertificate, err = r.consolidateDuplicates(ctx, acmClient, secret, domainName, existingCertificate, leafCert, chainCert)
+		if err != nil {
+			log.Error(err, "Failed to consolidate duplicate certificates in ACM")
+			return regionSync{}, err
+		}
+		r.Index.Set(aws.ToString(existingCertificate.CertificateArn), key)
+		if secret.Annotations[promoteAnnotation] == "true" {
+			if err := r.promote(ctx, acmClient, existingCertificate.CertificateArn); err != nil {
+				log.Error(err, "Failed to promote certificate in ACM")
+				return regionSync{}, err
+			}
+		}
+		log.Info("Found certificate in ACM", "certificateArn", aws.ToString(existingCertificate.CertificateArn), "notAfter", aws.ToTime(existingCertificate.NotAfter))
+		// An adopted certificate was just found to hold the Secret's content
+		adopted, err := r.adoptIdentical(ctx, acmClient, secret, existingCertificate.CertificateArn, leafCert, chainCert)
+		if err != nil {
+			log.Error(err, "Failed to adopt certificate in ACM")
+			return regionSync{}, err
+		}
+		switch {
+		case forceReimportRequested(secret, acmClient.Options().Region):
+			log.Info("Force re-import requested; updating certificate", "token", secret.Annotations[forceReimportAnnotation])
+		case existingCertificate.NotAfter == nil || !existingCertificate.NotAfter.Before(time.Now().Add(r.renewBefore(log, secret))):
+			changed, current := false, false
+			if !adopted {
+				changed, current, err = r.contentChanged(ctx, acmClient, existingCertificate, secret, material.leaf, leafCert, chainCert)
+				if err != nil {
+					log.Error(err, "Failed to compare certificate with ACM")
+					return regionSync{}, err
+				}
+			}
+			if !changed {
+				if err := r.syncAnnotationTags(ctx, acmClient, existingCertificate.CertificateArn, secret); err != nil {
+					log.Error(err, "Failed to sync ACM tags from annotations")
+					return regionSync{}, err
+				}
+				log.Info("Certificate exists in ACM and is valid; skipping import")
+				importsTotal.WithLabelValues(importResultSkipped, acmClient.Options().Region).Inc()
+				r.eventf(secret, corev1.EventTypeNormal, reasonSkippedValid, "Certificate %s in %s is valid and up to date", aws.ToString(existingCertificate.CertificateArn), acmClient.Options().Region)
+				return regionSync{certificateArn: aws.ToString(existingCertificate.CertificateArn), notAfter: existingCertificate.NotAfter, sourceRecorded: current}, nil
+			}
+			// Another Secret for the same domain, or another tool, may hold the
+			// certificate; overwriting it would make them flip-flop
+			owned, err := r.mayOverwrite(ctx, acmClient, existingCertificate, secret)
+			if err != nil {
+				log.Error(err, "Failed to check the owner of the certificate in ACM")
+				return regionSync{}, err
+			}
+			if !owned {
+				log.Info("Certificate in ACM differs from the Secret but wasn't imported from it; importing a new certificate", "certificateArn", aws.ToString(existingCertificate.CertificateArn))
+				return r.importNew(ctx, log, acmClient, secret, domainName, material)
+			}
+			log.Info("Certificate in ACM differs from the Secret; updating certificate")
+		default:
+			identical := adopted
+			if !identical {
+				identical, err = r.acmContentMatches(ctx, acmClient, existingCertificate.CertificateArn, leafCert, chainCert)
+				if err != nil {
+					log.Error(err, "Failed to fetch certificate from ACM")
+					return regionSync{}, err
+				}
+			}
+			if identical {
+				// Re-importing the same expiring certificate won't help; wait for
+				// cert-manager to reissue it, which updates the Secret.
+				log.Info("Certificate in ACM is going to expire but matches the Secret; waiting for renewal")
+				return regionSync{certificateArn: aws.ToString(existingCertificate.CertificateArn), notAfter: existingCertificate.NotAfter, requeueAfter: time.Hour}, nil
+			}
+			log.Info("Certificate exists in ACM and is going to expire; updating certificate")
+		}
+
+		if r.DryRun {
+			r.dryRunImport(log, acmClient, secret, aws.ToString(existingCertificate.CertificateArn))
+			return regionSync{certificateArn: aws.ToString(existingCertificate.CertificateArn), notAfter: existingCertificate.NotAfter}, nil
+		}
+
+		// Process to sync (import) the certificate
+		err = r.updateToAcm(ctx, acmClient, secret, existingCertificate.CertificateArn, leafCert, chainCert, material.keyPEM)
+		r.record(AuditEntry{
+			Action:         AuditActionUpdate,
+			Secret:         key.String(),
+			Domain:         domainName,
+			CertificateArn: aws.ToString(existingCertificate.CertificateArn),
+			Region:         acmClient.Options().Region,
+		}, err)
+		if err != nil {
 			log.Error(err, "Failed to sync certificate to ACM")
-			return ctrl.Result{RequeueAfter: 5 * time.Minute}, err
+			importsTotal.WithLabelValues(importResultFailed, acmClient.Options().Region).Inc()
+			r.eventf(secret, corev1.EventTypeWarning, reasonImportFailed, "Failed to update certificate %s in %s: %v", aws.ToString(existingCertificate.CertificateArn), acmClient.Options().Region, err)
+			if err := r.mirrorACMError(ctx, secret, "ImportCertificate", err); err != nil {
+				log.Error(err, "Failed to record ACM error on Secret")
+			}
+			return regionSync{}, err
 		}
+		importsTotal.WithLabelValues(importResultUpdated, acmClient.Options().Region).Inc()
+		r.eventf(secret, corev1.EventTypeNormal, reasonRenewed, "Updated certificate %s in %s", aws.ToString(existingCertificate.CertificateArn), acmClient.Options().Region)
+		return regionSync{certificateArn: aws.ToString(existingCertificate.CertificateArn), notAfter: &material.leaf.NotAfter}, nil
 	}
 
-	log.Info("Sucessfully synced certificate to ACM")
-	return ctrl.Result{RequeueAfter: 24 * time.Hour}, nil
+	log.Info("Certificate does not exist in ACM; importing certificate")
+	return r.importNew(ctx, log, acmClient, secret, domainName, material)
 }
 
-func (r *SecretReconciler) importToAcm(ctx context.Context, acmClient *acm.Client, secret *corev1.Secret, certPEM, chainPEM, keyPEM []byte) error {
+// importNew imports the Secret's certificate into the region of acmClient as
+// a new ACM certificate.
+func (r *SecretReconciler) importNew(ctx context.Context, log logr.Logger, acmClient awsclient.ACMAPI, secret *corev1.Secret, domainName string, material *certificateMaterial) (regionSync, error) {
+	key := client.ObjectKeyFromObject(secret)
+	if r.DryRun {
+		r.dryRunImport(log, acmClient, secret, "")
+		return regionSync{}, nil
+	}
+
+	// Sync to ACM
+	certificateArn, err := r.importToAcm(ctx, acmClient, secret, material.leafPEM, material.chainPEM, material.keyPEM)
+	r.record(AuditEntry{
+		Action:         AuditActionImport,
+		Secret:         key.String(),
+		Domain:         domainName,
+		CertificateArn: certificateArn,
+		Region:         acmClient.Options().Region,
+	}, err)
+	if err != nil {
+		log.Error(err, "Failed to sync certificate to ACM")
+		importsTotal.WithLabelValues(importResultFailed, acmClient.Options().Region).Inc()
+		r.eventf(secret, corev1.EventTypeWarning, reasonImportFailed, "Failed to import certificate into %s: %v", acmClient.Options().Region, err)
+		if err := r.mirrorACMError(ctx, secret, "ImportCertificate", err); err != nil {
+			log.Error(err, "Failed to record ACM error on Secret")
+		}
+		return regionSync{}, err
+	}
+	r.Index.Set(certificateArn, key)
+	log.Info("Imported certificate into ACM", "certificateArn", certificateArn)
+	importsTotal.WithLabelValues(importResultImported, acmClient.Options().Region).Inc()
+	r.eventf(secret, corev1.EventTypeNormal, reasonImported, "Imported certificate %s into %s", certificateArn, acmClient.Options().Region)
+	return regionSync{certificateArn: certificateArn, notAfter: &material.leaf.NotAfter}, nil
+}
+
+// importToAcm imports a new certificate and returns its ARN.
+func (r *SecretReconciler) importToAcm(ctx context.Context, acmClient awsclient.ACMAPI, secret *corev1.Secret, certPEM, chainPEM, keyPEM []byte) (string, error) {
 
 	// https://pkg.go.dev/github.com/aws/aws-sdk-go-v2/service/acm#ImportCertificateInput
 	input := &acm.ImportCertificateInput{
 		Certificate:      certPEM,
 		PrivateKey:       keyPEM,
 		CertificateChain: chainPEM,
-		Tags: []types.Tag{
-			{
-				Key:   aws.String("kubernetes-secrets"),
-				Value: aws.String(secret.Namespace + "/" + secret.Name),
-			},
-		},
+		Tags:             r.certificateTags(secret, slices.Concat(r.stageTags(), r.contentHashTags(certPEM, chainPEM), r.sourceVersionTags(secret)), r.templateTags(secret)),
 	}
 
 	// Import the certificate
-	_, err := acmClient.ImportCertificate(ctx, input)
+	output, err := r.limitedImport(ctx, acmClient, input)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	return nil
+	return aws.ToString(output.CertificateArn), nil
 }
 
-func (r *SecretReconciler) updateToAcm(ctx context.Context, acmClient *acm.Client, secret *corev1.Secret, certificateArn *string, certPEM, chainPEM, keyPEM []byte) error {
+func (r *SecretReconciler) updateToAcm(ctx context.Context, acmClient awsclient.ACMAPI, secret *corev1.Secret, certificateArn *string, certPEM, chainPEM, keyPEM []byte) error {
+
+	// Tags describing the content, which change with every import
+	contentTags := append(r.contentHashTags(certPEM, chainPEM), r.sourceVersionTags(secret)...)
 
 	// https://pkg.go.dev/github.com/aws/aws-sdk-go-v2/service/acm#ImportCertificateInput
 	input := &acm.ImportCertificateInput{
@@ -144,24 +928,80 @@ func (r *SecretReconciler) updateToAcm(ctx context.Context, acmClient *acm.Clien
 		PrivateKey:       keyPEM,
 		CertificateChain: chainPEM,
 		CertificateArn:   certificateArn,
-		Tags: []types.Tag{
-			{
-				Key:   aws.String("kubernetes-secrets"),
-				Value: aws.String(secret.Namespace + "/" + secret.Name),
-			},
-		},
+		Tags:             r.certificateTags(secret, contentTags, r.templateTags(secret)),
 	}
 
 	// Import the certificate
-	_, err := acmClient.ImportCertificate(ctx, input)
+	_, err := r.limitedImport(ctx, acmClient, input)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	// Re-importing doesn't overwrite existing tags, so the old content hash
+	// and Secret version have to be replaced explicitly.
+	if len(contentTags) > 0 {
+		_, err = acmClient.AddTagsToCertificate(ctx, &acm.AddTagsToCertificateInput{
+			CertificateArn: certificateArn,
+			Tags:           contentTags,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := r.syncAnnotationTags(ctx, acmClient, certificateArn, secret); err != nil {
+		return err
+	}
+	if err := r.ensureTags(ctx, acmClient, certificateArn, input.Tags); err != nil {
+		return err
+	}
+	return r.pruneStaleTags(ctx, acmClient, certificateArn, input.Tags)
+}
+
+// limitedImport calls importCertificate in one of the ImportLimiter slots,
+// releasing it as soon as the import returns so the tag calls that follow
+// don't hold up other imports.
+func (r *SecretReconciler) limitedImport(ctx context.Context, acmClient awsclient.ACMAPI, input *acm.ImportCertificateInput) (*acm.ImportCertificateOutput, error) {
+	if err := r.ImportLimiter.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer r.ImportLimiter.Release()
+	return r.importCertificate(ctx, acmClient, input)
 }
 
-func (r *SecretReconciler) findSecretByDomain(ctx context.Context, acmClient *acm.Client, domainName string) (*types.CertificateDetail, error) {
+// acmContentMatches reports whether the certificate stored in ACM under
+// certificateArn has the same leaf and chain as the Secret. With
+// ContentHashTags it compares the content hash tag, and only fetches the
+// certificate when the tag is missing.
+func (r *SecretReconciler) acmContentMatches(ctx context.Context, acmClient awsclient.ACMAPI, certificateArn *string, leafPEM, chainPEM []byte) (bool, error) {
+	if r.ContentHashTags {
+		tags, err := acmClient.ListTagsForCertificate(ctx, &acm.ListTagsForCertificateInput{CertificateArn: certificateArn})
+		if err != nil {
+			return false, err
+		}
+		for _, tag := range tags.Tags {
+			if aws.ToString(tag.Key) == contentHashTagKey {
+				return aws.ToString(tag.Value) == contentHash(leafPEM, chainPEM), nil
+			}
+		}
+	}
+
+	output, err := acmClient.GetCertificate(ctx, &acm.GetCertificateInput{CertificateArn: certificateArn})
+	if err != nil {
+		return false, err
+	}
+
+	return sameCertificateContent(aws.ToString(output.Certificate), aws.ToString(output.CertificateChain), leafPEM, chainPEM), nil
+}
+
+func (r *SecretReconciler) findSecretByDomain(ctx context.Context, acmClient awsclient.ACMAPI, domainName string) (*types.CertificateDetail, error) {
+	return r.findMatchingCertificate(ctx, acmClient, domainName, nil)
+}
+
+// findMatchingCertificate returns the first imported certificate covering
+// domainName that accept, when set, agrees to. With PreferInUse, the first
+// such certificate that is in use wins over earlier detached ones.
+func (r *SecretReconciler) findMatchingCertificate(ctx context.Context, acmClient awsclient.ACMAPI, domainName string, accept func(*types.CertificateDetail) bool) (*types.CertificateDetail, error) {
 	// use ListCertificates with a filter on a domain name
 	input := &acm.ListCertificatesInput{
 		CertificateStatuses: []types.CertificateStatus{
@@ -175,48 +1015,145 @@ func (r *SecretReconciler) findSecretByDomain(ctx context.Context, acmClient *ac
 				types.ExtendedKeyUsageNameTlsWebClientAuthentication,
 				types.ExtendedKeyUsageNameTlsWebServerAuthentication,
 			},
+			// ACM only lists RSA 1024 and 2048 bit certificates by default
+			KeyTypes: types.KeyAlgorithm("").Values(),
 		},
 	}
 
 	paginator := acm.NewListCertificatesPaginator(acmClient, input)
 
+	// Without an exact match in use, the best other match: one naming the
+	// domain itself beats a wildcard covering it, then one in use
+	var fallback *types.CertificateDetail
+	fallbackRank := 0
 	for paginator.HasMorePages() {
-		page, err := paginator.NextPage(ctx)
+		page, err := retryThrottled(ctx, r, "ListCertificates", r.ScanThrottleRetries, func() (*acm.ListCertificatesOutput, error) {
+			return paginator.NextPage(ctx)
+		})
 		if err != nil {
 			return nil, err
 		}
 
 		for _, certSummary := range page.CertificateSummaryList {
+			if !summaryMayMatchDomain(certSummary, domainName) {
+				continue
+			}
+
 			certDetailInput := &acm.DescribeCertificateInput{
 				CertificateArn: certSummary.CertificateArn,
 			}
 
-			certDetailOutput, err := acmClient.DescribeCertificate(ctx, certDetailInput)
+			certDetailOutput, err := r.describeForScan(ctx, acmClient, certDetailInput)
 			if err != nil {
 				return nil, err
 			}
 
+			if certDetailOutput == nil || certDetailOutput.Certificate == nil {
+				r.Log.Info("DescribeCertificate returned no certificate detail; skipping", "certificateArn", aws.ToString(certSummary.CertificateArn))
+				continue
+			}
+
 			certDetail := certDetailOutput.Certificate
-			if certDetail.DomainName == &domainName {
-				return certDetail, nil
+			if certDetail.Type == types.CertificateTypeAmazonIssued {
+				// ACM-managed certificates can't be re-imported; never adopt one
+				if certMatchesDomain(certDetail, domainName) {
+					skippedAmazonIssuedTotal.Inc()
+				}
+				continue
 			}
 
-			// Also check Subject Alternative Names
-			for _, san := range certDetail.SubjectAlternativeNames {
-				if san == domainName {
+			if certMatchesDomain(certDetail, domainName) && (accept == nil || accept(certDetail)) {
+				rank := 1
+				if certNamesDomain(certDetail, domainName) {
+					rank += 2
+				}
+				if !r.PreferInUse || len(certDetail.InUseBy) > 0 {
+					rank++
+				}
+				if rank == 4 {
 					return certDetail, nil
 				}
+				// Keep looking for an exact copy that is serving traffic
+				if rank > fallbackRank {
+					fallback, fallbackRank = certDetail, rank
+				}
 			}
 		}
 	}
-	// certificate not found
-	return nil, nil
+	return fallback, nil
+}
+
+// forEachImported calls visit with the summary of every imported certificate
+// in acmClient's region, retrying throttled pages like findMatchingCertificate.
+// The scan stops early once visit returns true.
+func (r *SecretReconciler) forEachImported(ctx context.Context, acmClient awsclient.ACMAPI, visit func(types.CertificateSummary) (bool, error)) error {
+	paginator := acm.NewListCertificatesPaginator(acmClient, &acm.ListCertificatesInput{
+		// ACM only lists RSA 1024 and 2048 bit certificates by default
+		Includes: &types.Filters{KeyTypes: types.KeyAlgorithm("").Values()},
+	})
+	for paginator.HasMorePages() {
+		page, err := retryThrottled(ctx, r, "ListCertificates", r.ScanThrottleRetries, func() (*acm.ListCertificatesOutput, error) {
+			return paginator.NextPage(ctx)
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, summary := range page.CertificateSummaryList {
+			if summary.Type != "" && summary.Type != types.CertificateTypeImported {
+				continue
+			}
+			if done, err := visit(summary); err != nil || done {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// summaryMayMatchDomain reports whether the certificate listed in summary
+// could cover domainName, so only candidates have to be described. ACM lists
+// the domain and up to 100 SANs; a summary without a domain, or with more
+// SANs than listed, can't rule the certificate out.
+func summaryMayMatchDomain(summary types.CertificateSummary, domainName string) bool {
+	if summary.DomainName == nil || aws.ToBool(summary.HasAdditionalSubjectAlternativeNames) {
+		return true
+	}
+	matches := func(name string) bool { return domainMatches(name, domainName) }
+	return matches(aws.ToString(summary.DomainName)) || slices.ContainsFunc(summary.SubjectAlternativeNameSummaries, matches)
+}
+
+// certMatchesDomain reports whether certDetail covers domainName, either by
+// name or through a wildcard one label up.
+func certMatchesDomain(certDetail *types.CertificateDetail, domainName string) bool {
+	matches := func(name string) bool { return domainMatches(name, domainName) }
+	return matches(aws.ToString(certDetail.DomainName)) || slices.ContainsFunc(certDetail.SubjectAlternativeNames, matches)
+}
+
+// certNamesDomain reports whether certDetail names domainName itself rather
+// than only covering it through a wildcard.
+func certNamesDomain(certDetail *types.CertificateDetail, domainName string) bool {
+	matches := func(name string) bool { return sameDomain(name, domainName) }
+	return matches(aws.ToString(certDetail.DomainName)) || slices.ContainsFunc(certDetail.SubjectAlternativeNames, matches)
+}
+
+// wildcardOnly reports whether certDetail covers domainName only through a
+// wildcard that leaf doesn't carry itself, so it must not be replaced by leaf.
+func wildcardOnly(certDetail *types.CertificateDetail, domainName string, leaf *x509.Certificate) bool {
+	if !certMatchesDomain(certDetail, domainName) || certNamesDomain(certDetail, domainName) {
+		return false
+	}
+	leafNames := append([]string{leaf.Subject.CommonName}, leaf.DNSNames...)
+	carried := func(name string) bool {
+		return domainMatches(name, domainName) && slices.ContainsFunc(leafNames, func(leafName string) bool { return sameDomain(leafName, name) })
+	}
+	return !carried(aws.ToString(certDetail.DomainName)) && !slices.ContainsFunc(certDetail.SubjectAlternativeNames, carried)
 }
 
 // splitCertificateChain splits the PEM-encoded certificate chain into the leaf certificate and the certificate chain.
 func splitCertificateChain(certChainPEM []byte) (leafCertPEM []byte, chainPEM []byte, err error) {
 	var certBlocks []*pem.Block
-	rest := certChainPEM
+	rest := normalizePEM(certChainPEM)
 
 	// Decode all PEM blocks
 	for {
@@ -234,13 +1171,30 @@ func splitCertificateChain(certChainPEM []byte) (leafCertPEM []byte, chainPEM []
 		return nil, nil, fmt.Errorf("no certificates found in PEM data")
 	}
 
-	// The first certificate is the leaf certificate
+	// Put the leaf first and its issuers after it, whatever order the
+	// bundle uses
+	certBlocks = orderBundle(certBlocks)
+	if _, err := x509.ParseCertificate(certBlocks[0].Bytes); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse leaf certificate: %w", err)
+	}
 	leafCertPEM = pem.EncodeToMemory(certBlocks[0])
 
+	// ACM rejects a chain that ends in the self-signed root, which
+	// cert-manager sometimes includes
+	if last := len(certBlocks) - 1; last > 0 {
+		if root, err := x509.ParseCertificate(certBlocks[last].Bytes); err == nil && isSelfSigned(root) {
+			certBlocks = certBlocks[:last]
+		}
+	}
+
 	// If there are additional certificates, they form the certificate chain
 	if len(certBlocks) > 1 {
 		var chainBytes []byte
 		for _, block := range certBlocks[1:] {
+			// Some Secrets repeat the leaf in the chain, which ACM rejects
+			if bytes.Equal(block.Bytes, certBlocks[0].Bytes) {
+				continue
+			}
 			chainBytes = append(chainBytes, pem.EncodeToMemory(block)...)
 		}
 		chainPEM = chainBytes
@@ -249,9 +1203,96 @@ func splitCertificateChain(certChainPEM []byte) (leafCertPEM []byte, chainPEM []
 	return leafCertPEM, chainPEM, nil
 }
 
+// predatesCutoff reports whether leaf was issued before MinNotBefore.
+func (r *SecretReconciler) predatesCutoff(leaf *x509.Certificate) bool {
+	return !r.MinNotBefore.IsZero() && leaf.NotBefore.Before(r.MinNotBefore)
+}
+
+// hasExpired reports whether leaf has expired at now and AllowExpired isn't
+// set.
+func (r *SecretReconciler) hasExpired(leaf *x509.Certificate, now time.Time) bool {
+	return !r.AllowExpired && leaf.NotAfter.Before(now)
+}
+
+// expiresTooSoon reports whether leaf has less than MinRemainingValidity left
+// at now.
+func (r *SecretReconciler) expiresTooSoon(leaf *x509.Certificate, now time.Time) bool {
+	return r.MinRemainingValidity > 0 && leaf.NotAfter.Sub(now) < r.MinRemainingValidity
+}
+
+// checkEmptyChain applies the OnEmptyChain policy to chainPEM. It returns an
+// error only when the chain is empty and the policy is EmptyChainFail.
+func (r *SecretReconciler) checkEmptyChain(log logr.Logger, chainPEM []byte) error {
+	if len(chainPEM) > 0 {
+		return nil
+	}
+
+	switch r.OnEmptyChain {
+	case EmptyChainFail:
+		return fmt.Errorf("certificate has no intermediate chain")
+	case EmptyChainProceed:
+		return nil
+	default:
+		log.Info("Certificate has no intermediate chain; importing leaf only")
+		return nil
+	}
+}
+
+// warnExpiringChain logs and records a warning event for every intermediate
+// in chainPEM that expires within ChainExpiryWarning. An expired intermediate
+// breaks trust even while the leaf is still valid.
+func (r *SecretReconciler) warnExpiringChain(log logr.Logger, secret *corev1.Secret, chainPEM []byte) {
+	if r.ChainExpiryWarning <= 0 {
+		return
+	}
+
+	expiring := expiringCertificates(chainPEM, time.Now().Add(r.ChainExpiryWarning))
+	for _, cert := range expiring {
+		log.Info("Intermediate certificate expires soon", "subject", cert.Subject.String(), "notAfter", cert.NotAfter)
+		r.eventf(secret, corev1.EventTypeWarning, reasonIntermediateExpiring, "Intermediate certificate %q expires at %s", cert.Subject.String(), cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+	if len(expiring) > 0 {
+		expiringIntermediatesTotal.Inc()
+	}
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *SecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.Secret{}).
-		Complete(r)
+	if err := r.watchCacheSync(context.Background(), mgr); err != nil {
+		return err
+	}
+	if r.Recorder == nil {
+		r.Recorder = mgr.GetEventRecorderFor("cert-sync")
+	}
+
+	bldr := ctrl.NewControllerManagedBy(mgr)
+	if r.ExpiryPriorityWindow > 0 {
+		bldr = bldr.Named("secret").
+			Watches(&corev1.Secret{}, &expiryPriorityHandler{Window: r.ExpiryPriorityWindow}, builder.WithPredicates(r.syncPredicate(), r.selectorPredicate()))
+	} else {
+		bldr = bldr.For(&corev1.Secret{}, builder.WithPredicates(r.syncPredicate(), r.selectorPredicate()))
+	}
+
+	bldr = bldr.WithOptions(controller.Options{
+		MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+		RateLimiter:             r.rateLimiter(),
+	})
+
+	if r.NamespaceRegions {
+		bldr = bldr.Watches(&corev1.Namespace{}, r.secretsInNamespace(),
+			builder.WithPredicates(predicate.AnnotationChangedPredicate{}))
+	}
+
+	if r.Triggers != nil {
+		bldr = bldr.WatchesRawSource(source.Channel(r.Triggers, &handler.EnqueueRequestForObject{}))
+	}
+
+	if r.State == nil {
+		return bldr.Complete(r)
+	}
+	return bldr.Complete(reconcile.Func(func(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+		result, err := r.Reconcile(ctx, req)
+		r.State.Observe(err)
+		return result, err
+	}))
 }
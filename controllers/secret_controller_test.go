@@ -0,0 +1,349 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"slices"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("SecretReconciler", func() {
+	Context("with a leaf-only certificate", func() {
+		var chain []byte
+
+		BeforeEach(func() {
+			leaf := newTestCert("example.com", nil, testCertOptions{})
+			var err error
+			_, chain, err = splitCertificateChain(leaf.PEM)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(chain).To(BeEmpty())
+		})
+
+		DescribeTable("applies the empty chain policy",
+			func(policy EmptyChainPolicy, expectErr bool) {
+				r := &SecretReconciler{OnEmptyChain: policy}
+				err := r.checkEmptyChain(logr.Discard(), chain)
+				if expectErr {
+					Expect(err).To(HaveOccurred())
+				} else {
+					Expect(err).NotTo(HaveOccurred())
+				}
+			},
+			Entry("defaults to warn", EmptyChainPolicy(""), false),
+			Entry("warn", EmptyChainWarn, false),
+			Entry("proceed", EmptyChainProceed, false),
+			Entry("fail", EmptyChainFail, true),
+		)
+
+		It("rejects unknown policies", func() {
+			_, err := ParseEmptyChainPolicy("ignore")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("reading the Secret", func() {
+	var (
+		ctx    context.Context
+		secret *corev1.Secret
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "prod",
+			Name:        "web-tls",
+			Annotations: map[string]string{"sync-to-acm": "true"},
+		}}
+	})
+
+	It("requeues when the Secret changes during the read", func() {
+		gets := 0
+		k8s := fake.NewClientBuilder().WithObjects(secret).WithInterceptorFuncs(interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				gets++
+				return apierrors.NewConflict(corev1.Resource("secrets"), key.Name, nil)
+			},
+		}).Build()
+		r := &SecretReconciler{Client: k8s, Log: logr.Discard()}
+
+		result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(ctrl.Result{Requeue: true}))
+		Expect(gets).To(Equal(1))
+	})
+
+	It("writes the ARN back onto the latest version", func() {
+		k8s := fake.NewClientBuilder().WithObjects(secret).Build()
+		r := &SecretReconciler{Client: k8s, Log: logr.Discard()}
+
+		var read corev1.Secret
+		Expect(k8s.Get(ctx, client.ObjectKeyFromObject(secret), &read)).To(Succeed())
+
+		// Someone else updates the Secret after our read
+		var concurrent corev1.Secret
+		Expect(k8s.Get(ctx, client.ObjectKeyFromObject(secret), &concurrent)).To(Succeed())
+		concurrent.Labels = map[string]string{"team": "web"}
+		Expect(k8s.Update(ctx, &concurrent)).To(Succeed())
+
+		original := read.DeepCopy()
+		read.Annotations[arnAnnotation] = "arn:aws:acm:us-east-1:123456789012:certificate/abc"
+		Expect(r.writeBack(ctx, original, &read)).To(Succeed())
+
+		var stored corev1.Secret
+		Expect(k8s.Get(ctx, client.ObjectKeyFromObject(secret), &stored)).To(Succeed())
+		Expect(stored.Annotations).To(HaveKeyWithValue(arnAnnotation, "arn:aws:acm:us-east-1:123456789012:certificate/abc"))
+		Expect(stored.Labels).To(HaveKeyWithValue("team", "web"))
+	})
+})
+
+var _ = Describe("splitCertificateChain", func() {
+	var root, intermediate, leaf *testCert
+
+	BeforeEach(func() {
+		root, intermediate, leaf = newTestChain("example.com")
+	})
+
+	It("strips a trailing self-signed root", func() {
+		bundle := append(append(append([]byte{}, leaf.PEM...), intermediate.PEM...), root.PEM...)
+
+		leafPEM, chainPEM, err := splitCertificateChain(bundle)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(leafPEM).To(Equal(leaf.PEM))
+		Expect(chainPEM).To(Equal(intermediate.PEM))
+	})
+
+	It("strips a leaf that is repeated in the chain", func() {
+		bundle := append(append(append([]byte{}, leaf.PEM...), leaf.PEM...), intermediate.PEM...)
+
+		leafPEM, chainPEM, err := splitCertificateChain(bundle)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(leafPEM).To(Equal(leaf.PEM))
+		Expect(chainPEM).To(Equal(intermediate.PEM))
+	})
+
+	It("returns an empty chain when the chain only repeats the leaf", func() {
+		leafPEM, chainPEM, err := splitCertificateChain(append(append([]byte{}, leaf.PEM...), leaf.PEM...))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(leafPEM).To(Equal(leaf.PEM))
+		Expect(chainPEM).To(BeEmpty())
+	})
+
+	It("splits a bundle with CRLF line endings and a byte order mark", func() {
+		bundle := append([]byte("\xef\xbb\xbf"), bytes.ReplaceAll(append(append([]byte{}, leaf.PEM...), intermediate.PEM...), []byte("\n"), []byte("\r\n"))...)
+
+		leafPEM, chainPEM, err := splitCertificateChain(bundle)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(leafPEM).To(Equal(leaf.PEM))
+		Expect(chainPEM).To(Equal(intermediate.PEM))
+	})
+
+	It("keeps every block of a bundle with text between and around them", func() {
+		bundle := slices.Concat(
+			[]byte("Certificate for example.com\n"),
+			leaf.PEM,
+			[]byte("\n# issuer: Test Intermediate\nsubject=/CN=Test Intermediate\n"),
+			intermediate.PEM,
+		)
+		// Concatenated without a newline in between
+		glued := slices.Concat(bytes.TrimRight(leaf.PEM, "\n"), intermediate.PEM)
+
+		for _, data := range [][]byte{bundle, glued} {
+			leafPEM, chainPEM, err := splitCertificateChain(data)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(leafPEM).To(Equal(leaf.PEM))
+			Expect(chainPEM).To(Equal(intermediate.PEM))
+		}
+	})
+
+	DescribeTable("puts the leaf first and orders its issuers",
+		func(order func() [][]byte) {
+			leafPEM, chainPEM, err := splitCertificateChain(slices.Concat(order()...))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(leafPEM).To(Equal(leaf.PEM))
+			Expect(chainPEM).To(Equal(intermediate.PEM))
+		},
+		Entry("ordered", func() [][]byte { return [][]byte{leaf.PEM, intermediate.PEM, root.PEM} }),
+		Entry("reversed", func() [][]byte { return [][]byte{root.PEM, intermediate.PEM, leaf.PEM} }),
+		Entry("intermediate first", func() [][]byte { return [][]byte{intermediate.PEM, leaf.PEM} }),
+		Entry("shuffled", func() [][]byte { return [][]byte{intermediate.PEM, root.PEM, leaf.PEM} }),
+	)
+
+	It("orders a longer chain from the leaf up", func() {
+		second := newTestCert("Second Intermediate", intermediate, testCertOptions{IsCA: true})
+		issued := newTestCert("example.com", second, testCertOptions{DNSNames: []string{"example.com"}})
+
+		leafPEM, chainPEM, err := splitCertificateChain(slices.Concat(intermediate.PEM, root.PEM, issued.PEM, second.PEM))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(leafPEM).To(Equal(issued.PEM))
+		Expect(chainPEM).To(Equal(slices.Concat(second.PEM, intermediate.PEM)))
+	})
+
+	It("syncs a reversed bundle without a domain annotation under its leaf's domain", func() {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web-tls", Annotations: map[string]string{"sync-to-acm": "true"}},
+			Type:       corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       slices.Concat(root.PEM, intermediate.PEM, leaf.PEM),
+				corev1.TLSPrivateKeyKey: leaf.keyPEM(),
+			},
+		}
+		acmFake := newFakeACM()
+		r := &SecretReconciler{Client: fake.NewClientBuilder().WithObjects(secret).Build(), Log: logr.Discard(), ACM: acmFake}
+
+		domain, _ := r.secretDomain(secret)
+		Expect(domain).To(Equal("example.com"))
+		Expect(secretDomains(secret)).To(Equal(map[string]bool{"example.com": true}))
+
+		_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acmFake.called("ImportCertificate")).To(Equal(1))
+		Expect(acmFake.certs[0].Cert).To(Equal(string(leaf.PEM)))
+	})
+
+	It("reports a leaf that isn't a valid certificate", func() {
+		broken := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("not a certificate")})
+
+		_, _, err := splitCertificateChain(append(broken, intermediate.PEM...))
+		Expect(err).To(MatchError(ContainSubstring("failed to parse leaf certificate")))
+	})
+})
+
+var _ = Describe("--min-notbefore cutoff", func() {
+	cutoff := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	It("skips certificates issued before the cutoff", func() {
+		leaf := newTestCert("example.com", nil, testCertOptions{NotBefore: cutoff.Add(-24 * time.Hour)})
+		r := &SecretReconciler{MinNotBefore: cutoff}
+		Expect(r.predatesCutoff(leaf.Cert)).To(BeTrue())
+	})
+
+	It("keeps certificates issued after the cutoff", func() {
+		leaf := newTestCert("example.com", nil, testCertOptions{NotBefore: cutoff.Add(24 * time.Hour)})
+		r := &SecretReconciler{MinNotBefore: cutoff}
+		Expect(r.predatesCutoff(leaf.Cert)).To(BeFalse())
+	})
+
+	It("keeps every certificate when unset", func() {
+		leaf := newTestCert("example.com", nil, testCertOptions{NotBefore: cutoff.Add(-24 * time.Hour)})
+		Expect((&SecretReconciler{}).predatesCutoff(leaf.Cert)).To(BeFalse())
+	})
+})
+
+var _ = Describe("--min-remaining-validity", func() {
+	now := time.Date(2024, 9, 15, 0, 0, 0, 0, time.UTC)
+	r := &SecretReconciler{MinRemainingValidity: 7 * 24 * time.Hour}
+
+	It("skips certificates about to expire", func() {
+		leaf := newTestCert("example.com", nil, testCertOptions{NotBefore: now.Add(-80 * 24 * time.Hour), NotAfter: now.Add(2 * 24 * time.Hour)})
+		Expect(r.expiresTooSoon(leaf.Cert, now)).To(BeTrue())
+	})
+
+	It("skips certificates that already expired", func() {
+		leaf := newTestCert("example.com", nil, testCertOptions{NotBefore: now.Add(-90 * 24 * time.Hour), NotAfter: now.Add(-time.Hour)})
+		Expect(r.expiresTooSoon(leaf.Cert, now)).To(BeTrue())
+	})
+
+	It("keeps certificates with enough validity left", func() {
+		leaf := newTestCert("example.com", nil, testCertOptions{NotBefore: now.Add(-time.Hour), NotAfter: now.Add(60 * 24 * time.Hour)})
+		Expect(r.expiresTooSoon(leaf.Cert, now)).To(BeFalse())
+	})
+
+	It("keeps every certificate when unset", func() {
+		leaf := newTestCert("example.com", nil, testCertOptions{NotBefore: now.Add(-80 * 24 * time.Hour), NotAfter: now.Add(time.Hour)})
+		Expect((&SecretReconciler{}).expiresTooSoon(leaf.Cert, now)).To(BeFalse())
+	})
+})
+
+var _ = Describe("expired certificates", func() {
+	now := time.Date(2024, 9, 15, 0, 0, 0, 0, time.UTC)
+	expired := newTestCert("example.com", nil, testCertOptions{NotBefore: now.Add(-90 * 24 * time.Hour), NotAfter: now.Add(-time.Hour)})
+	valid := newTestCert("example.com", nil, testCertOptions{NotBefore: now.Add(-time.Hour), NotAfter: now.Add(60 * 24 * time.Hour)})
+
+	It("skips certificates that already expired", func() {
+		Expect((&SecretReconciler{}).hasExpired(expired.Cert, now)).To(BeTrue())
+	})
+
+	It("keeps valid certificates", func() {
+		Expect((&SecretReconciler{}).hasExpired(valid.Cert, now)).To(BeFalse())
+	})
+
+	It("keeps expired certificates with --allow-expired", func() {
+		Expect((&SecretReconciler{AllowExpired: true}).hasExpired(expired.Cert, now)).To(BeFalse())
+	})
+})
+
+var _ = Describe("--chain-expiry-warning", func() {
+	var (
+		root, intermediate, leaf *testCert
+		r                        *SecretReconciler
+	)
+
+	BeforeEach(func() {
+		root = newTestCert("Test Root CA", nil, testCertOptions{IsCA: true})
+		intermediate = newTestCert("Test Intermediate CA", root, testCertOptions{IsCA: true, NotAfter: time.Now().Add(10 * 24 * time.Hour)})
+		leaf = newTestCert("example.com", intermediate, testCertOptions{DNSNames: []string{"example.com"}})
+		r = &SecretReconciler{ChainExpiryWarning: 30 * 24 * time.Hour}
+	})
+
+	It("finds an intermediate expiring within the window while the leaf is valid", func() {
+		expiring := expiringCertificates(intermediate.PEM, time.Now().Add(r.ChainExpiryWarning))
+		Expect(expiring).To(HaveLen(1))
+		Expect(expiring[0].Subject.CommonName).To(Equal("Test Intermediate CA"))
+		Expect(leaf.Cert.NotAfter).To(BeTemporally(">", intermediate.Cert.NotAfter))
+	})
+
+	It("counts and records a warning for an intermediate nearing expiry", func() {
+		recorder := record.NewFakeRecorder(1)
+		r.Recorder = recorder
+		before := testutil.ToFloat64(expiringIntermediatesTotal)
+		r.warnExpiringChain(logr.Discard(), &corev1.Secret{}, intermediate.PEM)
+		Expect(testutil.ToFloat64(expiringIntermediatesTotal)).To(Equal(before + 1))
+		Expect(recorder.Events).To(Receive(And(HavePrefix(corev1.EventTypeWarning+" "+reasonIntermediateExpiring), ContainSubstring("Test Intermediate CA"))))
+	})
+
+	It("stays quiet for intermediates outside the window", func() {
+		r.ChainExpiryWarning = 24 * time.Hour
+		before := testutil.ToFloat64(expiringIntermediatesTotal)
+		r.warnExpiringChain(logr.Discard(), &corev1.Secret{}, intermediate.PEM)
+		Expect(testutil.ToFloat64(expiringIntermediatesTotal)).To(Equal(before))
+	})
+
+	It("is disabled by default", func() {
+		r.ChainExpiryWarning = 0
+		before := testutil.ToFloat64(expiringIntermediatesTotal)
+		r.warnExpiringChain(logr.Discard(), &corev1.Secret{}, intermediate.PEM)
+		Expect(testutil.ToFloat64(expiringIntermediatesTotal)).To(Equal(before))
+	})
+})
@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// selected reports whether obj's labels match SecretSelector. A nil selector
+// selects everything.
+func (r *SecretReconciler) selected(obj client.Object) bool {
+	return r.SecretSelector == nil || r.SecretSelector.Matches(labels.Set(obj.GetLabels()))
+}
+
+// selectorPredicate lets through only events for Secrets matching
+// SecretSelector. Like syncPredicate, an update passes when either version
+// matches and Secrets holding our finalizer always pass, so a Secret that
+// loses its label is still cleaned up when deleted.
+func (r *SecretReconciler) selectorPredicate() predicate.Predicate {
+	selected := func(obj client.Object) bool {
+		return r.selected(obj) || controllerutil.ContainsFinalizer(obj, secretFinalizer)
+	}
+	return predicate.Funcs{
+		CreateFunc:  func(e event.CreateEvent) bool { return selected(e.Object) },
+		UpdateFunc:  func(e event.UpdateEvent) bool { return selected(e.ObjectOld) || selected(e.ObjectNew) },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return selected(e.Object) },
+		GenericFunc: func(event.GenericEvent) bool { return true },
+	}
+}
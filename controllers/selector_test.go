@@ -0,0 +1,109 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("selectorPredicate", func() {
+	secretWith := func(secretLabels map[string]string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web-tls", Labels: secretLabels},
+			Type:       corev1.SecretTypeTLS,
+		}
+	}
+	matching := map[string]string{"team": "web"}
+	other := map[string]string{"team": "data"}
+
+	reconcilerFor := func(selector string) *SecretReconciler {
+		s, err := labels.Parse(selector)
+		Expect(err).NotTo(HaveOccurred())
+		return &SecretReconciler{SecretSelector: s}
+	}
+
+	It("excludes Secrets that don't match", func() {
+		p := reconcilerFor("team=web").selectorPredicate()
+		Expect(p.Create(event.CreateEvent{Object: secretWith(matching)})).To(BeTrue())
+		Expect(p.Create(event.CreateEvent{Object: secretWith(other)})).To(BeFalse())
+		Expect(p.Create(event.CreateEvent{Object: secretWith(nil)})).To(BeFalse())
+		Expect(p.Delete(event.DeleteEvent{Object: secretWith(matching)})).To(BeTrue())
+		Expect(p.Delete(event.DeleteEvent{Object: secretWith(other)})).To(BeFalse())
+	})
+
+	DescribeTable("filters updates",
+		func(old, new map[string]string, expected bool) {
+			p := reconcilerFor("team=web").selectorPredicate()
+			Expect(p.Update(event.UpdateEvent{ObjectOld: secretWith(old), ObjectNew: secretWith(new)})).To(Equal(expected))
+		},
+		Entry("label added", nil, matching, true),
+		Entry("label removed", matching, nil, true),
+		Entry("matching throughout", matching, matching, true),
+		Entry("never matching", other, other, false),
+	)
+
+	It("supports set-based selectors", func() {
+		p := reconcilerFor("team in (web,api),env!=dev").selectorPredicate()
+		Expect(p.Create(event.CreateEvent{Object: secretWith(map[string]string{"team": "api", "env": "prod"})})).To(BeTrue())
+		Expect(p.Create(event.CreateEvent{Object: secretWith(map[string]string{"team": "api", "env": "dev"})})).To(BeFalse())
+	})
+
+	It("lets Secrets holding the finalizer through", func() {
+		secret := secretWith(other)
+		secret.Finalizers = []string{secretFinalizer}
+		Expect(reconcilerFor("team=web").selectorPredicate().Delete(event.DeleteEvent{Object: secret})).To(BeTrue())
+	})
+
+	It("selects every Secret without a selector", func() {
+		p := (&SecretReconciler{}).selectorPredicate()
+		Expect(p.Create(event.CreateEvent{Object: secretWith(nil)})).To(BeTrue())
+		Expect(p.Create(event.CreateEvent{Object: secretWith(other)})).To(BeTrue())
+	})
+
+	It("keeps Reconcile from syncing Secrets that don't match", func() {
+		_, intermediate, leaf := newTestChain("example.com")
+		secret := secretWith(other)
+		secret.Annotations = map[string]string{
+			"sync-to-acm":                 "true",
+			"cert-manager.io/common-name": "example.com",
+		}
+		secret.Data = map[string][]byte{
+			corev1.TLSCertKey:       append(append([]byte{}, leaf.PEM...), intermediate.PEM...),
+			corev1.TLSPrivateKeyKey: leaf.keyPEM(),
+		}
+
+		acmFake := newFakeACM()
+		r := reconcilerFor("team=web")
+		r.Client = fake.NewClientBuilder().WithObjects(secret).Build()
+		r.Log = logr.Discard()
+		r.ACM = acmFake
+		_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acmFake.called("ImportCertificate")).To(BeZero())
+	})
+})
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	corev1 "k8s.io/api/core/v1"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+const (
+	// secretUIDTagKey records the UID of the Secret a certificate was last
+	// synced from, so a Secret recreated under the same name isn't mistaken
+	// for the original.
+	secretUIDTagKey = "cert-sync/secret-uid"
+
+	// secretVersionTagKey records the resourceVersion of the Secret at the
+	// last sync. While the Secret still has it, its content can't have
+	// changed.
+	secretVersionTagKey = "cert-sync/secret-resource-version"
+)
+
+// sourceVersionTags returns the tags recording the UID and resourceVersion
+// of secret, or nil when SourceVersionTags is disabled.
+func (r *SecretReconciler) sourceVersionTags(secret *corev1.Secret) []types.Tag {
+	if !r.SourceVersionTags {
+		return nil
+	}
+	return []types.Tag{
+		{Key: aws.String(secretUIDTagKey), Value: aws.String(string(secret.UID))},
+		{Key: aws.String(secretVersionTagKey), Value: aws.String(secret.ResourceVersion)},
+	}
+}
+
+// sourceUnchanged reports whether tags were recorded from the current version
+// of secret.
+func sourceUnchanged(tags []types.Tag, secret *corev1.Secret) bool {
+	var uid, version string
+	for _, tag := range tags {
+		switch aws.ToString(tag.Key) {
+		case secretUIDTagKey:
+			uid = aws.ToString(tag.Value)
+		case secretVersionTagKey:
+			version = aws.ToString(tag.Value)
+		}
+	}
+	return uid != "" && version != "" && uid == string(secret.UID) && version == secret.ResourceVersion
+}
+
+// syncedCertificate is a certificate in the region of acmClient that a
+// reconcile synced the Secret to.
+type syncedCertificate struct {
+	acmClient      awsclient.ACMAPI
+	certificateArn string
+}
+
+// recordSourceVersion tags certificates with the current version of secret.
+// It runs after the Secret's own write-backs, which bump its
+// resourceVersion, so the next reconcile finds the version it reads.
+func (r *SecretReconciler) recordSourceVersion(ctx context.Context, secret *corev1.Secret, certificates []syncedCertificate) error {
+	versionTags := r.sourceVersionTags(secret)
+	if versionTags == nil {
+		return nil
+	}
+	for _, certificate := range certificates {
+		_, err := certificate.acmClient.AddTagsToCertificate(ctx, &acm.AddTagsToCertificateInput{CertificateArn: aws.String(certificate.certificateArn), Tags: versionTags})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
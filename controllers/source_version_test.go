@@ -0,0 +1,128 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("Secret version tags", func() {
+	var (
+		ctx     context.Context
+		acmFake *fakeACM
+		r       *SecretReconciler
+		req     reconcile.Request
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		acmFake = newFakeACM()
+		_, intermediate, leaf := newTestChain("example.com")
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "prod",
+				Name:      "web-tls",
+				UID:       "7a0f5c1e-0000-4000-8000-000000000001",
+				Annotations: map[string]string{
+					"sync-to-acm":                 "true",
+					"cert-manager.io/common-name": "example.com",
+				},
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       append(append([]byte{}, leaf.PEM...), intermediate.PEM...),
+				corev1.TLSPrivateKeyKey: leaf.keyPEM(),
+			},
+		}
+		r = &SecretReconciler{
+			Client:            fake.NewClientBuilder().WithObjects(secret).Build(),
+			Log:               logr.Discard(),
+			ACM:               acmFake,
+			SourceVersionTags: true,
+		}
+		req = reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)}
+	})
+
+	stored := func() *corev1.Secret {
+		var secret corev1.Secret
+		Expect(r.Get(ctx, req.NamespacedName, &secret)).To(Succeed())
+		return &secret
+	}
+
+	reconcileSecret := func() {
+		_, err := r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	It("records the UID and resourceVersion after the write-back", func() {
+		before := stored().ResourceVersion
+		reconcileSecret()
+
+		arn := aws.ToString(acmFake.certs[0].Detail.CertificateArn)
+		Expect(acmFake.tag(arn, secretUIDTagKey)).To(Equal("7a0f5c1e-0000-4000-8000-000000000001"))
+		// The ARN write-back bumped the resourceVersion
+		Expect(stored().ResourceVersion).NotTo(Equal(before))
+		Expect(acmFake.tag(arn, secretVersionTagKey)).To(Equal(stored().ResourceVersion))
+	})
+
+	It("skips the comparison from the first sync on", func() {
+		reconcileSecret()
+		tagged := acmFake.called("AddTagsToCertificate")
+		// A different serial would otherwise count as changed
+		acmFake.certs[0].Detail.Serial = aws.String("01")
+
+		reconcileSecret()
+		Expect(acmFake.called("AddTagsToCertificate")).To(Equal(tagged))
+		Expect(acmFake.called("ImportCertificate")).To(Equal(1))
+	})
+
+	It("refreshes the version when the Secret changes without new content", func() {
+		reconcileSecret()
+		secret := stored()
+		secret.Labels = map[string]string{"team": "web"}
+		Expect(r.Update(ctx, secret)).To(Succeed())
+
+		reconcileSecret()
+		arn := aws.ToString(acmFake.certs[0].Detail.CertificateArn)
+		Expect(acmFake.tag(arn, secretVersionTagKey)).To(Equal(stored().ResourceVersion))
+		Expect(acmFake.called("ImportCertificate")).To(Equal(1))
+	})
+
+	It("does not trust the version of a recreated Secret", func() {
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{UID: "new", ResourceVersion: "5"}}
+		tags := (&SecretReconciler{SourceVersionTags: true}).sourceVersionTags(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{UID: "old", ResourceVersion: "5"}})
+		Expect(sourceUnchanged(tags, secret)).To(BeFalse())
+		Expect(sourceUnchanged(tags[:1], secret)).To(BeFalse())
+	})
+
+	It("is not set when disabled", func() {
+		r.SourceVersionTags = false
+		reconcileSecret()
+		arn := aws.ToString(acmFake.certs[0].Detail.CertificateArn)
+		Expect(acmFake.tag(arn, secretVersionTagKey)).To(BeEmpty())
+	})
+})
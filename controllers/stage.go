@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+const (
+	// stageTagKey marks where a certificate is in a staged (blue/green)
+	// cutover. It is only set when ImportStaged is enabled.
+	stageTagKey = "cert-sync/stage"
+	stageStaged = "staged"
+	stageLive   = "live"
+
+	// promoteAnnotation, when "true", flips a staged certificate to live.
+	promoteAnnotation = "cert-sync.denyshubh.github.io/promote"
+)
+
+// stageTags returns the tags marking a newly imported certificate as staged,
+// or nil when staged imports are disabled.
+func (r *SecretReconciler) stageTags() []types.Tag {
+	if !r.ImportStaged {
+		return nil
+	}
+	return []types.Tag{{Key: aws.String(stageTagKey), Value: aws.String(stageStaged)}}
+}
+
+// promote flips the stage tag of a staged certificate to live. Certificates
+// that aren't staged are left untouched.
+func (r *SecretReconciler) promote(ctx context.Context, acmClient awsclient.ACMAPI, certificateArn *string) error {
+	output, err := acmClient.ListTagsForCertificate(ctx, &acm.ListTagsForCertificateInput{CertificateArn: certificateArn})
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range output.Tags {
+		if aws.ToString(tag.Key) != stageTagKey || aws.ToString(tag.Value) != stageStaged {
+			continue
+		}
+
+		_, err := acmClient.AddTagsToCertificate(ctx, &acm.AddTagsToCertificateInput{
+			CertificateArn: certificateArn,
+			Tags:           []types.Tag{{Key: aws.String(stageTagKey), Value: aws.String(stageLive)}},
+		})
+		if err != nil {
+			return err
+		}
+		r.Log.Info("Promoted staged certificate", "certificateArn", aws.ToString(certificateArn))
+		return nil
+	}
+	return nil
+}
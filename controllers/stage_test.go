@@ -0,0 +1,76 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("staged imports", func() {
+	var (
+		ctx     context.Context
+		acmFake *fakeACM
+		secret  *corev1.Secret
+		leaf    *testCert
+		r       *SecretReconciler
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		acmFake = newFakeACM()
+		secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web-tls"}}
+		leaf = newTestCert("example.com", nil, testCertOptions{})
+		r = &SecretReconciler{Log: logr.Discard(), ImportStaged: true}
+	})
+
+	It("tags newly imported certificates as staged", func() {
+		arn, err := r.importToAcm(ctx, acmFake, secret, leaf.PEM, nil, leaf.keyPEM())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acmFake.tag(arn, stageTagKey)).To(Equal(stageStaged))
+		Expect(acmFake.tag(arn, secretTagKey)).To(Equal("prod/web-tls"))
+	})
+
+	It("does not tag imports when staging is disabled", func() {
+		r.ImportStaged = false
+		arn, err := r.importToAcm(ctx, acmFake, secret, leaf.PEM, nil, leaf.keyPEM())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acmFake.tag(arn, stageTagKey)).To(BeEmpty())
+	})
+
+	It("promotes a staged certificate to live", func() {
+		arn, err := r.importToAcm(ctx, acmFake, secret, leaf.PEM, nil, leaf.keyPEM())
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(r.promote(ctx, acmFake, aws.String(arn))).To(Succeed())
+		Expect(acmFake.tag(arn, stageTagKey)).To(Equal(stageLive))
+	})
+
+	It("leaves unstaged certificates alone on promotion", func() {
+		arn := acmFake.add("example.com", &fakeCertificate{Tags: []types.Tag{}})
+
+		Expect(r.promote(ctx, acmFake, aws.String(arn))).To(Succeed())
+		Expect(acmFake.called("AddTagsToCertificate")).To(Equal(0))
+	})
+})
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// StateReporter periodically writes which replica is active and its recent
+// sync counters to a ConfigMap, so operators can inspect them with kubectl.
+// It runs as a manager Runnable and therefore only on the elected leader.
+type StateReporter struct {
+	Client client.Client
+	// ConfigMap is the ConfigMap the state is written to.
+	ConfigMap types.NamespacedName
+	// Identity names this replica, usually its pod name.
+	Identity string
+	// Interval is how often the state is refreshed. Defaults to 30s.
+	Interval time.Duration
+	Log      logr.Logger
+
+	mu         sync.Mutex
+	startedAt  time.Time
+	reconciles int64
+	errors     int64
+	lastSync   time.Time
+	now        func() time.Time
+}
+
+// NewStateReporter returns a StateReporter writing to configMap.
+func NewStateReporter(c client.Client, configMap types.NamespacedName, identity string, log logr.Logger) *StateReporter {
+	return &StateReporter{
+		Client:    c,
+		ConfigMap: configMap,
+		Identity:  identity,
+		Log:       log,
+	}
+}
+
+// Observe counts a finished reconcile. It is a no-op on a nil reporter.
+func (s *StateReporter) Observe(err error) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reconciles++
+	if err != nil {
+		s.errors++
+	} else {
+		s.lastSync = s.clock()
+	}
+}
+
+// Start writes the state every Interval until ctx is cancelled.
+func (s *StateReporter) Start(ctx context.Context) error {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	s.mu.Lock()
+	s.startedAt = s.clock()
+	s.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.write(ctx); err != nil {
+			s.Log.Error(err, "Failed to write controller state", "configMap", s.ConfigMap)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// write creates or replaces the state ConfigMap.
+func (s *StateReporter) write(ctx context.Context) error {
+	s.mu.Lock()
+	data := map[string]string{
+		"holderIdentity":  s.Identity,
+		"lastActive":      s.clock().UTC().Format(time.RFC3339),
+		"startedAt":       s.startedAt.UTC().Format(time.RFC3339),
+		"reconciles":      strconv.FormatInt(s.reconciles, 10),
+		"reconcileErrors": strconv.FormatInt(s.errors, 10),
+	}
+	if !s.lastSync.IsZero() {
+		data["lastSuccessfulSync"] = s.lastSync.UTC().Format(time.RFC3339)
+	}
+	s.mu.Unlock()
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: s.ConfigMap.Namespace, Name: s.ConfigMap.Name},
+		Data:       data,
+	}
+	err := s.Client.Update(ctx, configMap)
+	if apierrors.IsNotFound(err) {
+		return s.Client.Create(ctx, &corev1.ConfigMap{ObjectMeta: configMap.ObjectMeta, Data: data})
+	}
+	return err
+}
+
+func (s *StateReporter) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("StateReporter", func() {
+	var (
+		ctx      context.Context
+		k8s      client.Client
+		reporter *StateReporter
+		now      time.Time
+		key      = types.NamespacedName{Namespace: "cert-sync-system", Name: "cert-sync-state"}
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		k8s = fake.NewClientBuilder().Build()
+		now = time.Date(2024, 9, 15, 12, 0, 0, 0, time.UTC)
+		reporter = NewStateReporter(k8s, key, "cert-sync-7d9f-abcde", logr.Discard())
+		reporter.now = func() time.Time { return now }
+		reporter.startedAt = now
+	})
+
+	state := func() map[string]string {
+		configMap := &corev1.ConfigMap{}
+		Expect(k8s.Get(ctx, key, configMap)).To(Succeed())
+		return configMap.Data
+	}
+
+	It("creates the ConfigMap with the active replica", func() {
+		Expect(reporter.write(ctx)).To(Succeed())
+
+		Expect(state()).To(Equal(map[string]string{
+			"holderIdentity":  "cert-sync-7d9f-abcde",
+			"lastActive":      "2024-09-15T12:00:00Z",
+			"startedAt":       "2024-09-15T12:00:00Z",
+			"reconciles":      "0",
+			"reconcileErrors": "0",
+		}))
+	})
+
+	It("refreshes the timestamp and counters", func() {
+		Expect(reporter.write(ctx)).To(Succeed())
+
+		now = now.Add(time.Minute)
+		reporter.Observe(nil)
+		reporter.Observe(errors.New("throttled"))
+		Expect(reporter.write(ctx)).To(Succeed())
+
+		data := state()
+		Expect(data).To(HaveKeyWithValue("lastActive", "2024-09-15T12:01:00Z"))
+		Expect(data).To(HaveKeyWithValue("lastSuccessfulSync", "2024-09-15T12:01:00Z"))
+		Expect(data).To(HaveKeyWithValue("reconciles", "2"))
+		Expect(data).To(HaveKeyWithValue("reconcileErrors", "1"))
+	})
+
+	It("ignores observations without a reporter", func() {
+		var nilReporter *StateReporter
+		Expect(func() { nilReporter.Observe(nil) }).NotTo(Panic())
+	})
+})
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/go-logr/logr"
+)
+
+// SessionSummary tallies the ACM changes made since the controller started
+// and logs them on shutdown, which is handy for short-lived, job-style runs.
+type SessionSummary struct {
+	Log logr.Logger
+
+	mu        sync.Mutex
+	succeeded map[AuditAction]int
+	failed    map[AuditAction]int
+	domains   map[string]bool
+}
+
+// SummaryReport is a snapshot of a SessionSummary.
+type SummaryReport struct {
+	Succeeded map[AuditAction]int
+	Failed    map[AuditAction]int
+	Domains   []string
+}
+
+// NewSessionSummary returns an empty SessionSummary.
+func NewSessionSummary(log logr.Logger) *SessionSummary {
+	return &SessionSummary{
+		Log:       log,
+		succeeded: map[AuditAction]int{},
+		failed:    map[AuditAction]int{},
+		domains:   map[string]bool{},
+	}
+}
+
+// Record counts entry as succeeded or failed depending on err. It is a no-op
+// on a nil SessionSummary.
+func (s *SessionSummary) Record(entry AuditEntry, err error) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.failed[entry.Action]++
+	} else {
+		s.succeeded[entry.Action]++
+	}
+	if entry.Domain != "" {
+		s.domains[entry.Domain] = true
+	}
+}
+
+// Report returns the current tallies.
+func (s *SessionSummary) Report() SummaryReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := SummaryReport{
+		Succeeded: make(map[AuditAction]int, len(s.succeeded)),
+		Failed:    make(map[AuditAction]int, len(s.failed)),
+		Domains:   make([]string, 0, len(s.domains)),
+	}
+	for action, n := range s.succeeded {
+		report.Succeeded[action] = n
+	}
+	for action, n := range s.failed {
+		report.Failed[action] = n
+	}
+	for domain := range s.domains {
+		report.Domains = append(report.Domains, domain)
+	}
+	sort.Strings(report.Domains)
+	return report
+}
+
+// Start waits for the manager to stop and then logs the summary.
+func (s *SessionSummary) Start(ctx context.Context) error {
+	<-ctx.Done()
+
+	report := s.Report()
+	s.Log.Info("Session summary", "succeeded", report.Succeeded, "failed", report.Failed, "domains", report.Domains)
+	return nil
+}
+
+// NeedLeaderElection makes the summary run on every replica, so standbys
+// report an empty session too.
+func (s *SessionSummary) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+var _ = Describe("SessionSummary", func() {
+	It("reports the syncs of a run on shutdown", func() {
+		var logged []string
+		summary := NewSessionSummary(funcr.New(func(_, args string) { logged = append(logged, args) }, funcr.Options{}))
+
+		_, intermediate, leaf := newTestChain("example.com")
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "prod",
+				Name:      "web-tls",
+				Annotations: map[string]string{
+					"sync-to-acm":                 "true",
+					"cert-manager.io/common-name": "example.com",
+				},
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       append(append([]byte{}, leaf.PEM...), intermediate.PEM...),
+				corev1.TLSPrivateKeyKey: leaf.keyPEM(),
+			},
+		}
+		acmFake := newFakeACM()
+		r := &SecretReconciler{
+			Client:  fake.NewClientBuilder().WithObjects(secret).Build(),
+			Log:     logr.Discard(),
+			Summary: summary,
+			NewACMClient: func(context.Context, awsclient.ClientKey) (awsclient.ACMAPI, error) {
+				return acmFake, nil
+			},
+		}
+
+		_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)})
+		Expect(err).NotTo(HaveOccurred())
+		r.record(AuditEntry{Action: AuditActionUpdate, Domain: "api.example.com"}, errors.New("throttled"))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		Expect(summary.Start(ctx)).To(Succeed())
+
+		Expect(summary.Report()).To(Equal(SummaryReport{
+			Succeeded: map[AuditAction]int{AuditActionImport: 1},
+			Failed:    map[AuditAction]int{AuditActionUpdate: 1},
+			Domains:   []string{"api.example.com", "example.com"},
+		}))
+		Expect(logged).To(ConsistOf(ContainSubstring("Session summary")))
+	})
+
+	It("ignores records without a summary", func() {
+		var summary *SessionSummary
+		Expect(func() { summary.Record(AuditEntry{}, nil) }).NotTo(Panic())
+	})
+})
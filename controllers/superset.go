@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// SupersetMatchPolicy controls whether an ACM certificate that covers the
+// Secret's domain but also other domains may be updated from the Secret.
+type SupersetMatchPolicy string
+
+const (
+	// SupersetMatchAccept updates the first certificate covering the domain.
+	SupersetMatchAccept SupersetMatchPolicy = "accept"
+	// SupersetMatchReject only matches certificates whose domains are all
+	// covered by the Secret, importing a new certificate otherwise, so that
+	// unrelated domains are never bound to the Secret's certificate.
+	SupersetMatchReject SupersetMatchPolicy = "reject"
+)
+
+// ParseSupersetMatchPolicy validates s as a SupersetMatchPolicy.
+func ParseSupersetMatchPolicy(s string) (SupersetMatchPolicy, error) {
+	switch p := SupersetMatchPolicy(s); p {
+	case SupersetMatchAccept, SupersetMatchReject:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid superset match policy %q: must be one of accept, reject", s)
+}
+
+// secretDomains returns the domains covered by secret's leaf certificate, or
+// nil if it can't be parsed.
+func secretDomains(secret *corev1.Secret) map[string]bool {
+	leaf, err := parseLeafCertificate(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		return nil
+	}
+
+	domains := map[string]bool{}
+	if leaf.Subject.CommonName != "" {
+		domains[leaf.Subject.CommonName] = true
+	}
+	for _, name := range leaf.DNSNames {
+		domains[name] = true
+	}
+	return domains
+}
+
+// extraDomains returns the domains certDetail covers beyond domains.
+func extraDomains(certDetail *types.CertificateDetail, domains map[string]bool) []string {
+	var extra []string
+	names := append([]string{aws.ToString(certDetail.DomainName)}, certDetail.SubjectAlternativeNames...)
+	seen := map[string]bool{}
+	for _, name := range names {
+		if name == "" || domains[name] || seen[name] {
+			continue
+		}
+		seen[name] = true
+		extra = append(extra, name)
+	}
+	return extra
+}
+
+// supersetFilter returns the match filter for secret under the configured
+// policy, or nil when every match is acceptable.
+func (r *SecretReconciler) supersetFilter(secret *corev1.Secret) func(*types.CertificateDetail) bool {
+	if r.SupersetMatch != SupersetMatchReject {
+		return nil
+	}
+	domains := secretDomains(secret)
+	if domains == nil {
+		return nil
+	}
+
+	return func(certDetail *types.CertificateDetail) bool {
+		extra := extraDomains(certDetail, domains)
+		if len(extra) > 0 {
+			r.Log.Info("Declining ACM certificate covering domains not in the Secret", "certificateArn", aws.ToString(certDetail.CertificateArn), "extraDomains", extra)
+			return false
+		}
+		return true
+	}
+}
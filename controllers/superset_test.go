@@ -0,0 +1,92 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("superset matches", func() {
+	var (
+		ctx      context.Context
+		acmFake  *fakeACM
+		secret   *corev1.Secret
+		r        *SecretReconciler
+		superset string
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		acmFake = newFakeACM()
+		superset = acmFake.add("example.com", &fakeCertificate{Detail: types.CertificateDetail{
+			Type:                    types.CertificateTypeImported,
+			SubjectAlternativeNames: []string{"example.com", "www.example.com", "unrelated.org"},
+		}})
+
+		leaf := newTestCert("example.com", nil, testCertOptions{DNSNames: []string{"example.com", "www.example.com"}})
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web-tls"},
+			Data:       map[string][]byte{corev1.TLSCertKey: leaf.PEM},
+		}
+		r = &SecretReconciler{Log: logr.Discard()}
+	})
+
+	It("accepts a certificate covering extra domains by default", func() {
+		certificate, err := r.findCertificate(ctx, acmFake, secret, "example.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(aws.ToString(certificate.CertificateArn)).To(Equal(superset))
+	})
+
+	Context("when rejecting supersets", func() {
+		BeforeEach(func() {
+			r.SupersetMatch = SupersetMatchReject
+		})
+
+		It("imports a new certificate instead", func() {
+			certificate, err := r.findCertificate(ctx, acmFake, secret, "example.com")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(certificate).To(BeNil())
+		})
+
+		It("keeps looking for a domain-exact certificate", func() {
+			exact := acmFake.add("example.com", &fakeCertificate{Detail: types.CertificateDetail{
+				Type:                    types.CertificateTypeImported,
+				SubjectAlternativeNames: []string{"example.com"},
+			}})
+
+			certificate, err := r.findCertificate(ctx, acmFake, secret, "example.com")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(aws.ToString(certificate.CertificateArn)).To(Equal(exact))
+		})
+	})
+
+	It("lists the domains a certificate covers beyond the Secret's", func() {
+		detail := &types.CertificateDetail{
+			DomainName:              aws.String("example.com"),
+			SubjectAlternativeNames: []string{"example.com", "unrelated.org", "unrelated.org"},
+		}
+		Expect(extraDomains(detail, secretDomains(secret))).To(Equal([]string{"unrelated.org"}))
+	})
+})
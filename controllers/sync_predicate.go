@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// syncPredicate lets through only events for TLS Secrets that ask to be
+// synced, so the thousands of other Secrets in a cluster don't wake the
+// controller. An update passes when either version asks to be synced, so
+// removing the annotation is still seen, and Secrets holding our finalizer
+// always pass so their deletion is cleaned up. With LenientAnnotations,
+// Secrets with a misspelled sync annotation pass too, to be warned about.
+// Reconcile keeps its own checks.
+func (r *SecretReconciler) syncPredicate() predicate.Predicate {
+	synced := func(obj client.Object) bool {
+		if controllerutil.ContainsFinalizer(obj, secretFinalizer) {
+			return true
+		}
+		secret, ok := obj.(*corev1.Secret)
+		if !ok || secret.Type != corev1.SecretTypeTLS {
+			return false
+		}
+		return r.syncRequested(secret) || r.LenientAnnotations && r.misspelled(secret)
+	}
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool { return synced(e.Object) },
+		UpdateFunc: func(e event.UpdateEvent) bool { return synced(e.ObjectOld) || synced(e.ObjectNew) },
+		DeleteFunc: func(e event.DeleteEvent) bool { return synced(e.Object) },
+		// Generic events are explicit requests, e.g. from the admin server
+		GenericFunc: func(event.GenericEvent) bool { return true },
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+var _ = Describe("syncPredicate", func() {
+	secretWith := func(secretType corev1.SecretType, annotations map[string]string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web-tls", Annotations: annotations},
+			Type:       secretType,
+		}
+	}
+	synced := map[string]string{"sync-to-acm": "true"}
+
+	DescribeTable("filters updates",
+		func(old, new *corev1.Secret, expected bool) {
+			p := (&SecretReconciler{}).syncPredicate()
+			Expect(p.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: new})).To(Equal(expected))
+		},
+		Entry("annotation added", secretWith(corev1.SecretTypeTLS, nil), secretWith(corev1.SecretTypeTLS, synced), true),
+		Entry("annotation removed", secretWith(corev1.SecretTypeTLS, synced), secretWith(corev1.SecretTypeTLS, nil), true),
+		Entry("annotated throughout", secretWith(corev1.SecretTypeTLS, synced), secretWith(corev1.SecretTypeTLS, synced), true),
+		Entry("never annotated", secretWith(corev1.SecretTypeTLS, nil), secretWith(corev1.SecretTypeTLS, nil), false),
+		Entry("set to false", secretWith(corev1.SecretTypeTLS, map[string]string{"sync-to-acm": "false"}), secretWith(corev1.SecretTypeTLS, map[string]string{"sync-to-acm": "false"}), false),
+		Entry("not a TLS Secret", secretWith(corev1.SecretTypeOpaque, synced), secretWith(corev1.SecretTypeOpaque, synced), false),
+	)
+
+	It("filters creates and deletes", func() {
+		p := (&SecretReconciler{}).syncPredicate()
+		Expect(p.Create(event.CreateEvent{Object: secretWith(corev1.SecretTypeTLS, synced)})).To(BeTrue())
+		Expect(p.Create(event.CreateEvent{Object: secretWith(corev1.SecretTypeOpaque, nil)})).To(BeFalse())
+		Expect(p.Delete(event.DeleteEvent{Object: secretWith(corev1.SecretTypeTLS, synced)})).To(BeTrue())
+		Expect(p.Delete(event.DeleteEvent{Object: secretWith(corev1.SecretTypeTLS, nil)})).To(BeFalse())
+	})
+
+	It("lets Secrets holding the finalizer through", func() {
+		secret := secretWith(corev1.SecretTypeTLS, nil)
+		secret.Finalizers = []string{secretFinalizer}
+		Expect((&SecretReconciler{}).syncPredicate().Update(event.UpdateEvent{ObjectOld: secret, ObjectNew: secret})).To(BeTrue())
+	})
+
+	It("honours lenient annotations", func() {
+		p := (&SecretReconciler{LenientAnnotations: true}).syncPredicate()
+		Expect(p.Create(event.CreateEvent{Object: secretWith(corev1.SecretTypeTLS, map[string]string{"sync-to-acm": "yes"})})).To(BeTrue())
+		Expect(p.Create(event.CreateEvent{Object: secretWith(corev1.SecretTypeTLS, map[string]string{"sync_to_acm": "true"})})).To(BeTrue())
+	})
+})
@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+// TagRefresher brings the tags of every synced ACM certificate in line with
+// the current tag configuration in one pass, without re-importing anything.
+// It is meant for mass tag-policy changes, e.g. a new --max-tags or edited
+// tag templates, and runs once when the manager starts.
+type TagRefresher struct {
+	// Reconciler provides the client and tag configuration.
+	Reconciler *SecretReconciler
+	// ACM is the client tags are refreshed through.
+	ACM awsclient.ACMAPI
+	// Concurrency bounds the number of certificates updated at once.
+	// Defaults to 4.
+	Concurrency int
+	Log         logr.Logger
+}
+
+// Start refreshes the tags of every Secret with a recorded ACM certificate.
+func (t *TagRefresher) Start(ctx context.Context) error {
+	var secrets corev1.SecretList
+	if err := t.Reconciler.List(ctx, &secrets); err != nil {
+		return err
+	}
+
+	var synced []*corev1.Secret
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if t.Reconciler.syncRequested(secret) && t.Reconciler.selected(secret) && secret.Annotations[arnAnnotation] != "" {
+			synced = append(synced, secret)
+		}
+	}
+
+	if err := t.refresh(ctx, synced); err != nil {
+		// A failed refresh is retried by the regular reconciles; don't take
+		// the manager down with it
+		t.Log.Error(err, "Failed to refresh some ACM tags")
+	}
+	return nil
+}
+
+// refresh updates the tags of secrets' certificates with at most Concurrency
+// certificates in flight, and returns the errors of all failed updates.
+func (t *TagRefresher) refresh(ctx context.Context, secrets []*corev1.Secret) error {
+	concurrency := t.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		errs    []error
+		updated int
+		slots   = make(chan struct{}, concurrency)
+	)
+	for _, secret := range secrets {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		}
+
+		wg.Add(1)
+		go func(secret *corev1.Secret) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			changed, err := t.refreshOne(ctx, secret)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", client.ObjectKeyFromObject(secret), err))
+			}
+			if changed {
+				updated++
+			}
+		}(secret)
+	}
+	wg.Wait()
+
+	t.Log.Info("Refreshed ACM tags", "certificates", len(secrets), "updated", updated, "failed", len(errs))
+	return errors.Join(errs...)
+}
+
+// refreshOne applies the tag changes for secret's certificate and reports
+// whether anything changed.
+func (t *TagRefresher) refreshOne(ctx context.Context, secret *corev1.Secret) (bool, error) {
+	certificateArn := aws.String(recordedArn(secret, t.ACM.Options().Region))
+	if aws.ToString(certificateArn) == "" {
+		// Synced to other regions only
+		return false, nil
+	}
+	output, err := t.ACM.ListTagsForCertificate(ctx, &acm.ListTagsForCertificateInput{CertificateArn: certificateArn})
+	if err != nil {
+		return false, err
+	}
+
+	desired := t.Reconciler.certificateTags(secret, nil, t.Reconciler.templateTags(secret))
+	add, remove := diffTags(output.Tags, desired)
+	if !t.Reconciler.PruneStaleTags {
+		remove = nil
+	}
+
+	if len(add) > 0 {
+		_, err := t.ACM.AddTagsToCertificate(ctx, &acm.AddTagsToCertificateInput{CertificateArn: certificateArn, Tags: add})
+		if err != nil {
+			return false, err
+		}
+	}
+	if len(remove) > 0 {
+		_, err := t.ACM.RemoveTagsFromCertificate(ctx, &acm.RemoveTagsFromCertificateInput{CertificateArn: certificateArn, Tags: remove})
+		if err != nil {
+			return false, err
+		}
+	}
+	return len(add) > 0 || len(remove) > 0, nil
+}
+
+// diffTags returns the tags to add or overwrite, and the tags to remove, to
+// turn existing into desired. The stage, content hash, last-synced and
+// Secret version tags are never removed.
+func diffTags(existing, desired []types.Tag) (add, remove []types.Tag) {
+	current := make(map[string]string, len(existing))
+	for _, tag := range existing {
+		current[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	wanted := map[string]bool{stageTagKey: true, contentHashTagKey: true, lastSyncedTagKey: true, secretUIDTagKey: true, secretVersionTagKey: true}
+	for _, tag := range desired {
+		wanted[aws.ToString(tag.Key)] = true
+		if value, ok := current[aws.ToString(tag.Key)]; !ok || value != aws.ToString(tag.Value) {
+			add = append(add, tag)
+		}
+	}
+	for _, tag := range existing {
+		if !wanted[aws.ToString(tag.Key)] {
+			remove = append(remove, tag)
+		}
+	}
+	return add, remove
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// slowTagACM slows down tag writes and records how many overlap.
+type slowTagACM struct {
+	*fakeACM
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+}
+
+func (s *slowTagACM) AddTagsToCertificate(ctx context.Context, params *acm.AddTagsToCertificateInput, optFns ...func(*acm.Options)) (*acm.AddTagsToCertificateOutput, error) {
+	s.mu.Lock()
+	s.inFlight++
+	s.peak = max(s.peak, s.inFlight)
+	s.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	s.mu.Lock()
+	s.inFlight--
+	s.mu.Unlock()
+	return s.fakeACM.AddTagsToCertificate(ctx, params, optFns...)
+}
+
+var _ = Describe("TagRefresher", func() {
+	var (
+		ctx     context.Context
+		acmFake *slowTagACM
+		arns    []string
+		objects []client.Object
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		acmFake = &slowTagACM{fakeACM: newFakeACM()}
+		arns, objects = nil, nil
+		for i := 0; i < 6; i++ {
+			name := fmt.Sprintf("web-%d", i)
+			arn := acmFake.add(name+".example.com", &fakeCertificate{
+				Detail: types.CertificateDetail{Type: types.CertificateTypeImported},
+				Tags: []types.Tag{
+					{Key: aws.String(secretTagKey), Value: aws.String("prod/" + name)},
+					{Key: aws.String("cost-center"), Value: aws.String("old")},
+				},
+			})
+			arns = append(arns, arn)
+			objects = append(objects, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+				Namespace: "prod",
+				Name:      name,
+				Annotations: map[string]string{
+					"sync-to-acm":         "true",
+					arnAnnotation:         arn,
+					tagTemplateAnnotation: "team=payments",
+				},
+			}})
+		}
+		objects = append(objects, &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "unsynced"}})
+	})
+
+	refresher := func(prune bool) *TagRefresher {
+		k8s := fake.NewClientBuilder().WithObjects(objects...).Build()
+		return &TagRefresher{
+			Reconciler:  &SecretReconciler{Client: k8s, Log: logr.Discard(), PruneStaleTags: prune},
+			ACM:         acmFake,
+			Concurrency: 2,
+			Log:         logr.Discard(),
+		}
+	}
+
+	It("updates every synced certificate with bounded concurrency", func() {
+		Expect(refresher(true).Start(ctx)).To(Succeed())
+
+		for _, arn := range arns {
+			Expect(acmFake.tag(arn, "team")).To(Equal("payments"))
+			Expect(acmFake.tag(arn, "cost-center")).To(BeEmpty())
+		}
+		Expect(acmFake.called("ListTagsForCertificate")).To(Equal(len(arns)))
+		Expect(acmFake.peak).To(BeNumerically("<=", 2))
+		Expect(acmFake.called("ImportCertificate")).To(Equal(0))
+	})
+
+	It("keeps stale tags unless pruning is enabled", func() {
+		Expect(refresher(false).Start(ctx)).To(Succeed())
+
+		Expect(acmFake.tag(arns[0], "team")).To(Equal("payments"))
+		Expect(acmFake.tag(arns[0], "cost-center")).To(Equal("old"))
+		Expect(acmFake.called("RemoveTagsFromCertificate")).To(Equal(0))
+	})
+
+	It("skips certificates whose tags are already current", func() {
+		Expect(refresher(true).Start(ctx)).To(Succeed())
+		Expect(refresher(true).Start(ctx)).To(Succeed())
+
+		Expect(acmFake.called("RemoveTagsFromCertificate")).To(Equal(len(arns)))
+	})
+
+	It("diffs tags by key and value", func() {
+		add, remove := diffTags(
+			[]types.Tag{
+				{Key: aws.String("team"), Value: aws.String("old")},
+				{Key: aws.String("env"), Value: aws.String("prod")},
+				{Key: aws.String(stageTagKey), Value: aws.String(stageLive)},
+				{Key: aws.String("retired"), Value: aws.String("x")},
+			},
+			[]types.Tag{
+				{Key: aws.String("team"), Value: aws.String("payments")},
+				{Key: aws.String("env"), Value: aws.String("prod")},
+			},
+		)
+		Expect(tagKeys(add)).To(Equal([]string{"team"}))
+		Expect(tagKeys(remove)).To(Equal([]string{"retired"}))
+	})
+})
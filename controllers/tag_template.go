@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// tagTemplateAnnotation holds a Go template rendering extra ACM tags for the
+// Secret, one key=value pair per line, e.g.
+//
+//	team={{ index .Labels "team" }}
+//	owner={{ .Namespace }}
+const tagTemplateAnnotation = "cert-sync.denyshubh.github.io/tag-template"
+
+// tagPattern is the character set ACM accepts in tag keys and values.
+var tagPattern = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]*$`)
+
+// tagTemplateData is what a tag template is rendered against. It deliberately
+// leaves out the Secret's data.
+type tagTemplateData struct {
+	Name        string
+	Namespace   string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// templateTags returns the tags rendered from secret's tag template. Template
+// errors are logged and yield no tags, and invalid tags are dropped, so a bad
+// template never blocks the sync itself.
+func (r *SecretReconciler) templateTags(secret *corev1.Secret) []types.Tag {
+	text, ok := secret.Annotations[tagTemplateAnnotation]
+	if !ok {
+		return nil
+	}
+
+	log := r.Log.WithValues("secret", client.ObjectKeyFromObject(secret))
+	tags, err := renderTagTemplate(text, secret)
+	if err != nil {
+		log.Error(err, "Ignoring invalid tag template")
+		return nil
+	}
+
+	valid := make([]types.Tag, 0, len(tags))
+	for _, tag := range tags {
+		if err := validateTag(aws.ToString(tag.Key), aws.ToString(tag.Value)); err != nil {
+			log.Error(err, "Dropping invalid templated tag", "key", aws.ToString(tag.Key))
+			continue
+		}
+		valid = append(valid, tag)
+	}
+	return valid
+}
+
+// renderTagTemplate executes text against secret and parses the result as
+// key=value lines. Blank lines are skipped.
+func renderTagTemplate(text string, secret *corev1.Secret) ([]types.Tag, error) {
+	tmpl, err := template.New(tagTemplateAnnotation).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tag template: %w", err)
+	}
+
+	var out strings.Builder
+	err = tmpl.Execute(&out, tagTemplateData{
+		Name:        secret.Name,
+		Namespace:   secret.Namespace,
+		Labels:      secret.Labels,
+		Annotations: secret.Annotations,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render tag template: %w", err)
+	}
+
+	var tags []types.Tag
+	scanner := bufio.NewScanner(strings.NewReader(out.String()))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("rendered tag %q is not a key=value pair", line)
+		}
+		tags = append(tags, types.Tag{
+			Key:   aws.String(strings.TrimSpace(key)),
+			Value: aws.String(strings.TrimSpace(value)),
+		})
+	}
+	return tags, scanner.Err()
+}
+
+// validateTag checks key and value against ACM's tag constraints and rejects
+// keys managed by cert-sync itself.
+func validateTag(key, value string) error {
+	switch {
+	case key == "" || utf8.RuneCountInString(key) > 128:
+		return fmt.Errorf("tag key must be 1 to 128 characters")
+	case utf8.RuneCountInString(value) > 256:
+		return fmt.Errorf("tag value must be at most 256 characters")
+	case strings.HasPrefix(strings.ToLower(key), "aws:"):
+		return fmt.Errorf("tag key must not start with aws:")
+	case key == secretTagKey || key == stageTagKey || key == contentHashTagKey || key == lastSyncedTagKey,
+		key == secretUIDTagKey || key == secretVersionTagKey || key == annotationTagsTagKey || key == clusterTagKey:
+		return fmt.Errorf("tag key %s is reserved", key)
+	case !tagPattern.MatchString(key) || !tagPattern.MatchString(value):
+		return fmt.Errorf("tag contains characters ACM doesn't allow")
+	}
+	return nil
+}
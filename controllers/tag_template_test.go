@@ -0,0 +1,91 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("tag templates", func() {
+	var (
+		secret *corev1.Secret
+		r      *SecretReconciler
+	)
+
+	BeforeEach(func() {
+		secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "prod",
+			Name:        "web-tls",
+			Labels:      map[string]string{"team": "payments", "cost-center": "cc-42"},
+			Annotations: map[string]string{},
+		}}
+		r = &SecretReconciler{Log: logr.Discard()}
+	})
+
+	It("renders tags from the Secret's labels and metadata", func() {
+		secret.Annotations[tagTemplateAnnotation] = `team={{ index .Labels "team" }}
+cost-center={{ index .Labels "cost-center" }}
+
+namespace={{ .Namespace }}`
+
+		Expect(r.templateTags(secret)).To(Equal([]types.Tag{
+			{Key: aws.String("team"), Value: aws.String("payments")},
+			{Key: aws.String("cost-center"), Value: aws.String("cc-42")},
+			{Key: aws.String("namespace"), Value: aws.String("prod")},
+		}))
+	})
+
+	It("merges rendered tags with the built-in ones", func() {
+		secret.Annotations[tagTemplateAnnotation] = `team={{ index .Labels "team" }}`
+
+		tags := r.certificateTags(secret, nil, r.templateTags(secret))
+		Expect(tagKeys(tags)).To(Equal([]string{secretTagKey, "team"}))
+	})
+
+	It("renders nothing for a template that doesn't parse", func() {
+		secret.Annotations[tagTemplateAnnotation] = `team={{ .Labels.team`
+		Expect(r.templateTags(secret)).To(BeEmpty())
+	})
+
+	It("renders nothing when the output isn't key=value lines", func() {
+		secret.Annotations[tagTemplateAnnotation] = `{{ .Name }}`
+		Expect(r.templateTags(secret)).To(BeEmpty())
+	})
+
+	It("drops tags ACM would reject and keeps the rest", func() {
+		secret.Annotations[tagTemplateAnnotation] = `aws:team=x
+` + secretTagKey + `=someone-else
+bad=semi;colon
+long=` + strings.Repeat("v", 257) + `
+team={{ index .Labels "team" }}`
+
+		Expect(tagKeys(r.templateTags(secret))).To(Equal([]string{"team"}))
+	})
+
+	It("renders a missing label as an empty value", func() {
+		secret.Annotations[tagTemplateAnnotation] = `owner={{ index .Labels "owner" }}`
+		Expect(r.templateTags(secret)).To(Equal([]types.Tag{{Key: aws.String("owner"), Value: aws.String("")}}))
+	})
+})
@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+// maxACMTags is the maximum number of tags ACM allows on a certificate.
+const maxACMTags = 50
+
+// lastSyncedTagKey records when the certificate was last synced from its
+// Secret in its region, in RFC 3339.
+const lastSyncedTagKey = "cert-sync/last-synced"
+
+// clusterTagKey records the ClusterName of the cluster a certificate was
+// imported from, so clusters sharing an AWS account can tell their
+// certificates apart.
+const clusterTagKey = "cert-sync/cluster"
+
+// clusterTags returns the tag naming ClusterName, or nil when it is unset.
+func (r *SecretReconciler) clusterTags() []types.Tag {
+	if r.ClusterName == "" {
+		return nil
+	}
+	return []types.Tag{{Key: aws.String(clusterTagKey), Value: aws.String(r.ClusterName)}}
+}
+
+// certificateTags returns the tags to apply to the ACM certificate imported
+// from secret: the identity and cluster tags and builtin, followed by custom and the
+// Secret's tag annotations trimmed to the configured tag limit.
+func (r *SecretReconciler) certificateTags(secret *corev1.Secret, builtin, custom []types.Tag) []types.Tag {
+	annotated := r.annotationTags(secret)
+	builtin = slices.Concat([]types.Tag{
+		{
+			Key:   aws.String(secretTagKey),
+			Value: aws.String(secret.Namespace + "/" + secret.Name),
+		},
+	}, r.clusterTags(), builtin, annotationTagsMarker(annotated))
+	custom = mergeTags(custom, annotated)
+
+	max := r.MaxTags
+	if max <= 0 || max > maxACMTags {
+		max = maxACMTags
+	}
+
+	// Sort custom tags first so the same ones are dropped on every reconcile
+	tags, dropped := limitTags(builtin, sortTags(custom), max)
+	if len(dropped) > 0 {
+		r.Log.Info("Dropping custom ACM tags over the tag limit", "secret", client.ObjectKeyFromObject(secret), "limit", max, "dropped", dropped)
+	}
+	return sortTags(tags)
+}
+
+// sortTags returns a copy of tags ordered by key, then value, so tag sets built
+// from maps compare and diff stably across reconciles.
+func sortTags(tags []types.Tag) []types.Tag {
+	sorted := append([]types.Tag{}, tags...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ki, kj := aws.ToString(sorted[i].Key), aws.ToString(sorted[j].Key)
+		if ki != kj {
+			return ki < kj
+		}
+		return aws.ToString(sorted[i].Value) < aws.ToString(sorted[j].Value)
+	})
+	return sorted
+}
+
+// limitTags keeps every built-in tag and as many custom tags, in order, as fit
+// within max. It returns the kept tags and the keys of the dropped ones.
+func limitTags(builtin, custom []types.Tag, max int) ([]types.Tag, []string) {
+	tags := append([]types.Tag{}, builtin...)
+
+	var dropped []string
+	for _, tag := range custom {
+		if len(tags) >= max {
+			dropped = append(dropped, aws.ToString(tag.Key))
+			continue
+		}
+		tags = append(tags, tag)
+	}
+	return tags, dropped
+}
+
+// pruneStaleTags removes the tags on certificateArn that are no longer in
+// desired, since re-importing only adds and overwrites tags. The stage,
+// content hash and last-synced tags are managed separately and always kept.
+// It does nothing unless PruneStaleTags is set.
+func (r *SecretReconciler) pruneStaleTags(ctx context.Context, acmClient awsclient.ACMAPI, certificateArn *string, desired []types.Tag) error {
+	if !r.PruneStaleTags {
+		return nil
+	}
+
+	output, err := acmClient.ListTagsForCertificate(ctx, &acm.ListTagsForCertificateInput{CertificateArn: certificateArn})
+	if err != nil {
+		return err
+	}
+
+	_, stale := diffTags(output.Tags, desired)
+	if len(stale) == 0 {
+		return nil
+	}
+
+	_, err = acmClient.RemoveTagsFromCertificate(ctx, &acm.RemoveTagsFromCertificateInput{
+		CertificateArn: certificateArn,
+		Tags:           stale,
+	})
+	if err != nil {
+		return err
+	}
+	r.Log.Info("Removed stale ACM tags", "certificateArn", aws.ToString(certificateArn), "removed", tagKeys(stale))
+	return nil
+}
+
+// tagLastSynced sets lastSyncedTagKey on certificateArn to the current time.
+// It does nothing unless LastSyncedTag is set.
+func (r *SecretReconciler) tagLastSynced(ctx context.Context, acmClient awsclient.ACMAPI, certificateArn string) error {
+	if !r.LastSyncedTag || certificateArn == "" {
+		return nil
+	}
+
+	_, err := acmClient.AddTagsToCertificate(ctx, &acm.AddTagsToCertificateInput{
+		CertificateArn: aws.String(certificateArn),
+		Tags:           []types.Tag{{Key: aws.String(lastSyncedTagKey), Value: aws.String(time.Now().UTC().Format(time.RFC3339))}},
+	})
+	return err
+}
+
+// ensureTags re-applies the tags in desired that are missing from
+// certificateArn or have a different value there. ACM ignores tags on
+// re-import, so a region whose certificate lost a tag, or was imported before
+// it was added, would otherwise keep diverging from the other regions. It
+// does nothing unless VerifyTags is set.
+func (r *SecretReconciler) ensureTags(ctx context.Context, acmClient awsclient.ACMAPI, certificateArn *string, desired []types.Tag) error {
+	if !r.VerifyTags {
+		return nil
+	}
+
+	output, err := acmClient.ListTagsForCertificate(ctx, &acm.ListTagsForCertificateInput{CertificateArn: certificateArn})
+	if err != nil {
+		return err
+	}
+
+	missing, _ := diffTags(output.Tags, desired)
+	if len(missing) == 0 {
+		return nil
+	}
+
+	_, err = acmClient.AddTagsToCertificate(ctx, &acm.AddTagsToCertificateInput{
+		CertificateArn: certificateArn,
+		Tags:           missing,
+	})
+	if err != nil {
+		return err
+	}
+	r.Log.Info("Re-applied missing ACM tags", "certificateArn", aws.ToString(certificateArn), "region", acmClient.Options().Region, "tags", tagKeys(missing))
+	return nil
+}
+
+// tagKeys returns the keys of tags in order.
+func tagKeys(tags []types.Tag) []string {
+	keys := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		keys = append(keys, aws.ToString(tag.Key))
+	}
+	return keys
+}
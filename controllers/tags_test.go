@@ -0,0 +1,148 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("certificate tags", func() {
+	var secret *corev1.Secret
+
+	BeforeEach(func() {
+		secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web-tls"}}
+	})
+
+	customTags := func(n int) []types.Tag {
+		tags := make([]types.Tag, 0, n)
+		for i := 0; i < n; i++ {
+			tags = append(tags, types.Tag{Key: aws.String(fmt.Sprintf("custom-%02d", i)), Value: aws.String("v")})
+		}
+		return tags
+	}
+
+	It("trims custom tags to the ACM limit keeping built-in tags first", func() {
+		r := &SecretReconciler{Log: logr.Discard()}
+		tags := r.certificateTags(secret, nil, customTags(60))
+
+		Expect(tags).To(HaveLen(maxACMTags))
+		Expect(tags).To(ContainElement(types.Tag{Key: aws.String(secretTagKey), Value: aws.String("prod/web-tls")}))
+		Expect(tagKeys(tags)).To(ContainElement("custom-48"))
+		Expect(tagKeys(tags)).NotTo(ContainElement("custom-49"))
+	})
+
+	It("tags the cluster name when set", func() {
+		r := &SecretReconciler{Log: logr.Discard()}
+		Expect(tagKeys(r.certificateTags(secret, nil, nil))).NotTo(ContainElement(clusterTagKey))
+
+		r.ClusterName = "prod-east"
+		Expect(r.certificateTags(secret, nil, nil)).To(ContainElement(types.Tag{Key: aws.String(clusterTagKey), Value: aws.String("prod-east")}))
+	})
+
+	It("honours a lower configured limit", func() {
+		kept, dropped := limitTags(customTags(1), customTags(5)[1:], 3)
+		Expect(tagKeys(kept)).To(Equal([]string{"custom-00", "custom-01", "custom-02"}))
+		Expect(dropped).To(Equal([]string{"custom-03", "custom-04"}))
+	})
+
+	It("keeps built-in tags even when they alone reach the limit", func() {
+		kept, dropped := limitTags(customTags(2), customTags(1), 1)
+		Expect(kept).To(HaveLen(2))
+		Expect(dropped).To(Equal([]string{"custom-00"}))
+	})
+
+	It("orders tags deterministically regardless of input order", func() {
+		r := &SecretReconciler{Log: logr.Discard()}
+		custom := []types.Tag{
+			{Key: aws.String("team"), Value: aws.String("payments")},
+			{Key: aws.String("cost-center"), Value: aws.String("42")},
+			{Key: aws.String("env"), Value: aws.String("prod")},
+		}
+		reversed := []types.Tag{custom[2], custom[1], custom[0]}
+
+		first := r.certificateTags(secret, nil, custom)
+		second := r.certificateTags(secret, nil, reversed)
+		Expect(first).To(Equal(second))
+		Expect(tagKeys(first)).To(Equal([]string{"cost-center", "env", secretTagKey, "team"}))
+	})
+
+	It("drops the same custom tags whatever their input order", func() {
+		r := &SecretReconciler{Log: logr.Discard(), MaxTags: 2}
+		custom := customTags(3)
+		reversed := []types.Tag{custom[2], custom[1], custom[0]}
+
+		Expect(r.certificateTags(secret, nil, custom)).To(Equal(r.certificateTags(secret, nil, reversed)))
+	})
+
+	Context("when re-importing with PruneStaleTags", func() {
+		var (
+			acmFake        *fakeACM
+			certificateArn string
+			r              *SecretReconciler
+		)
+
+		BeforeEach(func() {
+			acmFake = newFakeACM()
+			certificateArn = acmFake.add("example.com", &fakeCertificate{
+				Detail: types.CertificateDetail{Type: types.CertificateTypeImported},
+				Tags: []types.Tag{
+					{Key: aws.String(secretTagKey), Value: aws.String("prod/web-tls")},
+					{Key: aws.String(stageTagKey), Value: aws.String(stageLive)},
+					{Key: aws.String("team"), Value: aws.String("payments")},
+					{Key: aws.String("project"), Value: aws.String("retired")},
+				},
+			})
+			secret.Annotations = map[string]string{tagTemplateAnnotation: "team=payments"}
+			r = &SecretReconciler{Log: logr.Discard(), PruneStaleTags: true}
+		})
+
+		update := func() {
+			_, intermediate, leaf := newTestChain("example.com")
+			Expect(r.updateToAcm(context.Background(), acmFake, secret, aws.String(certificateArn), leaf.PEM, intermediate.PEM, leaf.keyPEM())).To(Succeed())
+		}
+
+		It("removes tags no longer desired and keeps the rest", func() {
+			update()
+			Expect(acmFake.tag(certificateArn, "project")).To(BeEmpty())
+			Expect(acmFake.tag(certificateArn, "team")).To(Equal("payments"))
+			Expect(acmFake.tag(certificateArn, secretTagKey)).To(Equal("prod/web-tls"))
+			Expect(acmFake.tag(certificateArn, stageTagKey)).To(Equal(stageLive))
+		})
+
+		It("doesn't call ACM when nothing is stale", func() {
+			secret.Annotations[tagTemplateAnnotation] = "team=payments\nproject=retired"
+			update()
+			Expect(acmFake.called("RemoveTagsFromCertificate")).To(Equal(0))
+		})
+
+		It("leaves stale tags alone when disabled", func() {
+			r.PruneStaleTags = false
+			update()
+			Expect(acmFake.tag(certificateArn, "project")).To(Equal("retired"))
+			Expect(acmFake.called("ListTagsForCertificate")).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+
+	"github.com/aws/smithy-go"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// isTerminalACMError reports whether ACM rejected the certificate itself, so
+// retrying with the same Secret content is bound to fail the same way.
+func isTerminalACMError(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "ValidationException", "InvalidParameterException":
+		return true
+	}
+	return false
+}
+
+// terminalFailures remembers the content of Secrets that failed with a
+// terminal ACM error, so they are not retried until the content changes.
+type terminalFailures struct {
+	mu      sync.Mutex
+	content map[types.NamespacedName]string
+}
+
+// failed records that key failed terminally with content.
+func (f *terminalFailures) failed(key types.NamespacedName, content string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.content == nil {
+		f.content = map[types.NamespacedName]string{}
+	}
+	f.content[key] = content
+}
+
+// blocked reports whether key last failed terminally with the same content.
+func (f *terminalFailures) blocked(key types.NamespacedName, content string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	failed, ok := f.content[key]
+	return ok && failed == content
+}
+
+// forget drops the failure of key, e.g. once it synced or was deleted.
+func (f *terminalFailures) forget(key types.NamespacedName) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.content, key)
+}
+
+// secretContent fingerprints the certificate and key held by secret.
+func secretContent(secret *corev1.Secret) string {
+	h := sha256.New()
+	h.Write(secret.Data[corev1.TLSCertKey])
+	h.Write([]byte{0})
+	h.Write(secret.Data[corev1.TLSPrivateKeyKey])
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// allTerminal reports whether every error in errs is a terminal ACM error.
+func allTerminal(errs []error) bool {
+	for _, err := range errs {
+		if !isTerminalACMError(err) {
+			return false
+		}
+	}
+	return len(errs) > 0
+}
+
+// skipTerminalFailure reports whether secret last failed terminally with its
+// current content and should be left alone. It does nothing unless
+// TerminalACMErrors is set.
+func (r *SecretReconciler) skipTerminalFailure(log logr.Logger, key types.NamespacedName, secret *corev1.Secret) bool {
+	if !r.TerminalACMErrors || !r.terminalFailures.blocked(key, secretContent(secret)) {
+		return false
+	}
+	log.Info("Certificate was rejected by ACM and hasn't changed since; skipping")
+	return true
+}
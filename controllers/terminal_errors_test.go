@@ -0,0 +1,135 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/smithy-go"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = Describe("terminal ACM errors", func() {
+	var (
+		ctx     context.Context
+		acmFake *fakeACM
+		secret  *corev1.Secret
+		r       *SecretReconciler
+		req     reconcile.Request
+	)
+
+	malformed := &smithy.GenericAPIError{Code: "ValidationException", Message: "The certificate field contains more than one certificate."}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		acmFake = newFakeACM()
+		_, intermediate, leaf := newTestChain("example.com")
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "prod",
+				Name:      "web-tls",
+				Annotations: map[string]string{
+					"sync-to-acm":                 "true",
+					"cert-manager.io/common-name": "example.com",
+				},
+			},
+			Type: corev1.SecretTypeTLS,
+			Data: map[string][]byte{
+				corev1.TLSCertKey:       append(append([]byte{}, leaf.PEM...), intermediate.PEM...),
+				corev1.TLSPrivateKeyKey: leaf.keyPEM(),
+			},
+		}
+		r = &SecretReconciler{
+			Client:            fake.NewClientBuilder().WithObjects(secret).Build(),
+			Log:               logr.Discard(),
+			ACM:               acmFake,
+			TerminalACMErrors: true,
+		}
+		req = reconcile.Request{NamespacedName: client.ObjectKeyFromObject(secret)}
+	})
+
+	DescribeTable("classifies errors",
+		func(err error, terminal bool) {
+			Expect(isTerminalACMError(err)).To(Equal(terminal))
+		},
+		Entry("validation", malformed, true),
+		Entry("invalid parameter", &smithy.GenericAPIError{Code: "InvalidParameterException"}, true),
+		Entry("throttling", &smithy.GenericAPIError{Code: "ThrottlingException"}, false),
+		Entry("limit", &smithy.GenericAPIError{Code: "LimitExceededException"}, false),
+		Entry("other", errors.New("connection reset"), false),
+	)
+
+	It("stops retrying until the Secret changes", func() {
+		acmFake.importErrs = []error{malformed}
+		before := testutil.ToFloat64(terminalErrorsTotal)
+
+		_, err := r.Reconcile(ctx, req)
+		Expect(errors.Is(err, reconcile.TerminalError(nil))).To(BeTrue())
+		Expect(testutil.ToFloat64(terminalErrorsTotal)).To(Equal(before + 1))
+
+		_, err = r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acmFake.called("ImportCertificate")).To(Equal(1))
+
+		// cert-manager reissues the certificate
+		_, intermediate, leaf := newTestChain("example.com")
+		var stored corev1.Secret
+		Expect(r.Get(ctx, req.NamespacedName, &stored)).To(Succeed())
+		stored.Data[corev1.TLSCertKey] = append(append([]byte{}, leaf.PEM...), intermediate.PEM...)
+		stored.Data[corev1.TLSPrivateKeyKey] = leaf.keyPEM()
+		Expect(r.Update(ctx, &stored)).To(Succeed())
+
+		_, err = r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acmFake.called("ImportCertificate")).To(Equal(2))
+		Expect(acmFake.certs).To(HaveLen(1))
+	})
+
+	It("keeps retrying transient errors", func() {
+		acmFake.importErrs = []error{&smithy.GenericAPIError{Code: "ThrottlingException"}}
+
+		_, err := r.Reconcile(ctx, req)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, reconcile.TerminalError(nil))).To(BeFalse())
+
+		_, err = r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acmFake.called("ImportCertificate")).To(Equal(2))
+	})
+
+	It("keeps retrying when disabled", func() {
+		r.TerminalACMErrors = false
+		acmFake.importErrs = []error{malformed}
+
+		_, err := r.Reconcile(ctx, req)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, reconcile.TerminalError(nil))).To(BeFalse())
+
+		_, err = r.Reconcile(ctx, req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acmFake.called("ImportCertificate")).To(Equal(2))
+	})
+})
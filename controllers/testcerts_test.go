@@ -0,0 +1,124 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+// testCert is a generated certificate together with its signing key.
+type testCert struct {
+	Cert *x509.Certificate
+	Key  crypto.Signer
+	PEM  []byte
+}
+
+// testCertOptions tweaks the template used by newTestCert.
+type testCertOptions struct {
+	DNSNames  []string
+	NotBefore time.Time
+	NotAfter  time.Time
+	IsCA      bool
+	// RSA generates an RSA key instead of ECDSA P-256.
+	RSA bool
+	// KeyUsage replaces the default key usage when set.
+	KeyUsage x509.KeyUsage
+}
+
+// newTestCert creates a certificate for commonName signed by parent, or a
+// self-signed one when parent is nil.
+func newTestCert(commonName string, parent *testCert, opts testCertOptions) *testCert {
+	var key crypto.Signer
+	var err error
+	if opts.RSA {
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	} else {
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	Expect(err).NotTo(HaveOccurred())
+
+	if opts.NotBefore.IsZero() {
+		opts.NotBefore = time.Now().Add(-time.Hour)
+	}
+	if opts.NotAfter.IsZero() {
+		opts.NotAfter = time.Now().Add(90 * 24 * time.Hour)
+	}
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	Expect(err).NotTo(HaveOccurred())
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		DNSNames:              opts.DNSNames,
+		NotBefore:             opts.NotBefore,
+		NotAfter:              opts.NotAfter,
+		IsCA:                  opts.IsCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if opts.IsCA {
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	}
+	if opts.KeyUsage != 0 {
+		tmpl.KeyUsage = opts.KeyUsage
+	}
+
+	signerCert, signerKey := tmpl, key
+	if parent != nil {
+		signerCert, signerKey = parent.Cert, parent.Key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signerCert, key.Public(), signerKey)
+	Expect(err).NotTo(HaveOccurred())
+	cert, err := x509.ParseCertificate(der)
+	Expect(err).NotTo(HaveOccurred())
+
+	return &testCert{
+		Cert: cert,
+		Key:  key,
+		PEM:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// newTestChain returns a root CA, an intermediate signed by it and a leaf for
+// commonName signed by the intermediate.
+func newTestChain(commonName string) (root, intermediate, leaf *testCert) {
+	root = newTestCert("Test Root CA", nil, testCertOptions{IsCA: true})
+	intermediate = newTestCert("Test Intermediate CA", root, testCertOptions{IsCA: true})
+	leaf = newTestCert(commonName, intermediate, testCertOptions{DNSNames: []string{commonName}})
+	return root, intermediate, leaf
+}
+
+// keyPEM encodes the private key of c as a PKCS#8 PEM block.
+func (c *testCert) keyPEM() []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(c.Key)
+	Expect(err).NotTo(HaveOccurred())
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
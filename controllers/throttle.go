@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/smithy-go"
+
+	awsclient "github.com/denyshubh/cert-sync/pkg/aws"
+)
+
+// scanThrottleBaseDelay is the first back-off delay after a throttled
+// DescribeCertificate call during a scan.
+const scanThrottleBaseDelay = 200 * time.Millisecond
+
+// isThrottle reports whether err is an AWS throttling error.
+func isThrottle(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "ThrottlingException", "Throttling", "TooManyRequestsException", "RequestLimitExceeded":
+		return true
+	}
+	return false
+}
+
+// describeForScan calls DescribeCertificate, backing off with jitter and
+// retrying up to ScanThrottleRetries times while ACM throttles it. A scan
+// covers every certificate in the account, so giving up on the first
+// throttled call would restart it from scratch on the next reconcile.
+func (r *SecretReconciler) describeForScan(ctx context.Context, acmClient awsclient.ACMAPI, input *acm.DescribeCertificateInput) (*acm.DescribeCertificateOutput, error) {
+	return retryThrottled(ctx, r, "DescribeCertificate", r.ScanThrottleRetries, func() (*acm.DescribeCertificateOutput, error) {
+		return acmClient.DescribeCertificate(ctx, input)
+	})
+}
+
+// retryThrottled calls call, backing off with jitter and retrying up to
+// retries times while ACM throttles it. The delay starts at
+// scanThrottleBaseDelay and doubles up to ScanThrottleMaxDelay. Once the
+// retries are exhausted the throttling error is returned, failing the
+// reconcile so it is requeued.
+func retryThrottled[T any](ctx context.Context, r *SecretReconciler, op string, retries int, call func() (T, error)) (T, error) {
+	maxDelay := r.ScanThrottleMaxDelay
+	if maxDelay <= 0 {
+		maxDelay = 5 * time.Second
+	}
+
+	delay := scanThrottleBaseDelay
+	for attempt := 0; ; attempt++ {
+		output, err := call()
+		if err == nil || !isThrottle(err) || attempt >= retries {
+			return output, err
+		}
+
+		delay = min(delay, maxDelay)
+		// Jitter within the upper half of the delay keeps concurrent calls apart
+		wait := delay/2 + rand.N(delay/2+1)
+		r.Log.V(1).Info("ACM call throttled; backing off", "operation", op, "delay", wait, "attempt", attempt+1)
+		if err := r.sleep(ctx, wait); err != nil {
+			var zero T
+			return zero, err
+		}
+		delay *= 2
+	}
+}
+
+// sleep waits for d or until ctx is done.
+func (r *SecretReconciler) sleep(ctx context.Context, d time.Duration) error {
+	if r.sleepFn != nil {
+		return r.sleepFn(ctx, d)
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
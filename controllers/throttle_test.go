@@ -0,0 +1,160 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/acm/types"
+	"github.com/aws/smithy-go"
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("DescribeCertificate throttling during a scan", func() {
+	var (
+		ctx      context.Context
+		acmFake  *fakeACM
+		r        *SecretReconciler
+		target   string
+		slept    []time.Duration
+		throttle = &smithy.GenericAPIError{Code: "ThrottlingException", Message: "Rate exceeded"}
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		acmFake = newFakeACM()
+		// Amazon-issued certificates for the domain are described, then passed over
+		acmFake.add("a.example.com", &fakeCertificate{Detail: types.CertificateDetail{Type: types.CertificateTypeAmazonIssued, SubjectAlternativeNames: []string{"a.example.com", "example.com"}}})
+		acmFake.add("b.example.com", &fakeCertificate{Detail: types.CertificateDetail{Type: types.CertificateTypeAmazonIssued, SubjectAlternativeNames: []string{"b.example.com", "example.com"}}})
+		target = acmFake.add("example.com", &fakeCertificate{Detail: types.CertificateDetail{SubjectAlternativeNames: []string{"example.com"}}})
+
+		slept = nil
+		r = &SecretReconciler{
+			Log:                  logr.Discard(),
+			ScanThrottleRetries:  3,
+			ScanThrottleMaxDelay: 300 * time.Millisecond,
+			sleepFn: func(_ context.Context, d time.Duration) error {
+				slept = append(slept, d)
+				return nil
+			},
+		}
+	})
+
+	It("backs off and completes the scan under intermittent throttling", func() {
+		acmFake.describeErrs = []error{throttle, nil, throttle, throttle, nil, throttle}
+
+		certificate, err := r.findSecretByDomain(ctx, acmFake, "example.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(aws.ToString(certificate.CertificateArn)).To(Equal(target))
+		Expect(acmFake.called("DescribeCertificate")).To(Equal(7))
+		Expect(slept).To(HaveLen(4))
+	})
+
+	It("grows the delay up to the cap", func() {
+		acmFake.describeErrs = []error{throttle, throttle, throttle}
+
+		_, err := r.findSecretByDomain(ctx, acmFake, "example.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(slept).To(HaveLen(3))
+		Expect(slept[0]).To(BeNumerically("<=", scanThrottleBaseDelay))
+		for _, d := range slept {
+			Expect(d).To(BeNumerically("<=", r.ScanThrottleMaxDelay))
+			Expect(d).To(BeNumerically(">=", scanThrottleBaseDelay/2))
+		}
+	})
+
+	It("gives up once the retries are exhausted", func() {
+		acmFake.describeErrs = []error{throttle, throttle, throttle, throttle}
+
+		_, err := r.findSecretByDomain(ctx, acmFake, "example.com")
+		Expect(isThrottle(err)).To(BeTrue())
+		Expect(slept).To(HaveLen(3))
+	})
+
+	It("doesn't retry other errors", func() {
+		acmFake.describeErrs = []error{errors.New("access denied")}
+
+		_, err := r.findSecretByDomain(ctx, acmFake, "example.com")
+		Expect(err).To(MatchError("access denied"))
+		Expect(slept).To(BeEmpty())
+	})
+
+	It("retries a throttled page of the certificate list", func() {
+		acmFake.listErrs = []error{throttle, throttle}
+
+		certificate, err := r.findSecretByDomain(ctx, acmFake, "example.com")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(aws.ToString(certificate.CertificateArn)).To(Equal(target))
+		Expect(acmFake.called("ListCertificates")).To(Equal(3))
+		Expect(slept).To(HaveLen(2))
+	})
+
+	It("retries throttled tag lookups while searching by tag", func() {
+		acmFake.certs[2].Tags = []types.Tag{{Key: aws.String(secretTagKey), Value: aws.String("prod/web-tls")}}
+		acmFake.certs[2].Detail.Type = types.CertificateTypeImported
+		acmFake.tagErrs = []error{throttle, throttle}
+		secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "prod", Name: "web-tls"}}
+
+		certificate, err := r.findCertificateByTag(ctx, acmFake, secret)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(aws.ToString(certificate.CertificateArn)).To(Equal(target))
+		Expect(slept).To(HaveLen(2))
+	})
+
+	It("lists imported certificates of every key type", func() {
+		_, err := r.identicalCertificates(ctx, acmFake, "example.com", nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(acmFake.listInputs).To(HaveLen(1))
+		Expect(acmFake.listInputs[0].Includes.KeyTypes).To(ConsistOf(types.KeyAlgorithm("").Values()))
+	})
+
+	Context("on import", func() {
+		var input *acm.ImportCertificateInput
+
+		BeforeEach(func() {
+			_, intermediate, leaf := newTestChain("example.com")
+			input = &acm.ImportCertificateInput{Certificate: leaf.PEM, CertificateChain: intermediate.PEM, PrivateKey: leaf.keyPEM()}
+			r.ImportThrottleRetries = 2
+		})
+
+		It("retries a throttled import until it succeeds", func() {
+			acmFake.importErrs = []error{throttle, &smithy.GenericAPIError{Code: "TooManyRequestsException"}}
+
+			output, err := r.importCertificate(ctx, acmFake, input)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.CertificateArn).NotTo(BeNil())
+			Expect(acmFake.called("ImportCertificate")).To(Equal(3))
+			Expect(slept).To(HaveLen(2))
+		})
+
+		It("gives up once the retries are exhausted", func() {
+			acmFake.importErrs = []error{throttle, throttle, throttle}
+
+			_, err := r.importCertificate(ctx, acmFake, input)
+			Expect(isThrottle(err)).To(BeTrue())
+			Expect(acmFake.called("ImportCertificate")).To(Equal(3))
+		})
+	})
+})
@@ -3,17 +3,134 @@ package aws
 import (
 	"context"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go/middleware"
 )
 
+// Version is the cert-sync version reported in the User-Agent of AWS calls.
+// It is set at build time with
+// -ldflags "-X github.com/denyshubh/cert-sync/pkg/aws.Version=<version>".
+var Version = "dev"
+
+// UserAgent is appended, followed by Version, to the User-Agent of every AWS
+// call so cert-sync's calls can be told apart in CloudTrail. An empty value
+// leaves the SDK's User-Agent unchanged.
+var UserAgent = "cert-sync"
+
+// Region, when set, is the region of clients that don't pick their own,
+// overriding the one from the environment and shared configuration.
+var Region string
+
+// ACMEndpoint, when set, replaces the ACM endpoint of every ACM client, e.g.
+// to point cert-sync at LocalStack or a VPC endpoint.
+var ACMEndpoint string
+
+// userAgentOptions returns the API options appending UserAgent to requests.
+func userAgentOptions() []func(*middleware.Stack) error {
+	if UserAgent == "" {
+		return nil
+	}
+	return []func(*middleware.Stack) error{awsmiddleware.AddUserAgentKeyValue(UserAgent, Version)}
+}
+
+// loadConfig loads the default configuration with optFns and the cert-sync
+// User-Agent.
+func loadConfig(ctx context.Context, optFns ...func(*config.LoadOptions) error) (aws.Config, error) {
+	if Region != "" {
+		// Ahead of optFns, so a region chosen by the caller still wins
+		optFns = append([]func(*config.LoadOptions) error{config.WithRegion(Region)}, optFns...)
+	}
+	return config.LoadDefaultConfig(ctx, append(optFns, config.WithAPIOptions(userAgentOptions()))...)
+}
+
+// newACMFromConfig returns an ACM client for cfg using ACMEndpoint.
+func newACMFromConfig(cfg aws.Config) *acm.Client {
+	return acm.NewFromConfig(cfg, func(o *acm.Options) {
+		if ACMEndpoint != "" {
+			o.BaseEndpoint = aws.String(ACMEndpoint)
+		}
+	})
+}
+
+// ACMAPI is the subset of the ACM client used by the controller. It is
+// satisfied by *acm.Client.
+type ACMAPI interface {
+	acm.ListCertificatesAPIClient
+	DescribeCertificate(ctx context.Context, params *acm.DescribeCertificateInput, optFns ...func(*acm.Options)) (*acm.DescribeCertificateOutput, error)
+	GetCertificate(ctx context.Context, params *acm.GetCertificateInput, optFns ...func(*acm.Options)) (*acm.GetCertificateOutput, error)
+	ImportCertificate(ctx context.Context, params *acm.ImportCertificateInput, optFns ...func(*acm.Options)) (*acm.ImportCertificateOutput, error)
+	DeleteCertificate(ctx context.Context, params *acm.DeleteCertificateInput, optFns ...func(*acm.Options)) (*acm.DeleteCertificateOutput, error)
+	ListTagsForCertificate(ctx context.Context, params *acm.ListTagsForCertificateInput, optFns ...func(*acm.Options)) (*acm.ListTagsForCertificateOutput, error)
+	AddTagsToCertificate(ctx context.Context, params *acm.AddTagsToCertificateInput, optFns ...func(*acm.Options)) (*acm.AddTagsToCertificateOutput, error)
+	RemoveTagsFromCertificate(ctx context.Context, params *acm.RemoveTagsFromCertificateInput, optFns ...func(*acm.Options)) (*acm.RemoveTagsFromCertificateOutput, error)
+	Options() acm.Options
+}
+
 // NewACMClient initializers a new ACM Client
 
 func NewACMClient(ctx context.Context) (*acm.Client, error) {
-	cfg, err := config.LoadDefaultConfig(ctx)
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return newACMFromConfig(cfg), nil
+}
+
+// ClientKey identifies the credential context of an ACM client. The zero
+// value is the default configuration.
+type ClientKey struct {
+	// Region overrides the region of the configuration when set.
+	Region string
+	// Profile selects a shared configuration profile when set.
+	Profile string
+	// RoleARN is assumed on top of the configured credentials when set.
+	RoleARN string
+}
+
+// NewACMClientForRegion initializes a new ACM Client for region. An empty
+// region uses the region from the default configuration.
+func NewACMClientForRegion(ctx context.Context, region string) (ACMAPI, error) {
+	return NewACMClientFor(ctx, ClientKey{Region: region})
+}
+
+// newSTSClient builds the STS client roles are assumed with. Tests replace it.
+var newSTSClient = func(cfg aws.Config) stscreds.AssumeRoleAPIClient {
+	return sts.NewFromConfig(cfg)
+}
+
+// NewACMClientFor initializes a new ACM Client for the credential context key.
+func NewACMClientFor(ctx context.Context, key ClientKey) (ACMAPI, error) {
+	var opts []func(*config.LoadOptions) error
+	if key.Profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(key.Profile))
+	}
+	if key.Region != "" {
+		opts = append(opts, config.WithRegion(key.Region))
+	}
+	cfg, err := loadConfig(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.RoleARN != "" {
+		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(newSTSClient(cfg), key.RoleARN))
+	}
+	return newACMFromConfig(cfg), nil
+}
+
+// NewSQSClient initializes a new SQS Client
+func NewSQSClient(ctx context.Context) (*sqs.Client, error) {
+	cfg, err := loadConfig(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return acm.NewFromConfig(cfg), nil
+	return sqs.NewFromConfig(cfg), nil
 }
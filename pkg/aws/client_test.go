@@ -0,0 +1,197 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/acm"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+)
+
+func TestUserAgent(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_CONFIG_FILE", "/dev/null")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/dev/null")
+
+	defer func(userAgent, version string) { UserAgent, Version = userAgent, version }(UserAgent, Version)
+	Version = "1.2.3"
+
+	for _, tc := range []struct {
+		userAgent string
+		want      bool
+	}{
+		{userAgent: "cert-sync", want: true},
+		{userAgent: "", want: false},
+	} {
+		UserAgent = tc.userAgent
+
+		var got string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r.Header.Get("User-Agent")
+			w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+			_, _ = w.Write([]byte(`{"CertificateSummaryList":[]}`))
+		}))
+
+		client, err := NewACMClientFor(context.Background(), ClientKey{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = client.ListCertificates(context.Background(), &acm.ListCertificatesInput{}, func(o *acm.Options) {
+			o.BaseEndpoint = aws.String(server.URL)
+		})
+		server.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if has := strings.Contains(got, "cert-sync/1.2.3"); has != tc.want {
+			t.Errorf("UserAgent %q: User-Agent %q, want cert-sync/1.2.3 included: %v", tc.userAgent, got, tc.want)
+		}
+	}
+}
+
+// fakeSTS hands out fixed credentials for any role and records the roles
+// assumed.
+type fakeSTS struct {
+	assumed []string
+}
+
+func (f *fakeSTS) AssumeRole(_ context.Context, params *sts.AssumeRoleInput, _ ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	f.assumed = append(f.assumed, aws.ToString(params.RoleArn))
+	return &sts.AssumeRoleOutput{Credentials: &ststypes.Credentials{
+		AccessKeyId:     aws.String("ASSUMED"),
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String("token"),
+		Expiration:      aws.Time(time.Now().Add(time.Hour)),
+	}}, nil
+}
+
+func TestAssumeRole(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "base")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_CONFIG_FILE", "/dev/null")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/dev/null")
+
+	fake := &fakeSTS{}
+	defer func(original func(aws.Config) stscreds.AssumeRoleAPIClient) { newSTSClient = original }(newSTSClient)
+	newSTSClient = func(aws.Config) stscreds.AssumeRoleAPIClient { return fake }
+
+	const role = "arn:aws:iam::222222222222:role/cert-sync"
+	client, err := NewACMClientFor(context.Background(), ClientKey{Region: "eu-west-1", RoleARN: role})
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := client.Options()
+	if options.Region != "eu-west-1" {
+		t.Errorf("region %q, want eu-west-1", options.Region)
+	}
+	credentials, err := options.Credentials.Retrieve(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credentials.AccessKeyID != "ASSUMED" {
+		t.Errorf("access key %q, want the assumed role's", credentials.AccessKeyID)
+	}
+	if len(fake.assumed) != 1 || fake.assumed[0] != role {
+		t.Errorf("assumed %v, want [%s]", fake.assumed, role)
+	}
+
+	client, err = NewACMClientFor(context.Background(), ClientKey{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credentials, err = client.Options().Credentials.Retrieve(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if credentials.AccessKeyID != "base" || len(fake.assumed) != 1 {
+		t.Errorf("access key %q after %d AssumeRole calls, want the base credentials without assuming a role", credentials.AccessKeyID, len(fake.assumed))
+	}
+}
+
+func TestACMEndpoint(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_CONFIG_FILE", "/dev/null")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/dev/null")
+
+	defer func(endpoint, region string) { ACMEndpoint, Region = endpoint, region }(ACMEndpoint, Region)
+
+	// Defaults leave the configuration alone
+	client, err := NewACMClient(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options := client.Options(); options.BaseEndpoint != nil || options.Region != "us-east-1" {
+		t.Errorf("default client has endpoint %v in %q, want none in us-east-1", aws.ToString(options.BaseEndpoint), options.Region)
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		_, _ = w.Write([]byte(`{"CertificateSummaryList":[]}`))
+	}))
+	defer server.Close()
+	ACMEndpoint, Region = server.URL, "eu-central-1"
+
+	client, err = NewACMClient(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := client.Options()
+	if options.Region != "eu-central-1" {
+		t.Errorf("region %q, want eu-central-1", options.Region)
+	}
+	endpoint, err := options.EndpointResolverV2.ResolveEndpoint(context.Background(), acm.EndpointParameters{
+		Region:   aws.String(options.Region),
+		Endpoint: options.BaseEndpoint,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if endpoint.URI.String() != server.URL {
+		t.Errorf("resolved endpoint %q, want %q", endpoint.URI.String(), server.URL)
+	}
+	if _, err := client.ListCertificates(context.Background(), &acm.ListCertificatesInput{}); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 {
+		t.Errorf("endpoint got %d requests, want 1", requests)
+	}
+
+	// A region chosen for the client wins over Region
+	regional, err := NewACMClientFor(context.Background(), ClientKey{Region: "ap-southeast-2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options := regional.Options(); options.Region != "ap-southeast-2" || aws.ToString(options.BaseEndpoint) != server.URL {
+		t.Errorf("regional client has endpoint %v in %q, want %s in ap-southeast-2", aws.ToString(options.BaseEndpoint), options.Region, server.URL)
+	}
+}